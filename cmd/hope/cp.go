@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+var cpCmdLabelsString string
+var cpCmdNamespace string
+var cpCmdContainer string
+
+func initCpCmdFlags() {
+	cpCmd.Flags().StringVarP(&cpCmdLabelsString, "selector", "l", "", "Copy to or from any pod matching the given selector")
+	cpCmd.Flags().StringVarP(&cpCmdNamespace, "namespace", "n", "default", "Namespace of the pod")
+	cpCmd.Flags().StringVarP(&cpCmdContainer, "container", "c", "", "Container of the pod to copy to or from")
+}
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <src> <dest>",
+	Short: "Copy files to or from a pod",
+	Long: "Copy files to or from a pod.\n" +
+		"The pod's side of the copy is given as <pod>:<path>, where the pod can also be an object managing pods, as kind/name.\n" +
+		"With a selector, it's given as :<path>, and the pod is picked from those matching the selector.",
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcTarget, srcPath, srcRemote := cpRemotePath(args[0])
+		destTarget, destPath, destRemote := cpRemotePath(args[1])
+		if srcRemote == destRemote {
+			return errors.New("exactly one of the source and destination must be in a pod")
+		}
+
+		target, path := srcTarget, srcPath
+		if destRemote {
+			target, path = destTarget, destPath
+		}
+
+		if target == "" {
+			if cpCmdLabelsString == "" {
+				return errors.New("a pod or selector must be given")
+			}
+			target = cpCmdLabelsString
+		} else if cpCmdLabelsString != "" {
+			return errors.New("a pod can't be given along with a selector")
+		}
+
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		podName, err := utils.SelectPod(kubectl, cpCmdNamespace, target)
+		if err != nil {
+			return err
+		}
+
+		remote := fmt.Sprintf("%s/%s:%s", cpCmdNamespace, podName, path)
+		allArgs := []string{"cp"}
+		if cpCmdContainer != "" {
+			allArgs = append(allArgs, "--container", cpCmdContainer)
+		}
+
+		if srcRemote {
+			allArgs = append(allArgs, remote, args[1])
+		} else {
+			allArgs = append(allArgs, args[0], remote)
+		}
+
+		return kubeutil.ExecKubectl(kubectl, allArgs...)
+	},
+}
+
+// cpRemotePath - Split a copy argument into the pod it refers to and the
+// path within it, if it refers to a pod at all.
+// Local paths with a colon can be given as relative or absolute paths,
+// e.g. ./a:b, to keep them from being taken as a pod.
+func cpRemotePath(arg string) (string, string, bool) {
+	if strings.HasPrefix(arg, ".") || strings.HasPrefix(arg, "/") {
+		return "", arg, false
+	}
+
+	target, path, found := strings.Cut(arg, ":")
+	if !found {
+		return "", arg, false
+	}
+
+	return target, path, true
+}
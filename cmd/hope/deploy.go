@@ -2,8 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"os"
-	"strings"
+	"time"
 )
 
 import (
@@ -13,16 +12,22 @@ import (
 
 import (
 	"github.com/Eagerod/hope/cmd/hope/utils"
-	"github.com/Eagerod/hope/pkg/docker"
-	"github.com/Eagerod/hope/pkg/helm"
 	"github.com/Eagerod/hope/pkg/hope"
 	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
 var deployCmdTagSlice *[]string
+var deployCmdPrune bool
+var deployCmdPruneDryRun bool
+var deployCmdWait bool
+var deployCmdWaitTimeout time.Duration
 
 func initDeployCmdFlags() {
 	deployCmdTagSlice = deployCmd.Flags().StringArrayP("tag", "t", []string{}, "deploy resources with this tag")
+	deployCmd.Flags().BoolVarP(&deployCmdPrune, "prune", "", false, "delete objects previously deployed by a resource that are no longer part of it")
+	deployCmd.Flags().BoolVarP(&deployCmdPruneDryRun, "prune-dry-run", "", false, "list the objects --prune would delete, without deleting them")
+	deployCmd.Flags().BoolVarP(&deployCmdWait, "wait", "w", false, "wait for applied objects to finish rolling out before deploying the next resource")
+	deployCmd.Flags().DurationVarP(&deployCmdWaitTimeout, "wait-timeout", "", 5*time.Minute, "how long to wait for a resource's objects to roll out")
 }
 
 var deployCmd = &cobra.Command{
@@ -57,29 +62,15 @@ var deployCmd = &cobra.Command{
 		// Do a pass over the resources to be deployed, and determine what
 		//   kinds of local operations need to be done before all of these
 		//   things can be deployed.
-		hasDockerResource := false
 		hasKubernetesResource := false
 		for _, resource := range *resources {
 			resourceType, _ := resource.GetType()
 			switch resourceType {
-			case hope.ResourceTypeDockerBuild:
-				hasDockerResource = true
-			case hope.ResourceTypeFile, hope.ResourceTypeInline, hope.ResourceTypeJob, hope.ResourceTypeExec:
+			case hope.ResourceTypeFile, hope.ResourceTypeInline, hope.ResourceTypeKustomize, hope.ResourceTypeJob, hope.ResourceTypeExec:
 				hasKubernetesResource = true
 			}
 		}
 
-		if hasDockerResource {
-			docker.SetUseSudo()
-			if docker.UseSudo {
-				log.Info("Docker needs sudo to continue. Checking if elevated permissions are available...")
-				err := docker.AskSudo()
-				if err != nil {
-					return err
-				}
-			}
-		}
-
 		var kubectl *kubeutil.Kubectl
 		if hasKubernetesResource {
 			var err error
@@ -91,6 +82,15 @@ var deployCmd = &cobra.Command{
 			defer kubectl.Destroy()
 		}
 
+		allResources, err := utils.GetResources()
+		if err != nil {
+			return err
+		}
+
+		// Digests of images built during this deploy, by the parameter later
+		//   resources can use to pin them.
+		imageDigests := map[string]string{}
+
 		// TODO: Should be done in hope pkg
 		// TODO: Add validation to ensure each type of deployment can run given
 		//   the current dev environment -- ensure docker can connect, etc.
@@ -101,194 +101,96 @@ var deployCmd = &cobra.Command{
 				return err
 			}
 
-			parameters, err := utils.FlattenParameters(resource.Parameters, resource.FileParameters)
+			// Image digests are pinned before anything asks for their values.
+			pinned, err := hope.PinImageDigests(*allResources, resource.Parameters, imageDigests)
 			if err != nil {
 				return err
 			}
 
-			switch resourceType {
-			case hope.ResourceTypeFile:
-				if len(parameters) != 0 {
-					if info, err := os.Stat(resource.File); err != nil {
-						return err
-					} else if info.IsDir() {
-						log.Trace("Deploying directory with parameters; creating copy for parameter substitution.")
-						tempDir, err := hope.ReplaceParametersInDirectoryCopy(resource.File, parameters)
-						if err != nil {
-							return err
-						}
-						defer os.RemoveAll(tempDir)
+			parameters, err := utils.ResourceParameters(&resource, pinned)
+			if err != nil {
+				return err
+			}
 
-						if err := hope.KubectlApplyF(kubectl, tempDir); err != nil {
-							return err
-						}
-					} else {
-						content, err := hope.ReplaceParametersInFile(resource.File, parameters)
-						if err != nil {
-							return err
-						}
+			waitTimeout, wait, err := resource.WaitTimeout(deployCmdWaitTimeout)
+			if err != nil {
+				return err
+			}
+			wait = wait || deployCmdWait
 
-						if err := hope.KubectlApplyStdIn(kubectl, content); err != nil {
-							return err
-						}
-					}
-				} else {
-					log.Trace(resource.Name, " does not have any parameters. Skipping population and applying file directly")
-					if err := hope.KubectlApplyF(kubectl, resource.File); err != nil {
-						return err
-					}
+			switch resourceType {
+			case hope.ResourceTypeFile, hope.ResourceTypeInline, hope.ResourceTypeKustomize:
+				if resourceType == hope.ResourceTypeInline {
+					// Log out the inline resource before substituting it;
+					//   secrets are likely being populated.
+					log.Trace(resource.Inline)
 				}
-			case hope.ResourceTypeInline:
-				inline := resource.Inline
-
-				// Log out the inline resource before substituting it; secrets
-				//   are likely being populated.
-				log.Trace(inline)
 
-				if len(parameters) != 0 {
-					inline, err = hope.ReplaceParametersInString(inline, parameters)
-					if err != nil {
-						return err
-					}
-				} else {
+				if len(parameters) == 0 {
 					log.Trace(resource.Name, " does not have any parameters. Skipping population.")
 				}
 
-				if err := hope.KubectlApplyStdIn(kubectl, inline); err != nil {
+				manifests, err := hope.ResourceManifests(&resource, parameters)
+				if err != nil {
 					return err
 				}
-			case hope.ResourceTypeDockerBuild:
-				isCacheCommand := len(resource.Build.Source) != 0
-				isBuildCommand := len(resource.Build.Path) != 0
-
-				if isCacheCommand && isBuildCommand {
-					return fmt.Errorf("docker build step %s cannot have a path and a source", resource.Name)
-				}
 
-				// TODO: Move these to constants somewhere
-				pullConstraintAlways := resource.Build.Pull == "always"
-				pullConstraintIfNotPresent := resource.Build.Pull == "if-not-present" || resource.Build.Pull == ""
-
-				if !pullConstraintAlways && !pullConstraintIfNotPresent {
-					return fmt.Errorf("unknown Docker image pull constraint: %s", resource.Build.Pull)
+				objects, err := hope.ApplyResourceManifests(kubectl, resource.Name, manifests)
+				if err != nil {
+					return err
 				}
 
-				pullImage := ""
-				if isCacheCommand {
-					pullImage = resource.Build.Source
-				} else {
-					pullImage = resource.Build.Tag
+				for _, object := range objects {
+					log.Info(object.String(), " applied")
 				}
 
-				ifNotPresentShouldPull := false
-				if pullConstraintIfNotPresent {
-					output, err := docker.GetDocker("images", pullImage, "--format={{.Repository}}:{{.Tag}}")
+				if deployCmdPrune || deployCmdPruneDryRun {
+					stale, err := hope.StaleResourceObjects(kubectl, resource.Name, objects)
 					if err != nil {
 						return err
 					}
 
-					outputLines := strings.Split(output, "\n")
-					if len(outputLines) == 0 {
-						log.Infof("No Docker images like %s not found locally, must pull from upstream.", pullImage)
-						ifNotPresentShouldPull = true
-					} else {
-						// Figure out if the latest tag needs to be defaulted
-						//   to, or if a specific one was requested.
-						searchTag := pullImage
-						tagIndex := strings.LastIndex(searchTag, ":")
-						if tagIndex == -1 {
-							log.Debug("Provided image isn't tagged; assuming latest")
-							searchTag = fmt.Sprintf("%s:latest", searchTag)
-						}
-
-						log.Tracef("Searching for local copy of tag: %s", searchTag)
-
-						imageFound := false
-						for _, imageTag := range outputLines {
-							if imageTag == searchTag {
-								log.Debugf("Docker image matching %s found, skipping upstream pull", searchTag)
-								imageFound = true
-								break
-							}
-						}
-
-						if !imageFound {
-							log.Infof("Docker image %s not found among candidates, must pull from upstream", searchTag)
-							ifNotPresentShouldPull = true
+					if deployCmdPruneDryRun {
+						for _, object := range stale {
+							log.Info(object.String(), " would be pruned")
 						}
+					} else if err := hope.PruneObjects(log.WithFields(log.Fields{}), kubectl, stale); err != nil {
+						return err
 					}
 				}
 
-				if ifNotPresentShouldPull || pullConstraintAlways {
-					if err := docker.ExecDocker("pull", pullImage); err != nil {
-						return fmt.Errorf("failed to find image named %s", pullImage)
+				if wait {
+					log.Info("Waiting up to ", waitTimeout, " for ", resource.Name, " to roll out...")
+					if err := hope.WaitForObjects(log.WithFields(log.Fields{}), kubectl, objects, waitTimeout); err != nil {
+						return fmt.Errorf("resource %s did not roll out: %w", resource.Name, err)
 					}
 				}
-
-				if isBuildCommand {
-					if err := docker.ExecDocker("build", resource.Build.Path, "-t", resource.Build.Tag); err != nil {
-						return err
-					}
-				} else {
-					if err := docker.ExecDocker("tag", resource.Build.Source, resource.Build.Tag); err != nil {
-						return err
-					}
-				}
-
-				if err := docker.ExecDocker("push", resource.Build.Tag); err != nil {
+			case hope.ResourceTypeDockerBuild:
+				digest, err := hope.BuildImage(log.WithFields(log.Fields{}), &resource, parameters)
+				if err != nil {
 					return err
 				}
+
+				imageDigests[hope.ImageDigestParameter(resource.Name)] = digest
 			case hope.ResourceTypeJob:
-				if err := hope.FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, resource.Job, 10, 60); err != nil {
+				options, err := hope.NewJobWaitOptions(resource.Timeout, resource.MaxAttempts, resource.LogFile, 60*time.Second)
+				if err != nil {
 					return err
 				}
-			case hope.ResourceTypeExec:
-				allArgs := []string{"exec", "-it", resource.Exec.Selector}
-				if len(resource.Exec.Timeout) != 0 {
-					allArgs = append(allArgs, "--pod-running-timeout", resource.Exec.Timeout)
-				}
-
-				allArgs = append(allArgs, "--")
-				allArgs = append(allArgs, resource.Exec.Command...)
 
-				if err := kubeutil.ExecKubectl(kubectl, allArgs...); err != nil {
+				if err := hope.FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, resource.Job, options); err != nil {
 					return err
 				}
-			case hope.ResourceTypeHelm:
-				if hasRepo, err := helm.HasRepo(resource.Helm.Repo, resource.Helm.Path); err != nil {
-					return err
-				} else if !hasRepo {
-					if err := helm.ExecHelm("repo", "add", resource.Helm.Repo, resource.Helm.Path); err != nil {
-						return err
-					}
+			case hope.ResourceTypeExec:
+				if err := hope.ExecResource(log.WithFields(log.Fields{}), kubectl, &resource); err != nil {
+					return fmt.Errorf("exec resource %s failed: %w", resource.Name, err)
 				}
-
-				if err := helm.ExecHelm("repo", "update", resource.Helm.Repo); err != nil {
+			case hope.ResourceTypeHelm:
+				if err := hope.DeployHelmRelease(log.WithFields(log.Fields{}), &resource, parameters); err != nil {
 					return err
 				}
-
-				allArgs := []string{"upgrade", "--install"}
-				if len(resource.Helm.Namespace) != 0 {
-					allArgs = append(allArgs, "--namespace", resource.Helm.Namespace, "--create-namespace")
-				}
-
-				if len(resource.Helm.ValuesFile) != 0 {
-					log.Trace("Copying values file for parameter replacement")
-					tempFile, err := hope.ReplaceParametersInFileCopy(resource.Helm.ValuesFile, parameters)
-					if err != nil {
-						return err
-					}
-					defer os.Remove(tempFile)
-
-					allArgs = append(allArgs, "--values", tempFile)
-				}
-
-				if len(resource.Helm.Version) != 0 {
-					allArgs = append(allArgs, "--version", resource.Helm.Version)
-				}
-
-				allArgs = append(allArgs, resource.Helm.Release, resource.Helm.Chart)
-				if err := helm.ExecHelm(allArgs...); err != nil {
+			case hope.ResourceTypeMirror:
+				if err := hope.MirrorResource(log.WithFields(log.Fields{}), &resource); err != nil {
 					return err
 				}
 			default:
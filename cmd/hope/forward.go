@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var forwardCmd = &cobra.Command{
+	Use:   "forward [name...]",
+	Short: "Forward local ports to pods in the cluster",
+	Long: "Forward local ports to pods in the cluster, as defined in the forwards section of the hope file.\n" +
+		"If no forwards are named, all of them are started.\n" +
+		"Forwards are kept running, and reconnected when their pods go away, until interrupted.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		allForwards, err := utils.GetForwards()
+		if err != nil {
+			return err
+		}
+
+		forwards := *allForwards
+		if len(args) != 0 {
+			forwards = []hope.Forward{}
+			for _, name := range args {
+				forward, err := utils.GetForward(name)
+				if err != nil {
+					return err
+				}
+				forwards = append(forwards, *forward)
+			}
+		}
+
+		if len(forwards) == 0 {
+			return errors.New("no forwards defined in the hope file")
+		}
+
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		return hope.ForwardAll(ctx, log.WithFields(log.Fields{}), kubectl, forwards)
+	},
+}
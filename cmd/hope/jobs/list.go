@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"os"
+	"time"
+)
+
+import (
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var listCmd = &cobra.Command{
+	Use:   "list [job-name]",
+	Short: "lists the runs of jobs created by hope, newest first",
+	Args:  cobra.RangeArgs(0, 1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		jobName := ""
+		if len(args) == 1 {
+			jobName = args[0]
+			if _, err := utils.GetJob(jobName); err != nil {
+				return err
+			}
+		}
+
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		runs, err := hope.ListJobRuns(kubectl, jobName)
+		if err != nil {
+			return err
+		}
+
+		hope.PrintJobRuns(os.Stdout, runs, time.Now())
+		return nil
+	},
+}
@@ -0,0 +1,35 @@
+package jobs
+
+import (
+	"os"
+)
+
+import (
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs <[namespace/]run-name>",
+	Short: "prints the logs of a past run of a job",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		nsRun, err := hope.FindJobRun(kubectl, args[0])
+		if err != nil {
+			return err
+		}
+
+		return hope.PrintJobRunLogs(kubectl, nsRun, os.Stdout)
+	},
+}
@@ -0,0 +1,19 @@
+package jobs
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var RootCommand = &cobra.Command{
+	Use:   "jobs",
+	Short: "schedule jobs, and inspect their past runs",
+	Long:  "Schedule jobs to run in the cluster, list the runs of jobs started by hope, and retrieve their logs.",
+}
+
+func InitJobsCommand() {
+	RootCommand.AddCommand(listCmd)
+	RootCommand.AddCommand(logsCmd)
+	RootCommand.AddCommand(scheduleCmd)
+
+	initScheduleCmdFlags()
+}
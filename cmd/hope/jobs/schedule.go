@@ -0,0 +1,80 @@
+package jobs
+
+import (
+	"fmt"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var scheduleCmdParameterSlice *[]string
+var scheduleCmdNamespace string
+var scheduleCmdPrint bool
+
+func initScheduleCmdFlags() {
+	scheduleCmdParameterSlice = scheduleCmd.Flags().StringArrayP("param", "p", []string{}, "parameters to populate in the job yaml")
+	scheduleCmd.Flags().StringVarP(&scheduleCmdNamespace, "namespace", "n", "", "namespace to schedule the job in, if the job yaml doesn't set one")
+	scheduleCmd.Flags().BoolVar(&scheduleCmdPrint, "print", false, "print the rendered manifests instead of applying them")
+}
+
+var scheduleCmd = &cobra.Command{
+	Use:   "schedule <job-name>",
+	Short: "creates or updates a CronJob that runs the job on its schedule",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		job, err := utils.GetJob(args[0])
+		if err != nil {
+			return err
+		}
+
+		parameters, err := utils.JobParameters(job, *scheduleCmdParameterSlice)
+		if err != nil {
+			return err
+		}
+
+		schedule, err := hope.NewJobSchedule(job, parameters, scheduleCmdNamespace)
+		if err != nil {
+			return err
+		}
+
+		if scheduleCmdPrint {
+			objects, err := schedule.Objects()
+			if err != nil {
+				return err
+			}
+
+			for _, object := range objects {
+				manifest, err := yaml.Marshal(object.Object)
+				if err != nil {
+					return err
+				}
+
+				fmt.Printf("---\n%s", manifest)
+			}
+
+			return nil
+		}
+
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		if err := hope.ApplyJobSchedule(kubectl, schedule); err != nil {
+			return err
+		}
+
+		log.Info("Scheduled job ", job.Name, " to run on schedule ", job.Schedule)
+		return nil
+	},
+}
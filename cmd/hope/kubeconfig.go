@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"time"
 )
 
 import (
@@ -12,19 +15,35 @@ import (
 import (
 	"github.com/Eagerod/hope/cmd/hope/utils"
 	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
 var kubeconfigCmdMergeFlag bool
+var kubeconfigCmdOutput string
+var kubeconfigCmdServiceAccount string
+var kubeconfigCmdNamespace string
+var kubeconfigCmdDuration time.Duration
+var kubeconfigCmdAudiences []string
+var kubeconfigCmdLongLived bool
 
 func initKubeconfigCmdFlags() {
 	kubeconfigCmd.Flags().BoolVarP(&kubeconfigCmdMergeFlag, "merge", "", false, "merge the kubeconfigs if one is already present locally")
+	kubeconfigCmd.Flags().StringVarP(&kubeconfigCmdOutput, "output", "o", "", "write the kubeconfig to the given file on its own, instead of the local kubeconfig")
+	kubeconfigCmd.Flags().StringVarP(&kubeconfigCmdServiceAccount, "service-account", "", "", "print a kubeconfig that connects as the given service account, instead of fetching the admin kubeconfig")
+	kubeconfigCmd.Flags().StringVarP(&kubeconfigCmdNamespace, "namespace", "n", "kube-system", "namespace of the service account")
+	kubeconfigCmd.Flags().DurationVarP(&kubeconfigCmdDuration, "duration", "", time.Hour, "how long the service account's token is valid for")
+	kubeconfigCmd.Flags().StringArrayVarP(&kubeconfigCmdAudiences, "audience", "", []string{}, "audience of the service account's token; can be given multiple times")
+	kubeconfigCmd.Flags().BoolVarP(&kubeconfigCmdLongLived, "long-lived", "", false, "use a long-lived token from a service account token secret, creating it if needed")
 }
 
 var kubeconfigCmd = &cobra.Command{
 	Use:   "kubeconfig [node-name]",
 	Short: "Fetch the kubeconfig from a master node",
-	Long:  "Fetch the kubeconfig from a master node. If a node-name is given, fetches from that node. If not provided, will fetch from any node.",
-	Args:  cobra.RangeArgs(0, 1),
+	Long: "Fetch the kubeconfig from a master node. If a node-name is given, fetches from that node. If not provided, will fetch from any node.\n" +
+		"The kubeconfig's cluster, user, and context are named after the configured cluster_name, and its server is the load_balancer_host, when they're set. " +
+		"When merging, entries for the same cluster already in the local kubeconfig are replaced.\n" +
+		"With a service account, a kubeconfig that connects as that service account is written to stdout, or the output file, instead.",
+	Args: cobra.RangeArgs(0, 1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 
 		var master hope.Node
@@ -50,8 +69,40 @@ var kubeconfigCmd = &cobra.Command{
 			return fmt.Errorf("node: %s is not a master node", master.Host)
 		}
 
+		if kubeconfigCmdServiceAccount != "" {
+			if kubeconfigCmdMergeFlag {
+				return errors.New("service account kubeconfigs can't be merged")
+			}
+
+			kubectl, err := kubeutil.NewKubectlFromNode(master.ConnectionString())
+			if err != nil {
+				return err
+			}
+
+			defer kubectl.Destroy()
+
+			cluster, err := utils.KubeconfigCluster(kubectl)
+			if err != nil {
+				return err
+			}
+
+			options := hope.TokenOptions{Duration: kubeconfigCmdDuration, Audiences: kubeconfigCmdAudiences, LongLived: kubeconfigCmdLongLived}
+			kubeconfig, err := hope.ServiceAccountKubeconfig(log.WithFields(log.Fields{}), kubectl, cluster, kubeconfigCmdNamespace, kubeconfigCmdServiceAccount, options)
+			if err != nil {
+				return err
+			}
+
+			if kubeconfigCmdOutput != "" {
+				return os.WriteFile(kubeconfigCmdOutput, kubeconfig, 0600)
+			}
+
+			_, err = os.Stdout.Write(kubeconfig)
+			return err
+		}
+
 		log.Debug("Fetching admin kubeconfig file from ", master.Host)
 
-		return hope.FetchKubeconfig(log.WithFields(log.Fields{}), &master, kubeconfigCmdMergeFlag)
+		options := utils.KubeconfigOptions(kubeconfigCmdMergeFlag, kubeconfigCmdOutput)
+		return hope.FetchKubeconfig(log.WithFields(log.Fields{}), &master, options)
 	},
 }
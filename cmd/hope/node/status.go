@@ -4,7 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
+	"sync"
 	"text/tabwriter"
+	"time"
 )
 
 import (
@@ -15,14 +19,73 @@ import (
 import (
 	"github.com/Eagerod/hope/cmd/hope/utils"
 	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/hope/hypervisors"
 	"github.com/Eagerod/hope/pkg/kubeutil"
-	"github.com/Eagerod/hope/pkg/ssh"
 )
 
+// Node conditions, other than Ready, that should be False on a healthy node.
+var unhealthyNodeConditions = []string{
+	"MemoryPressure",
+	"DiskPressure",
+	"PIDPressure",
+	"NetworkUnavailable",
+}
+
 var statusCmdTypeSlice *[]string
+var statusCmdShowChecks bool
+var statusCmdMinDatastoreFree int
+var statusCmdParallelism int
+var statusCmdCheckTimeout time.Duration
+var statusCmdWatch bool
+var statusCmdWatchInterval time.Duration
 
 func initStatusCmd() {
 	statusCmdTypeSlice = statusCmd.Flags().StringArrayP("type", "t", []string{}, "fetch status of nodes of this type")
+	statusCmd.Flags().BoolVarP(&statusCmdShowChecks, "checks", "c", false, "print the result of every check run against each node")
+	statusCmd.Flags().IntVarP(&statusCmdMinDatastoreFree, "min-datastore-free", "", 10, "percentage of free datastore space below which a hypervisor is unhealthy")
+	statusCmd.Flags().IntVarP(&statusCmdParallelism, "parallelism", "j", 4, "maximum number of nodes to check at the same time")
+	statusCmd.Flags().DurationVarP(&statusCmdCheckTimeout, "check-timeout", "", 30*time.Second, "how long any single check can take before it's considered failed")
+	statusCmd.Flags().BoolVarP(&statusCmdWatch, "watch", "w", false, "keep refreshing node statuses, and log any changes")
+	statusCmd.Flags().DurationVarP(&statusCmdWatchInterval, "interval", "", 10*time.Second, "time between refreshes when watching")
+}
+
+// ErrCheckTimedOut - A status check didn't finish within the check timeout.
+var ErrCheckTimedOut error = errors.New("timed out")
+
+// nodeStatusChecker - Shared state used while checking the status of many
+// nodes at once.
+// Everything here is fetched once, before any checks run, and is only read
+// from afterwards.
+type nodeStatusChecker struct {
+	kubectl     *kubeutil.Kubectl
+	hypervisors map[string]hypervisors.Hypervisor
+	masterHosts []string
+	timeout     time.Duration
+}
+
+// Run the given check, giving up on it if it takes longer than the check
+// timeout.
+// The underlying commands can't be cancelled, so a check that times out is
+// left to finish in the background, and its result is discarded.
+func withTimeout[T any](timeout time.Duration, check func() (T, error)) (T, error) {
+	type result struct {
+		value T
+		err   error
+	}
+
+	c := make(chan result, 1)
+	go func() {
+		value, err := check()
+		c <- result{value, err}
+	}()
+
+	select {
+	case r := <-c:
+		return r.value, r.err
+	case <-time.After(timeout):
+		var zero T
+		return zero, ErrCheckTimedOut
+	}
 }
 
 var statusCmd = &cobra.Command{
@@ -61,72 +124,57 @@ var statusCmd = &cobra.Command{
 			}
 		}
 
-		// If there are any Kubernetes nodes to be checked, cache a kubectl
-		//   instance.
-		var kubectl *kubeutil.Kubectl
+		if statusCmdParallelism <= 0 {
+			return errors.New("parallelism must be at least 1")
+		}
+
+		checker := nodeStatusChecker{timeout: statusCmdCheckTimeout}
 		defer func() {
-			if kubectl != nil {
-				kubectl.Destroy()
+			if checker.kubectl != nil {
+				checker.kubectl.Destroy()
 			}
 		}()
 
-		for _, node := range nodes {
-			if node.IsKubernetesNode() {
-				kubectl, err = utils.KubectlFromAnyMaster()
-				if err != nil {
-					return err
-				}
-				break
-			}
+		if err := checker.prepare(nodes); err != nil {
+			return err
 		}
 
-		nodeStatuses := map[string]hope.NodeStatus{}
-		shouldFail := false
-		for _, node := range nodes {
-			nodeStatuses[node.Name] = hope.NodeStatusUnavailable
-
-			switch node.Role {
-			case hope.NodeRoleLoadBalancer.String():
-				var status hope.NodeStatus
-				status, err = loadBalancerNodeStatus(node)
-				if err != nil {
-					return err
-				}
-
-				nodeStatuses[node.Name] = status
-			case hope.NodeRoleMaster.String(),
-				hope.NodeRoleMasterAndNode.String(),
-				hope.NodeRoleNode.String():
-				var status hope.NodeStatus
-				status, err = kubernetesNodeStatus(kubectl, node)
+		if statusCmdWatch {
+			return utils.Watch(statusCmdWatchInterval, func() (*utils.WatchTable, error) {
+				reports, err := checker.nodeStatuses(nodes)
 				if err != nil {
-					return err
+					return nil, err
 				}
 
-				nodeStatuses[node.Name] = status
-			case hope.NodeRoleHypervisor.String():
-				var status hope.NodeStatus
-				status, err = hypervisorNodeStatus(node)
-				if err != nil {
-					return err
-				}
+				return nodeStatusWatchTable(nodes, reports), nil
+			})
+		}
 
-				nodeStatuses[node.Name] = status
-			default:
-				return fmt.Errorf("unknown node type: %s", node.Role)
-			}
+		reports, err := checker.nodeStatuses(nodes)
+		if err != nil {
+			return err
+		}
 
-			if nodeStatuses[node.Name] != hope.NodeStatusHealthy {
+		shouldFail := false
+		writer := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
+		fmt.Fprintln(writer, "Node\tStatus\tReason\t")
+		for i, node := range nodes {
+			report := reports[i]
+			if report.Status != hope.NodeStatusHealthy {
 				shouldFail = true
 			}
-		}
 
-		// Order output by the order in the yaml file, rather than iterating
-		//   over the map.
-		writer := tabwriter.NewWriter(os.Stdout, 1, 1, 1, ' ', 0)
-		fmt.Fprintln(writer, "Node\tStatus\t")
-		for _, node := range nodes {
-			fmt.Fprintf(writer, "%s\t%s\t\n", node.Name, nodeStatuses[node.Name])
+			fmt.Fprintf(writer, "%s\t%s\t%s\t\n", node.Name, report.Status, report.Reason)
+
+			if statusCmdShowChecks {
+				for _, check := range report.Checks {
+					result := "ok"
+					if !check.Passed {
+						result = "FAIL"
+					}
+					fmt.Fprintf(writer, "  %s\t%s\t%s\t\n", check.Name, result, check.Detail)
+				}
+			}
 		}
 		writer.Flush()
 
@@ -138,63 +186,320 @@ var statusCmd = &cobra.Command{
 	},
 }
 
-func kubernetesNodeStatus(kubectl *kubeutil.Kubectl, node hope.Node) (hope.NodeStatus, error) {
-	status, err := kubeutil.GetKubectl(
-		kubectl, "get", "node", node.Name,
-		"-o", "template={{range .status.conditions}}{{if eq .reason \"KubeletReady\"}}{{.status}}{{end}}{{end}}")
-	if err == nil && status == "True" {
-		return hope.NodeStatusHealthy, nil
-	} else if err == nil {
-		return hope.NodeStatusUnavailable, nil
+// Fetch everything that checks across many nodes would otherwise each
+// fetch for themselves.
+// If there are any Kubernetes nodes to be checked, cache a kubectl instance.
+// If there are any load balancers, find the masters they should be pointing
+// at.
+func (c *nodeStatusChecker) prepare(nodes []hope.Node) error {
+	allHypervisors, err := utils.GetHypervisors()
+	if err != nil {
+		return err
+	}
+
+	c.hypervisors = map[string]hypervisors.Hypervisor{}
+	for _, hypervisor := range allHypervisors {
+		hvNode, err := hypervisor.UnderlyingNode()
+		if err != nil {
+			return err
+		}
+		c.hypervisors[hvNode.Name] = hypervisor
+	}
+
+	for _, node := range nodes {
+		if node.IsKubernetesNode() && c.kubectl == nil {
+			c.kubectl, err = utils.KubectlFromAnyMaster()
+			if err != nil {
+				return err
+			}
+		}
+
+		if node.IsLoadBalancer() && c.masterHosts == nil {
+			masters, err := utils.GetAvailableMasters()
+			if err != nil {
+				return err
+			}
+
+			c.masterHosts = []string{}
+			for _, master := range masters {
+				c.masterHosts = append(c.masterHosts, master.Host)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Check all of the given nodes, running several at once.
+// Each node writes its report into its own slot, so reports come back in the
+// same order as the nodes, regardless of which checks finish first.
+func (c *nodeStatusChecker) nodeStatuses(nodes []hope.Node) ([]hope.NodeStatusReport, error) {
+	reports := make([]hope.NodeStatusReport, len(nodes))
+	errs := make([]error, len(nodes))
+	semaphore := make(chan bool, statusCmdParallelism)
+
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- true
+			defer func() { <-semaphore }()
+
+			log.Tracef("Checking status of %s", node.Name)
+			reports[i], errs[i] = c.nodeStatus(node)
+		}()
+	}
+	wg.Wait()
+
+	return reports, errors.Join(errs...)
+}
+
+// Summarize the most interesting checks of each node into a table that can
+// be watched for changes.
+func nodeStatusWatchTable(nodes []hope.Node, reports []hope.NodeStatusReport) *utils.WatchTable {
+	table := utils.WatchTable{
+		Headers: []string{"Node", "Status", "VM", "Kubelet", "Reason"},
+	}
+
+	for i, node := range nodes {
+		vm, kubelet := "-", "-"
+		for _, check := range reports[i].Checks {
+			switch check.Name {
+			case "vm":
+				vm = check.Detail
+			case "kubelet":
+				kubelet = check.Detail
+			}
+		}
+
+		reason := reports[i].Reason
+		if reason == "" {
+			reason = "-"
+		}
+
+		columns := []string{node.Name, reports[i].Status.String(), vm, kubelet, reason}
+		table.Rows = append(table.Rows, utils.WatchRow{Key: node.Name, Columns: columns})
+	}
+
+	return &table
+}
+
+func (c *nodeStatusChecker) nodeStatus(node hope.Node) (hope.NodeStatusReport, error) {
+	switch node.Role {
+	case hope.NodeRoleLoadBalancer.String():
+		return c.loadBalancerNodeStatus(node)
+	case hope.NodeRoleMaster.String(),
+		hope.NodeRoleMasterAndNode.String(),
+		hope.NodeRoleNode.String():
+		return c.kubernetesNodeStatus(node)
+	case hope.NodeRoleHypervisor.String():
+		return c.hypervisorNodeStatus(node)
 	}
 
-	hypervisor, err := utils.GetHypervisor(node.Hypervisor)
+	return hope.NodeStatusReport{}, fmt.Errorf("unknown node type: %s", node.Role)
+}
+
+func (c *nodeStatusChecker) hypervisor(name string) (hypervisors.Hypervisor, error) {
+	hypervisor, ok := c.hypervisors[name]
+	if !ok {
+		return nil, fmt.Errorf("failed to find a hypervisor named %s", name)
+	}
+
+	return hypervisor, nil
+}
+
+// Check that the node's VM exists, is running, and has an IP address.
+// Returns the node with its host resolved, and whether or not any further
+// checks can be run against it.
+// Nodes without a hypervisor are assumed to exist at their given host.
+func (c *nodeStatusChecker) vmNodeStatus(report *hope.NodeStatusReport, node hope.Node) (hope.Node, bool, error) {
+	if node.Hypervisor == "" {
+		return node, true, nil
+	}
+
+	hypervisor, err := c.hypervisor(node.Hypervisor)
 	if err != nil {
-		return hope.NodeStatusUnavailable, err
+		return node, false, err
+	}
+
+	powerState, err := withTimeout(c.timeout, func() (string, error) {
+		return hypervisor.VMPowerState(node.Name)
+	})
+	if err == ErrCheckTimedOut {
+		report.AddCheck("vm", false, fmt.Sprintf("hypervisor %s %s", node.Hypervisor, err))
+		return node, false, nil
+	} else if errors.Is(err, hypervisors.ErrVMNotFound) {
+		log.Debugf("Failed to get power state of %s: %s", node.Name, err)
+		report.AddCheck("vm", false, fmt.Sprintf("not found on hypervisor %s", node.Hypervisor))
+		report.Status = hope.NodeStatusDoesNotExist
+		return node, false, nil
+	} else if err != nil {
+		report.AddCheck("vm", false, fmt.Sprintf("failed to get power state from hypervisor %s: %s", node.Hypervisor, err))
+		return node, false, nil
 	}
 
-	_, err = hypervisor.VMIPAddress(node.Name)
+	if powerState != hypervisors.VMPowerStateOn {
+		report.AddCheck("vm", false, fmt.Sprintf("powered %s", powerState))
+		return node, false, nil
+	}
+	report.AddCheck("vm", true, fmt.Sprintf("powered %s", powerState))
+
+	ip, err := withTimeout(c.timeout, func() (string, error) {
+		return hypervisor.VMIPAddress(node.Name)
+	})
 	if err != nil {
-		return hope.NodeStatusDoesNotExist, nil
+		report.AddCheck("ip-address", false, err.Error())
+		return node, false, nil
 	}
+	report.AddCheck("ip-address", true, ip)
 
-	log.Debugf("VM %s exists with an IP address, but isn't a Kubernetes node.", node.Name)
-	return hope.NodeStatusUnavailable, nil
+	node.Hypervisor = ""
+	node.Host = ip
+	return node, true, nil
 }
 
-func loadBalancerNodeStatus(node hope.Node) (hope.NodeStatus, error) {
-	hypervisor, err := utils.GetHypervisor(node.Hypervisor)
+func (c *nodeStatusChecker) sshNodeStatus(report *hope.NodeStatusReport, node hope.Node) bool {
+	_, err := withTimeout(c.timeout, func() (bool, error) {
+		return true, hope.TestCanSSHWithoutPassword(&node)
+	})
 	if err != nil {
-		return hope.NodeStatusUnavailable, err
+		report.AddCheck("ssh", false, fmt.Sprintf("cannot connect to %s", node.ConnectionString()))
+		return false
 	}
 
-	resolvedNode, err := hypervisor.ResolveNode(node)
-	if err != nil {
-		return hope.NodeStatusDoesNotExist, nil
+	report.AddCheck("ssh", true, fmt.Sprintf("connected to %s", node.ConnectionString()))
+	return true
+}
+
+func (c *nodeStatusChecker) kubernetesNodeStatus(node hope.Node) (hope.NodeStatusReport, error) {
+	report := hope.NodeStatusReport{Status: hope.NodeStatusHealthy}
+
+	resolvedNode, reachable, err := c.vmNodeStatus(&report, node)
+	if err != nil || !reachable {
+		return report, err
+	}
+
+	c.sshNodeStatus(&report, resolvedNode)
+
+	conditions, err := withTimeout(c.timeout, func() (map[string]string, error) {
+		return kubeutil.NodeConditions(c.kubectl, node.Name)
+	})
+	if err == ErrCheckTimedOut {
+		report.AddCheck("kubelet", false, fmt.Sprintf("fetching node from the cluster %s", err))
+		return report, nil
+	} else if err != nil {
+		log.Debugf("VM %s exists, but isn't a Kubernetes node.", node.Name)
+		report.AddCheck("kubelet", false, "not registered with the cluster")
+		return report, nil
+	}
+
+	if ready := conditions["Ready"]; ready != "True" {
+		report.AddCheck("kubelet", false, fmt.Sprintf("Ready is %s", ready))
 	} else {
-		cmd := []string{
-			resolvedNode.ConnectionString(),
-			"sudo",
-			"docker",
-			"ps",
-			"--filter",
-			"publish=6443",
-			"--quiet",
-		}
-		output, err := ssh.GetSSH(cmd...)
-		if err != nil || output == "" {
-			return hope.NodeStatusUnavailable, nil
-		} else {
-			return hope.NodeStatusHealthy, nil
+		report.AddCheck("kubelet", true, "Ready")
+	}
+
+	for _, condition := range unhealthyNodeConditions {
+		status, ok := conditions[condition]
+		if !ok {
+			continue
 		}
+
+		report.AddCheck(condition, status == "False", fmt.Sprintf("%s is %s", condition, status))
 	}
+
+	return report, nil
 }
 
-func hypervisorNodeStatus(node hope.Node) (hope.NodeStatus, error) {
-	err := ssh.ExecSSH(node.ConnectionString(), "exit")
+func (c *nodeStatusChecker) loadBalancerNodeStatus(node hope.Node) (hope.NodeStatusReport, error) {
+	report := hope.NodeStatusReport{Status: hope.NodeStatusHealthy}
+
+	resolvedNode, reachable, err := c.vmNodeStatus(&report, node)
+	if err != nil || !reachable {
+		return report, err
+	}
+
+	if !c.sshNodeStatus(&report, resolvedNode) {
+		return report, nil
+	}
+
+	container, err := withTimeout(c.timeout, func() (string, error) {
+		return hope.GetLoadBalancerContainer(&resolvedNode)
+	})
+	if err != nil || container == "" {
+		report.AddCheck("nginx", false, "no container publishing port 6443")
+		return report, nil
+	}
+	report.AddCheck("nginx", true, fmt.Sprintf("container %s running", container))
+
+	upstreams, err := withTimeout(c.timeout, func() ([]string, error) {
+		return hope.GetLoadBalancerHosts(log.WithFields(log.Fields{}), &resolvedNode)
+	})
+	if err != nil {
+		report.AddCheck("upstreams", false, "failed to read nginx configuration")
+		return report, nil
+	}
+
+	missing := []string{}
+	for _, host := range c.masterHosts {
+		if !slices.Contains(upstreams, host) {
+			missing = append(missing, host)
+		}
+	}
+
+	unexpected := []string{}
+	for _, host := range upstreams {
+		if !slices.Contains(c.masterHosts, host) {
+			unexpected = append(unexpected, host)
+		}
+	}
+
+	if len(missing) != 0 || len(unexpected) != 0 {
+		details := []string{}
+		if len(missing) != 0 {
+			details = append(details, fmt.Sprintf("missing %s", strings.Join(missing, ", ")))
+		}
+		if len(unexpected) != 0 {
+			details = append(details, fmt.Sprintf("unexpected %s", strings.Join(unexpected, ", ")))
+		}
+		report.AddCheck("upstreams", false, strings.Join(details, "; "))
+	} else {
+		report.AddCheck("upstreams", true, strings.Join(upstreams, ", "))
+	}
+
+	return report, nil
+}
+
+func (c *nodeStatusChecker) hypervisorNodeStatus(node hope.Node) (hope.NodeStatusReport, error) {
+	report := hope.NodeStatusReport{Status: hope.NodeStatusHealthy}
+
+	if !c.sshNodeStatus(&report, node) {
+		return report, nil
+	}
+
+	hypervisor, err := c.hypervisor(node.Name)
+	if err != nil {
+		return report, err
+	}
+
+	usage, err := withTimeout(c.timeout, func() ([2]uint64, error) {
+		size, free, err := hypervisor.DatastoreUsage()
+		return [2]uint64{size, free}, err
+	})
 	if err != nil {
-		return hope.NodeStatusUnavailable, nil
+		report.AddCheck("datastore", false, err.Error())
+		return report, nil
+	}
+
+	size, free := usage[0], usage[1]
+	freePercent := 0
+	if size != 0 {
+		freePercent = int(free * 100 / size)
 	}
 
-	return hope.NodeStatusHealthy, nil
+	detail := fmt.Sprintf("%s has %d%% free (%d GiB)", node.Datastore, freePercent, free/(1<<30))
+	report.AddCheck("datastore", freePercent >= statusCmdMinDatastoreFree, detail)
+
+	return report, nil
 }
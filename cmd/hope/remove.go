@@ -13,14 +13,22 @@ import (
 
 import (
 	"github.com/Eagerod/hope/cmd/hope/utils"
-	"github.com/Eagerod/hope/pkg/helm"
 	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
 var removeCmdTagSlice *[]string
+var removeCmdKeepHistory bool
+var removeCmdNamespaces bool
+var removeCmdCRDs bool
+var removeCmdYes bool
 
 func initRemoveCmdFlags() {
 	removeCmdTagSlice = removeCmd.Flags().StringArrayP("tag", "t", []string{}, "remove resources with this tag")
+	removeCmd.Flags().BoolVar(&removeCmdKeepHistory, "keep-history", false, "keep the release history of uninstalled helm releases")
+	removeCmd.Flags().BoolVar(&removeCmdNamespaces, "namespaces", false, "also remove namespaces created by the resources")
+	removeCmd.Flags().BoolVar(&removeCmdCRDs, "crds", false, "also remove custom resource definitions created by the resources")
+	removeCmd.Flags().BoolVarP(&removeCmdYes, "yes", "y", false, "remove without asking for confirmation")
 }
 
 var removeCmd = &cobra.Command{
@@ -50,87 +58,67 @@ var removeCmd = &cobra.Command{
 
 		defer kubectl.Destroy()
 
-		// TODO: Should be done in hope pkg
+		options := hope.RemovalOptions{
+			KeepHistory: removeCmdKeepHistory,
+			Namespaces:  removeCmdNamespaces,
+			CRDs:        removeCmdCRDs,
+		}
+
+		// Find everything that's going to be deleted before deleting any of
+		//   it, so it can all be confirmed at once.
+		// A resource that can't be planned doesn't stop the others from
+		//   being removed.
+		steps := []hope.RemovalStep{}
+		planErrs := []error{}
 		for i := len(*resources) - 1; i >= 0; i-- {
 			resource := (*resources)[i]
-			log.Debug("Starting removal of ", resource.Name)
-			resourceType, err := resource.GetType()
-			if err != nil {
-				return err
-			}
+			log.Debug("Planning removal of ", resource.Name)
 
-			parameters, err := utils.FlattenParameters(resource.Parameters, resource.FileParameters)
+			resourceSteps, err := planResourceRemoval(kubectl, &resource, options)
 			if err != nil {
-				return err
+				log.Error("Failed to plan removal of ", resource.Name, ": ", err)
+				planErrs = append(planErrs, fmt.Errorf("%s: %w", resource.Name, err))
+				continue
 			}
 
-			// It is possible that names of resources are created using
-			//   templated values, so still do the environment substitution
-			//   process.
-			switch resourceType {
-			case hope.ResourceTypeFile:
-				if len(parameters) != 0 {
-					if info, err := os.Stat(resource.File); err != nil {
-						return err
-					} else if info.IsDir() {
-						log.Trace("Deleting directory with parameters; creating copy for parameter substitution.")
-						tempDir, err := hope.ReplaceParametersInDirectoryCopy(resource.File, parameters)
-						if err != nil {
-							return err
-						}
-						defer os.RemoveAll(tempDir)
-
-						if err := hope.KubectlDeleteF(kubectl, tempDir); err != nil {
-							return err
-						}
-					} else {
-						content, err := hope.ReplaceParametersInFile(resource.File, parameters)
-						if err != nil {
-							return err
-						}
-
-						if err := hope.KubectlDeleteStdIn(kubectl, content); err != nil {
-							return err
-						}
-					}
-				} else {
-					log.Trace(resource.Name, " does not have any parameters. Skipping population and deleting file directly")
-					if err := hope.KubectlDeleteF(kubectl, resource.File); err != nil {
-						return err
-					}
-				}
-			case hope.ResourceTypeInline:
-				inline := resource.Inline
-
-				// Log out the inline resource before substituting it; secrets
-				//   are likely being populated.
-				log.Trace(inline)
-
-				if len(parameters) != 0 {
-					inline, err = hope.ReplaceParametersInString(inline, parameters)
-					if err != nil {
-						return err
-					}
-				} else {
-					log.Trace(resource.Name, " does not have any parameters. Skipping population.")
-				}
-
-				if err := hope.KubectlDeleteStdIn(kubectl, inline); err != nil {
-					return err
-				}
-			case hope.ResourceTypeDockerBuild:
-				log.Debug("Skipping removal of docker image.")
-			case hope.ResourceTypeJob:
-				log.Debug("Skipping removal of job resource type.")
-			case hope.ResourceTypeExec:
-				log.Debug("Skipping removal of exec resource type.")
-			case hope.ResourceTypeHelm:
-				return helm.ExecHelm("uninstall", resource.Helm.Release)
-			default:
-				return fmt.Errorf("resource type (%s) not implemented", resourceType)
+			steps = append(steps, resourceSteps...)
+		}
+
+		var planErr error
+		if len(planErrs) != 0 {
+			planErr = fmt.Errorf("failed to plan removal of %d of %d resources:\n%w", len(planErrs), len(*resources), errors.Join(planErrs...))
+		}
+
+		if len(steps) == 0 {
+			if planErr != nil {
+				return planErr
 			}
+
+			log.Info("Nothing to remove.")
+			return nil
+		}
+
+		fmt.Fprintln(os.Stdout, "The following will be deleted:")
+		for _, step := range steps {
+			fmt.Fprintf(os.Stdout, "  %s (%s)\n", step.Description, step.Resource)
+		}
+
+		if !removeCmdYes && !utils.Confirm(os.Stdin, os.Stdout, "Continue?") {
+			return errors.New("removal cancelled")
 		}
 
-		return nil
+		return errors.Join(planErr, hope.RemoveResources(log.WithFields(log.Fields{}), steps))
 	},
 }
+
+func planResourceRemoval(kubectl *kubeutil.Kubectl, resource *hope.Resource, options hope.RemovalOptions) ([]hope.RemovalStep, error) {
+	// It is possible that names of resources are created using templated
+	//   values, so still do the environment substitution process;
+	//   parameters aren't asked for, though.
+	parameters, err := utils.KnownResourceParameters(resource)
+	if err != nil {
+		return nil, err
+	}
+
+	return hope.PlanResourceRemoval(log.WithFields(log.Fields{}), kubectl, resource, parameters, options)
+}
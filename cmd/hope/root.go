@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 )
@@ -14,16 +16,20 @@ import (
 )
 
 import (
+	"github.com/Eagerod/hope/cmd/hope/jobs"
 	"github.com/Eagerod/hope/cmd/hope/node"
 	"github.com/Eagerod/hope/cmd/hope/unifi"
+	"github.com/Eagerod/hope/cmd/hope/user"
 	"github.com/Eagerod/hope/cmd/hope/utils"
 	"github.com/Eagerod/hope/cmd/hope/vm"
 
-	"github.com/Eagerod/hope/pkg/docker"
+	"github.com/Eagerod/hope/pkg/buildkit"
 	"github.com/Eagerod/hope/pkg/envsubst"
 	"github.com/Eagerod/hope/pkg/helm"
+	"github.com/Eagerod/hope/pkg/hope"
 	"github.com/Eagerod/hope/pkg/kubeutil"
 	"github.com/Eagerod/hope/pkg/packer"
+	"github.com/Eagerod/hope/pkg/registry"
 	"github.com/Eagerod/hope/pkg/scp"
 	"github.com/Eagerod/hope/pkg/ssh"
 )
@@ -78,29 +84,38 @@ var rootCmd = &cobra.Command{
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
+	rootCmd.AddCommand(cpCmd)
 	rootCmd.AddCommand(deployCmd)
+	rootCmd.AddCommand(forwardCmd)
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(kubeconfigCmd)
 	rootCmd.AddCommand(listCmd)
 	rootCmd.AddCommand(removeCmd)
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(tokenCmd)
 
+	rootCmd.AddCommand(jobs.RootCommand)
 	rootCmd.AddCommand(node.RootCommand)
 	rootCmd.AddCommand(unifi.RootCommand)
+	rootCmd.AddCommand(user.RootCommand)
 	rootCmd.AddCommand(vm.RootCommand)
 
+	initCpCmdFlags()
 	initDeployCmdFlags()
 	initKubeconfigCmdFlags()
 	initListCmdFlags()
 	initRemoveCmdFlags()
 	initRunCmdFlags()
 	initShellCmd()
+	initStatusCmdFlags()
 	initTokenCmd()
 
+	jobs.InitJobsCommand()
 	node.InitNodeCommand()
 	unifi.InitUnifiCommand()
+	user.InitUserCommand()
 	vm.InitVMCommand()
 
 	if err := rootCmd.Execute(); err != nil {
@@ -109,7 +124,7 @@ func Execute() {
 }
 
 func init() {
-	cobra.OnInitialize(initConfig, initLogger, patchInvocations)
+	cobra.OnInitialize(initConfig, initLogger, initCaches, patchInvocations)
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.hope.yaml)")
 	rootCmd.PersistentFlags().BoolVar(&debugLogFlag, "debug", false, "set the log level to debug; ignoring otherwise configured log levels")
@@ -179,27 +194,28 @@ func initLogger() {
 	log.Debug("Using config file: ", viper.ConfigFileUsed())
 }
 
+func initCaches() {
+	hope.RemoteFileCacheDir = viper.GetString("remote_file_cache")
+	hope.ImageDigestCachePath = viper.GetString("image_digest_cache")
+	hope.KubeconfigCacheTTL = viper.GetDuration("kubeconfig_cache_ttl")
+	hope.KubeconfigCacheDir = viper.GetString("kubeconfig_cache")
+	hope.KubeconfigCacheKeyPath = viper.GetString("kubeconfig_cache_key")
+	registry.InsecureRegistries = viper.GetStringSlice("insecure_registries")
+}
+
 func patchInvocations() {
 	// TODO: Probably create a single os.Exec wrapper of some kind, cause this
 	//   is getting ridiculous.
-	oldExecDocker := docker.ExecDocker
-	docker.ExecDocker = func(args ...string) error {
-		if docker.UseSudo {
-			log.Debug("sudo docker ", strings.Join(args, " "))
-		} else {
-			log.Debug("docker ", strings.Join(args, " "))
-		}
-		return oldExecDocker(args...)
+	oldExecBuildctl := buildkit.ExecBuildctl
+	buildkit.ExecBuildctl = func(args ...string) error {
+		log.Debug("buildctl ", strings.Join(args, " "))
+		return oldExecBuildctl(args...)
 	}
 
-	oldGetDocker := docker.GetDocker
-	docker.GetDocker = func(args ...string) (string, error) {
-		if docker.UseSudo {
-			log.Debug("sudo docker ", strings.Join(args, " "))
-		} else {
-			log.Debug("docker ", strings.Join(args, " "))
-		}
-		return oldGetDocker(args...)
+	oldExecBuildctlDaemonless := buildkit.ExecBuildctlDaemonless
+	buildkit.ExecBuildctlDaemonless = func(args ...string) error {
+		log.Debug("buildctl-daemonless.sh ", strings.Join(args, " "))
+		return oldExecBuildctlDaemonless(args...)
 	}
 
 	oldEnvsubstBytes := envsubst.GetEnvsubstBytes
@@ -224,12 +240,30 @@ func patchInvocations() {
 		return oldEnvsubstBytesArgs(args, contents)
 	}
 
+	oldGetRemoteFile := hope.GetRemoteFile
+	hope.GetRemoteFile = func(url string) ([]byte, error) {
+		log.Debug("GET ", url)
+		return oldGetRemoteFile(url)
+	}
+
 	oldExecKubectl := kubeutil.ExecKubectl
 	kubeutil.ExecKubectl = func(kubectl *kubeutil.Kubectl, args ...string) error {
 		log.Debug("kubectl ", strings.Join(args, " "))
 		return oldExecKubectl(kubectl, args...)
 	}
 
+	oldExecInPod := kubeutil.ExecInPod
+	kubeutil.ExecInPod = func(ctx context.Context, kubectl *kubeutil.Kubectl, namespace, pod string, options kubeutil.ExecOptions) error {
+		log.Debug("exec ", namespace, "/", pod, " -- ", strings.Join(options.Command, " "))
+		return oldExecInPod(ctx, kubectl, namespace, pod, options)
+	}
+
+	oldPortForward := kubeutil.PortForward
+	kubeutil.PortForward = func(ctx context.Context, kubectl *kubeutil.Kubectl, namespace, pod string, localPort, remotePort int, ready chan struct{}, errOut io.Writer) error {
+		log.Debug("port-forward ", namespace, "/", pod, " ", localPort, ":", remotePort)
+		return oldPortForward(ctx, kubectl, namespace, pod, localPort, remotePort, ready, errOut)
+	}
+
 	oldGetKubectl := kubeutil.GetKubectl
 	kubeutil.GetKubectl = func(kubectl *kubeutil.Kubectl, args ...string) (string, error) {
 		log.Debug("kubectl ", strings.Join(args, " "))
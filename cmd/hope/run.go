@@ -1,8 +1,8 @@
 package cmd
 
 import (
-	"fmt"
-	"strings"
+	"os"
+	"time"
 )
 
 import (
@@ -16,9 +16,23 @@ import (
 )
 
 var runCmdParameterSlice *[]string
+var runCmdNamespace string
+var runCmdTimeout string
+var runCmdMaxAttempts int
+var runCmdLogFile string
+var runCmdTTL string
+var runCmdRemove bool
+var runCmdHelpParams bool
 
 func initRunCmdFlags() {
 	runCmdParameterSlice = runCmd.Flags().StringArrayP("param", "p", []string{}, "parameters to populate in the job yaml")
+	runCmd.Flags().StringVarP(&runCmdNamespace, "namespace", "n", "", "namespace to create the job in, if the job yaml doesn't set one")
+	runCmd.Flags().StringVar(&runCmdTimeout, "timeout", "", "how long to wait for the job to finish, overriding the job's timeout")
+	runCmd.Flags().IntVar(&runCmdMaxAttempts, "max-attempts", 0, "how many times to check on the job before giving up, overriding the job's max attempts")
+	runCmd.Flags().StringVar(&runCmdLogFile, "log-file", "", "file to also write the job's logs to, overriding the job's log file")
+	runCmd.Flags().StringVar(&runCmdTTL, "ttl", "", "how long the cluster keeps the job after it finishes, overriding the job's ttl")
+	runCmd.Flags().BoolVar(&runCmdRemove, "rm", false, "delete the job once it completes successfully")
+	runCmd.Flags().BoolVar(&runCmdHelpParams, "help-params", false, "list the job's parameters instead of running it")
 }
 
 var runCmd = &cobra.Command{
@@ -33,34 +47,14 @@ var runCmd = &cobra.Command{
 			return err
 		}
 
-		// Combine args given from the command line, and ones not given to let
-		//   the parameter substitution fall back to env when available.
-		// Would probably be faster to just populate from the slice, then from
-		//   any remaining args via env, but this adds some extra validation
-		//   that would otherwise go unchecked.
-		fullArgsList := []string{}
-
-		remainingParams := map[string]bool{}
-		for _, param := range job.Parameters {
-			remainingParams[param] = true
+		if runCmdHelpParams {
+			hope.PrintParameters(os.Stdout, job.Parameters)
+			return nil
 		}
 
-		for _, param := range *runCmdParameterSlice {
-			components := strings.SplitN(param, "=", 2)
-			paramName := components[0]
-
-			if _, ok := remainingParams[paramName]; !ok {
-				return fmt.Errorf("parameter: %s not recognized", paramName)
-			}
-
-			remainingParams[paramName] = false
-			fullArgsList = append(fullArgsList, param)
-		}
-
-		for param, missed := range remainingParams {
-			if missed {
-				fullArgsList = append(fullArgsList, param)
-			}
+		fullArgsList, err := utils.JobParameters(job, *runCmdParameterSlice)
+		if err != nil {
+			return err
 		}
 
 		// TODO: Move to pkg
@@ -76,11 +70,44 @@ var runCmd = &cobra.Command{
 
 		defer kubectl.Destroy()
 
-		output, err := hope.KubectlGetCreateStdIn(kubectl, jobText, "-o", "template={{.metadata.namespace}}/{{.metadata.name}}")
+		if cmd.Flags().Changed("timeout") {
+			job.Timeout = runCmdTimeout
+		}
+		if cmd.Flags().Changed("max-attempts") {
+			job.MaxAttempts = runCmdMaxAttempts
+		}
+		if cmd.Flags().Changed("log-file") {
+			job.LogFile = runCmdLogFile
+		}
+		if cmd.Flags().Changed("ttl") {
+			job.TTL = runCmdTTL
+		}
+
+		options, err := hope.NewJobWaitOptions(job.Timeout, job.MaxAttempts, job.LogFile, 12*time.Second)
 		if err != nil {
 			return err
 		}
 
-		return hope.FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, output, 10, 12)
+		run, err := hope.NewJobRun(job, jobText, runCmdNamespace, job.TTL)
+		if err != nil {
+			return err
+		}
+
+		nsRun, err := hope.CreateJobRun(kubectl, run)
+		if err != nil {
+			return err
+		}
+
+		log.Info("Created job ", nsRun)
+		if err := hope.FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, nsRun, options); err != nil {
+			return err
+		}
+
+		if runCmdRemove {
+			log.Debug("Deleting job ", nsRun)
+			return hope.DeleteJobRun(kubectl, nsRun)
+		}
+
+		return nil
 	},
 }
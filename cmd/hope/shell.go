@@ -2,11 +2,12 @@ package cmd
 
 import (
 	"errors"
-	"strings"
+	"os"
 )
 
 import (
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 )
 
 import (
@@ -14,26 +15,48 @@ import (
 	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
+// Starts bash if the container has it, and sh otherwise, without needing a
+// separate exec to check first.
+var shellCmdDefaultCommand = []string{"sh", "-c", "command -v bash >/dev/null 2>&1 && exec bash || exec sh"}
+
 var shellCmdLabelsString string
+var shellCmdNamespace string
+var shellCmdContainer string
+var shellCmdDebugImage string
 
 func initShellCmd() {
 	shellCmd.Flags().StringVarP(&shellCmdLabelsString, "selector", "l", "", "Exec in any pod matching the given selector")
+	shellCmd.Flags().StringVarP(&shellCmdNamespace, "namespace", "n", "default", "Namespace of the pod")
+	shellCmd.Flags().StringVarP(&shellCmdContainer, "container", "c", "", "Container of the pod to exec in, or to target when debugging")
+	shellCmd.Flags().StringVar(&shellCmdDebugImage, "debug-image", "", "Start an ephemeral debug container from this image instead, for pods without a shell (e.g. distroless)")
 }
 
 var shellCmd = &cobra.Command{
-	Use:   "shell [exec args]...",
+	Use:   "shell [pod | kind/name] [exec args]...",
 	Short: "Start a shell, or run a command in the provided pod or any pod matching a label.",
 	Args:  cobra.ArbitraryArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// If the label argument is given, assume all arguments make up the
 		//   command.
 		// If the label argument isn't given, assume the first argument is the
-		//   pod name, and the remainder make up the command.
+		//   pod, or object managing pods, and the remainder make up the
+		//   command.
 		// If no command arguments are given, start an interactive shell.
 		if len(args) == 0 && shellCmdLabelsString == "" {
 			return errors.New("nothing to run against")
 		}
 
+		target := shellCmdLabelsString
+		commandArgs := args
+		if target == "" {
+			target = args[0]
+			commandArgs = args[1:]
+		}
+
+		if len(commandArgs) == 0 {
+			commandArgs = shellCmdDefaultCommand
+		}
+
 		kubectl, err := utils.KubectlFromAnyMaster()
 		if err != nil {
 			return err
@@ -41,38 +64,28 @@ var shellCmd = &cobra.Command{
 
 		defer kubectl.Destroy()
 
-		var podName string
-		var commandArgs []string
-		if shellCmdLabelsString != "" {
-			output, err := kubeutil.GetKubectl(kubectl, "get", "pods", "-l", shellCmdLabelsString, "-o", "template={{range .items}}{{.metadata.name}} {{end}}")
-			if err != nil {
-				return err
-			}
-
-			pods := strings.Split(strings.TrimSpace(output), " ")
-			podName = pods[0]
-			commandArgs = args
-		} else {
-			podName = args[0]
-			commandArgs = args[1:]
+		podName, err := utils.SelectPod(kubectl, shellCmdNamespace, target)
+		if err != nil {
+			return err
 		}
 
-		if len(commandArgs) == 0 {
-			// Check to see if the pod will start a bash shell.
-			if err := kubeutil.ExecKubectl(kubectl, "exec", "-it", podName, "--", "bash", "-c", "exit"); err == nil {
-				return kubeutil.ExecKubectl(kubectl, "exec", "-it", podName, "--", "bash")
+		allArgs := []string{"exec"}
+		if shellCmdDebugImage != "" {
+			allArgs = []string{"debug", "--image", shellCmdDebugImage}
+			if shellCmdContainer != "" {
+				allArgs = append(allArgs, "--target", shellCmdContainer)
 			}
+		} else if shellCmdContainer != "" {
+			allArgs = append(allArgs, "--container", shellCmdContainer)
+		}
 
-			return kubeutil.ExecKubectl(kubectl, "exec", "-it", podName, "--", "sh")
-		} else {
-			allArgs := []string{
-				"exec",
-				"-it",
-				podName,
-				"--",
-			}
-			allArgs = append(allArgs, commandArgs...)
-			return kubeutil.ExecKubectl(kubectl, allArgs...)
+		allArgs = append(allArgs, "--namespace", shellCmdNamespace, "--stdin")
+		if term.IsTerminal(int(os.Stdin.Fd())) {
+			allArgs = append(allArgs, "--tty")
 		}
+
+		allArgs = append(allArgs, podName, "--")
+		allArgs = append(allArgs, commandArgs...)
+		return kubeutil.ExecKubectl(kubectl, allArgs...)
 	},
 }
@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"time"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+var statusCmdTagSlice *[]string
+var statusCmdWatch bool
+var statusCmdWatchInterval time.Duration
+
+func initStatusCmdFlags() {
+	statusCmdTagSlice = statusCmd.Flags().StringArrayP("tag", "t", []string{}, "check the status of resources with this tag")
+	statusCmd.Flags().BoolVarP(&statusCmdWatch, "watch", "w", false, "keep refreshing resource statuses, and log any changes")
+	statusCmd.Flags().DurationVarP(&statusCmdWatchInterval, "interval", "", 10*time.Second, "time between refreshes when watching")
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status [resource-name]...",
+	Short: "Check whether resources defined in the hope file are deployed and healthy",
+	Args:  cobra.ArbitraryArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var resources *[]hope.Resource
+
+		if len(args) == 0 && len(*statusCmdTagSlice) == 0 {
+			r, err := utils.GetResources()
+			if err != nil {
+				return err
+			}
+
+			resources = r
+			log.Trace("Received no arguments for status. Checking all resources.")
+		} else {
+			r, err := utils.GetIdentifiableResources(&args, statusCmdTagSlice)
+			if err != nil {
+				return err
+			}
+
+			resources = r
+		}
+
+		if len(*resources) == 0 {
+			log.Warn("No resources matched the provided definitions.")
+			return nil
+		}
+
+		hasKubernetesResource := false
+		for _, resource := range *resources {
+			resourceType, _ := resource.GetType()
+			switch resourceType {
+			case hope.ResourceTypeFile, hope.ResourceTypeInline, hope.ResourceTypeKustomize, hope.ResourceTypeJob:
+				hasKubernetesResource = true
+			}
+		}
+
+		var kubectl *kubeutil.Kubectl
+		if hasKubernetesResource {
+			var err error
+			kubectl, err = utils.KubectlFromAnyMaster()
+			if err != nil {
+				return err
+			}
+
+			defer kubectl.Destroy()
+		}
+
+		// Parameters are resolved once, without asking for any, so that
+		//   watching doesn't ask for them again on every refresh.
+		parameters, parameterErrs := resourceStatusParameters(*resources)
+
+		if statusCmdWatch {
+			return utils.Watch(statusCmdWatchInterval, func() (*utils.WatchTable, error) {
+				reports, err := resourceStatuses(kubectl, *resources, parameters, parameterErrs)
+				if err != nil {
+					return nil, err
+				}
+
+				return resourceStatusWatchTable(*resources, reports), nil
+			})
+		}
+
+		reports, err := resourceStatuses(kubectl, *resources, parameters, parameterErrs)
+		if err != nil {
+			return err
+		}
+
+		table := resourceStatusWatchTable(*resources, reports)
+		utils.WriteTable(os.Stdout, table)
+
+		for _, report := range reports {
+			switch report.Status {
+			case hope.ResourceStatusHealthy, hope.ResourceStatusNotApplicable:
+			default:
+				return errors.New("error with resources; see output for more details")
+			}
+		}
+
+		return nil
+	},
+}
+
+// resourceStatusParameters - Resolve the parameters of each resource,
+// without asking for any, along with the error for each resource whose
+// parameters couldn't be resolved.
+func resourceStatusParameters(resources []hope.Resource) ([][]string, []error) {
+	parameters := make([][]string, len(resources))
+	errs := make([]error, len(resources))
+	for i, resource := range resources {
+		parameters[i], errs[i] = utils.KnownResourceParameters(&resource)
+	}
+
+	return parameters, errs
+}
+
+// resourceStatuses - Get the status of each resource, reporting resources
+// whose parameters couldn't be resolved as unknown.
+func resourceStatuses(kubectl *kubeutil.Kubectl, resources []hope.Resource, parameters [][]string, parameterErrs []error) ([]hope.ResourceStatusReport, error) {
+	reports := []hope.ResourceStatusReport{}
+	for i, resource := range resources {
+		log.Debug("Checking status of ", resource.Name)
+
+		if parameterErrs[i] != nil {
+			reports = append(reports, hope.ResourceStatusReport{Status: hope.ResourceStatusUnknown, Detail: parameterErrs[i].Error()})
+			continue
+		}
+
+		report, err := hope.GetResourceStatus(log.WithFields(log.Fields{}), kubectl, &resource, parameters[i])
+		if err != nil {
+			return nil, err
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func resourceStatusWatchTable(resources []hope.Resource, reports []hope.ResourceStatusReport) *utils.WatchTable {
+	table := utils.WatchTable{
+		Headers: []string{"Resource", "Type", "Status", "Detail"},
+	}
+
+	for i, resource := range resources {
+		resourceType, _ := resource.GetType()
+		columns := []string{resource.Name, resourceType.String(), reports[i].Status.String(), reports[i].Detail}
+		table.Rows = append(table.Rows, utils.WatchRow{Key: resource.Name, Columns: columns})
+	}
+
+	return &table
+}
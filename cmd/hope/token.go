@@ -1,31 +1,40 @@
 package cmd
 
 import (
-	"encoding/base64"
 	"fmt"
+	"time"
 )
 
 import (
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 import (
 	"github.com/Eagerod/hope/cmd/hope/utils"
-	"github.com/Eagerod/hope/pkg/kubeutil"
+	"github.com/Eagerod/hope/pkg/hope"
 )
 
 var tokenCmdNamespace string
+var tokenCmdDuration time.Duration
+var tokenCmdAudiences []string
+var tokenCmdLongLived bool
 
 func initTokenCmd() {
 	tokenCmd.Flags().StringVarP(&tokenCmdNamespace, "namespace", "n", "kube-system", "namespace in which to fetch the token")
+	tokenCmd.Flags().DurationVarP(&tokenCmdDuration, "duration", "", time.Hour, "how long the token is valid for")
+	tokenCmd.Flags().StringArrayVarP(&tokenCmdAudiences, "audience", "", []string{}, "audience of the token; can be given multiple times")
+	tokenCmd.Flags().BoolVarP(&tokenCmdLongLived, "long-lived", "", false, "fetch a long-lived token from a service account token secret, creating it if needed")
 }
 
 var tokenCmd = &cobra.Command{
 	Use:   "token <service-account-name>",
 	Short: "Fetch a service account token from the cluster",
-	Args:  cobra.ExactArgs(1),
+	Long: "Fetch a service account token from the cluster.\n" +
+		"Tokens are short-lived, and requested from the cluster each time, unless a long-lived token is asked for.",
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
-		username := args[0]
+		serviceAccount := args[0]
 
 		kubectl, err := utils.KubectlFromAnyMaster()
 		if err != nil {
@@ -34,24 +43,13 @@ var tokenCmd = &cobra.Command{
 
 		defer kubectl.Destroy()
 
-		// TODO: Should not live in cmd, but should be pushed into pkg/hope
-		//   with some creative name.
-		secretName, err := kubeutil.GetKubectl(kubectl, "-n", tokenCmdNamespace, "get", "serviceaccount", username, "-o", "jsonpath={.secrets[0].name}")
+		options := hope.TokenOptions{Duration: tokenCmdDuration, Audiences: tokenCmdAudiences, LongLived: tokenCmdLongLived}
+		token, err := hope.ServiceAccountToken(log.WithFields(log.Fields{}), kubectl, tokenCmdNamespace, serviceAccount, options)
 		if err != nil {
 			return err
 		}
 
-		secretB64, err := kubeutil.GetKubectl(kubectl, "-n", tokenCmdNamespace, "get", "secret", secretName, "-o", "jsonpath={.data.token}")
-		if err != nil {
-			return err
-		}
-
-		secret, err := base64.StdEncoding.DecodeString(secretB64)
-		if err != nil {
-			return err
-		}
-
-		fmt.Println(string(secret))
+		fmt.Println(token)
 
 		return nil
 	},
@@ -0,0 +1,69 @@
+package user
+
+import (
+	"os"
+	"time"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var createCmdGroups []string
+var createCmdClusterRole string
+var createCmdNamespace string
+var createCmdDuration time.Duration
+var createCmdOutput string
+
+func initCreateCmdFlags() {
+	createCmd.Flags().StringArrayVarP(&createCmdGroups, "group", "g", []string{}, "group the user belongs to; can be given multiple times")
+	createCmd.Flags().StringVarP(&createCmdClusterRole, "cluster-role", "", "", "cluster role to bind the user to")
+	createCmd.Flags().StringVarP(&createCmdNamespace, "namespace", "n", "", "namespace to bind the cluster role in, rather than across the cluster")
+	createCmd.Flags().DurationVarP(&createCmdDuration, "duration", "", 30*24*time.Hour, "how long the user's certificate is valid for")
+	createCmd.Flags().StringVarP(&createCmdOutput, "output", "o", "", "file to write the kubeconfig to, instead of stdout")
+}
+
+var createCmd = &cobra.Command{
+	Use:   "create <name>",
+	Short: "issues a client certificate for a user, and prints a kubeconfig that uses it",
+	Long: "Issues a client certificate for a user through a certificate signing request, and prints a kubeconfig that uses it to connect through the load balancer host.\n" +
+		"Running it again for the same user issues a new certificate.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		cluster, err := utils.KubeconfigCluster(kubectl)
+		if err != nil {
+			return err
+		}
+
+		options := hope.UserOptions{
+			Groups:      createCmdGroups,
+			ClusterRole: createCmdClusterRole,
+			Namespace:   createCmdNamespace,
+			Duration:    createCmdDuration,
+		}
+		kubeconfig, err := hope.CreateUser(log.WithFields(log.Fields{}), kubectl, cluster, args[0], options)
+		if err != nil {
+			return err
+		}
+
+		if createCmdOutput != "" {
+			return os.WriteFile(createCmdOutput, kubeconfig, 0600)
+		}
+
+		_, err = os.Stdout.Write(kubeconfig)
+		return err
+	},
+}
@@ -0,0 +1,30 @@
+package user
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+import (
+	"github.com/Eagerod/hope/cmd/hope/utils"
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+var revokeCmd = &cobra.Command{
+	Use:   "revoke <name>",
+	Short: "deletes the role bindings and certificate signing requests of a user",
+	Long: "Deletes the role bindings and certificate signing requests created for a user.\n" +
+		"Kubernetes can't revoke client certificates, so the user's certificates stay valid until they expire; " +
+		"anything bound to the user's groups, rather than the user, stays available until then.",
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		kubectl, err := utils.KubectlFromAnyMaster()
+		if err != nil {
+			return err
+		}
+
+		defer kubectl.Destroy()
+
+		return hope.RevokeUser(log.WithFields(log.Fields{}), kubectl, args[0])
+	},
+}
@@ -0,0 +1,18 @@
+package user
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var RootCommand = &cobra.Command{
+	Use:   "user",
+	Short: "issue and revoke client certificates for people using the cluster",
+	Long:  "Issue client certificates for people using the cluster, along with kubeconfigs that use them, and revoke their access.",
+}
+
+func InitUserCommand() {
+	RootCommand.AddCommand(createCmd)
+	RootCommand.AddCommand(revokeCmd)
+
+	initCreateCmdFlags()
+}
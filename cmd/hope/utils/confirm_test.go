@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		answer   string
+		expected bool
+	}{
+		{"y\n", true},
+		{"Yes\n", true},
+		{" YES \n", true},
+		{"y", true},
+		{"n\n", false},
+		{"\n", false},
+		{"yep\n", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.answer, func(t *testing.T) {
+			var out bytes.Buffer
+			assert.Equal(t, tt.expected, Confirm(strings.NewReader(tt.answer), &out, "Delete?"))
+			assert.True(t, strings.HasPrefix(out.String(), "Delete? [y/N]: "))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"fmt"
+)
+
+import (
+	"github.com/spf13/viper"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+func GetForwards() (*[]hope.Forward, error) {
+	var forwards []hope.Forward
+	err := viper.UnmarshalKey("forwards", &forwards)
+
+	nameMap := map[string]bool{}
+	for _, forward := range forwards {
+		if _, ok := nameMap[forward.Name]; ok {
+			return nil, fmt.Errorf("multiple forwards found in configuration file named: %s", forward.Name)
+		}
+		nameMap[forward.Name] = true
+	}
+
+	return &forwards, err
+}
+
+func GetForward(forwardName string) (*hope.Forward, error) {
+	forwards, err := GetForwards()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, forward := range *forwards {
+		if forward.Name == forwardName {
+			return &forward, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to find a forward named %s", forwardName)
+}
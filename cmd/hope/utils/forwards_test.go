@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/stretchr/testify/assert"
+)
+
+var testForwards []hope.Forward = []hope.Forward{
+	{
+		Name:       "grafana",
+		Namespace:  "monitoring",
+		Service:    "grafana",
+		LocalPort:  3000,
+		RemotePort: 80,
+	},
+	{
+		Name:       "mysql",
+		Selector:   "app=mysql",
+		RemotePort: 3306,
+	},
+}
+
+func TestGetForwards(t *testing.T) {
+	resetViper(t)
+
+	forwards, err := GetForwards()
+	assert.Nil(t, err)
+	assert.Equal(t, testForwards, *forwards)
+}
+
+func TestGetForward(t *testing.T) {
+	resetViper(t)
+
+	forward, err := GetForward("mysql")
+	assert.Nil(t, err)
+	assert.Equal(t, testForwards[1], *forward)
+
+	_, err = GetForward("redis")
+	assert.Equal(t, "failed to find a forward named redis", err.Error())
+}
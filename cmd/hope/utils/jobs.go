@@ -14,7 +14,7 @@ import (
 
 func GetJobs() (*[]hope.Job, error) {
 	var jobs []hope.Job
-	err := viper.UnmarshalKey("jobs", &jobs)
+	err := viper.UnmarshalKey("jobs", &jobs, parameterDecodeHook)
 
 	nameMap := map[string]bool{}
 	for _, job := range jobs {
@@ -41,3 +41,10 @@ func GetJob(jobName string) (*hope.Job, error) {
 
 	return nil, fmt.Errorf("failed to find a job named %s", jobName)
 }
+
+// JobParameters - Resolve the values of the job's parameters, using the
+// ones given from the command line first, and asking for any that can't be
+// found otherwise, if possible.
+func JobParameters(job *hope.Job, flagParameters []string) ([]string, error) {
+	return hope.ResolveParameters(job.Parameters, flagParameters, ParameterPrompt())
+}
@@ -9,9 +9,32 @@ import (
 
 var testJobs []hope.Job = []hope.Job{
 	{
-		Name:       "test-job",
-		File:       "test/job.yaml",
-		Parameters: []string{"LOG_LINE=I did the thing"},
+		Name:        "test-job",
+		File:        "test/job.yaml",
+		Parameters:  testParameters("LOG_LINE=I did the thing"),
+		Timeout:     "5m",
+		MaxAttempts: 20,
+		TTL:         "24h",
+	},
+	{
+		Name: "prune-registry",
+		File: "test/scheduled-job.yaml",
+		Parameters: []hope.Parameter{
+			hope.ParseParameter("REGISTRY_HOST=registry.internal.aleemhaji.com"),
+			{
+				Name:        "REGISTRY_TOKEN",
+				Description: "token with permission to delete images from the registry",
+				Secret:      true,
+			},
+			{
+				Name:        "KEEP_TAGS",
+				Type:        "int",
+				Default:     10,
+				Description: "number of tags of each image to keep",
+			},
+		},
+		Schedule: "0 4 * * 0",
+		TTL:      "168h",
 	},
 }
 
@@ -32,3 +55,19 @@ func TestGetJob(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, testJobs[0], *job)
 }
+
+func TestJobParameters(t *testing.T) {
+	t.Setenv("ONE", "1")
+	job := &hope.Job{Name: "job", Parameters: testParameters("ONE", "TWO", "THREE=3")}
+
+	parameters, err := JobParameters(job, []string{"TWO=2"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ONE", "TWO=2", "THREE=3"}, parameters)
+
+	parameters, err = JobParameters(job, []string{"TWO=2", "THREE=three"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ONE", "TWO=2", "THREE=three"}, parameters)
+
+	_, err = JobParameters(job, []string{"FOUR=4"})
+	assert.EqualError(t, err, "parameter: FOUR not recognized")
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"github.com/spf13/viper"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// KubeconfigOptions - Options for writing the admin kubeconfig, naming the
+// cluster after the configured cluster name, and connecting to it through
+// the load balancer host, when they're set.
+func KubeconfigOptions(merge bool, output string) hope.KubeconfigOptions {
+	return hope.KubeconfigOptions{
+		ClusterName: viper.GetString("cluster_name"),
+		ServerHost:  viper.GetString("load_balancer_host"),
+		Merge:       merge,
+		Output:      output,
+	}
+}
+
+// KubeconfigCluster - The cluster the kubectl's kubeconfig points at, named
+// and connected to the same way as in the admin kubeconfig hope writes.
+func KubeconfigCluster(kubectl *kubeutil.Kubectl) (*kubeutil.KubeconfigCluster, error) {
+	cluster, err := kubeutil.CurrentCluster(kubectl.KubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if name := viper.GetString("cluster_name"); name != "" {
+		cluster.Name = name
+	}
+
+	return cluster.WithServerHost(viper.GetString("load_balancer_host"))
+}
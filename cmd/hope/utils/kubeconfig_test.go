@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+func TestKubeconfigCluster(t *testing.T) {
+	resetViper(t)
+
+	path := filepath.Join(t.TempDir(), "config")
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://192.168.1.10:6443
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+`
+	assert.Nil(t, os.WriteFile(path, []byte(kubeconfig), 0600))
+
+	cluster, err := KubeconfigCluster(kubeutil.NewKubectl(path))
+	assert.Nil(t, err)
+	assert.Equal(t, "test-cluster", cluster.Name)
+	assert.Equal(t, "https://testapi.internal.aleemhaji.com:6443", cluster.Server)
+}
+
+func TestKubeconfigCacheKey(t *testing.T) {
+	resetViper(t)
+
+	key, err := kubeconfigCacheKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "test-cluster", key)
+
+	viper.Set("cluster_name", "")
+	key, err = kubeconfigCacheKey()
+	assert.Nil(t, err)
+	assert.Equal(t, "test-master-01,test-master-02,test-master-03", key)
+}
+
+func TestCachedKubectl(t *testing.T) {
+	resetViper(t)
+
+	originalTTL, originalDir, originalKey := hope.KubeconfigCacheTTL, hope.KubeconfigCacheDir, hope.KubeconfigCacheKeyPath
+	originalVerifyAccess := kubeutil.VerifyAccess
+	defer func() {
+		hope.KubeconfigCacheTTL, hope.KubeconfigCacheDir, hope.KubeconfigCacheKeyPath = originalTTL, originalDir, originalKey
+		kubeutil.VerifyAccess = originalVerifyAccess
+	}()
+
+	dir := t.TempDir()
+	hope.KubeconfigCacheTTL = time.Hour
+	hope.KubeconfigCacheDir = filepath.Join(dir, "cache")
+	hope.KubeconfigCacheKeyPath = filepath.Join(dir, "key")
+
+	namespaces := schema.GroupResource{Resource: "namespaces"}
+	tests := []struct {
+		name   string
+		err    error
+		used   bool
+		cached bool
+	}{
+		{"Accepted", nil, true, true},
+		{"Unauthorized", apierrors.NewUnauthorized("Unauthorized"), false, false},
+		{"Forbidden", apierrors.NewForbidden(namespaces, "", errors.New("no access")), false, false},
+		{"Timed out", context.DeadlineExceeded, true, true},
+		{"Unreachable", errors.New("dial tcp 127.0.0.1:1: connect: connection refused"), true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kubeutil.VerifyAccess = func(kubectl *kubeutil.Kubectl, timeout time.Duration) error {
+				return tt.err
+			}
+
+			assert.Nil(t, hope.WriteCachedKubeconfig("test-cluster", []byte("apiVersion: v1\nkind: Config\n")))
+
+			kubectl := cachedKubectl("test-cluster")
+			if tt.used {
+				if assert.NotNil(t, kubectl) {
+					kubectl.Destroy()
+				}
+			} else {
+				assert.Nil(t, kubectl)
+			}
+
+			_, ok, err := hope.ReadCachedKubeconfig("test-cluster")
+			assert.Nil(t, err)
+			assert.Equal(t, tt.cached, ok)
+		})
+	}
+}
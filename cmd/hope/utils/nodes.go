@@ -3,10 +3,16 @@ package utils
 import (
 	"errors"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
 )
 
 import (
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 import (
@@ -15,6 +21,9 @@ import (
 	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
+// How long to wait for the cluster to accept a cached kubeconfig.
+const kubeconfigVerifyTimeout = 5 * time.Second
+
 type NodeNotFoundError struct {
 	node string
 }
@@ -228,7 +237,51 @@ func GetAvailableMasters() ([]hope.Node, error) {
 	return retVal, nil
 }
 
+// KubectlFromAnyMaster - Get a kubectl using the admin kubeconfig of the
+// cluster.
+// When the kubeconfig cache is enabled, a cached kubeconfig the cluster still
+// accepts is used before going to the masters, and what's fetched from them
+// is cached.
+// If no master can be reached, the context named after the cluster in the
+// user's kubeconfig is used, if there is one.
 func KubectlFromAnyMaster() (*kubeutil.Kubectl, error) {
+	cacheKey, err := kubeconfigCacheKey()
+	if err != nil {
+		return nil, err
+	}
+
+	if kubectl := cachedKubectl(cacheKey); kubectl != nil {
+		return kubectl, nil
+	}
+
+	kubectl, err := kubectlFromMasters()
+	if err == nil {
+		if hope.KubeconfigCacheTTL != 0 {
+			if contents, err := os.ReadFile(kubectl.KubeconfigPath); err != nil {
+				log.Warn("Failed to read kubeconfig for caching. ", err)
+			} else if err := hope.WriteCachedKubeconfig(cacheKey, contents); err != nil {
+				log.Warn("Failed to cache kubeconfig. ", err)
+			}
+		}
+
+		return kubectl, nil
+	}
+
+	clusterName := viper.GetString("cluster_name")
+	if clusterName != "" {
+		kubectl, contextErr := kubeutil.NewKubectlFromContext(clusterName)
+		if contextErr == nil {
+			log.Info("Failed to get a kubeconfig from any master; using context ", clusterName, " of the local kubeconfig")
+			return kubectl, nil
+		}
+
+		log.Debug("Failed to use context ", clusterName, " of the local kubeconfig. ", contextErr)
+	}
+
+	return nil, err
+}
+
+func kubectlFromMasters() (*kubeutil.Kubectl, error) {
 	// To prevent "dereferencing" all the master nodes in advance, and making
 	//   a ton of extra network traffic, do them incrementally until a valid
 	//   kubeconfig is found.
@@ -256,6 +309,70 @@ func KubectlFromAnyMaster() (*kubeutil.Kubectl, error) {
 	return nil, errors.New("failed to find a kubeconfig file in any of the master nodes")
 }
 
+// kubeconfigCacheKey - What the cluster's kubeconfig is cached under; its
+// name, if it has one, otherwise its masters.
+func kubeconfigCacheKey() (string, error) {
+	if clusterName := viper.GetString("cluster_name"); clusterName != "" {
+		return clusterName, nil
+	}
+
+	nodes, err := getNodes()
+	if err != nil {
+		return "", err
+	}
+
+	masters := []string{}
+	for _, node := range nodes {
+		if node.IsMaster() {
+			masters = append(masters, node.Name)
+		}
+	}
+	sort.Strings(masters)
+
+	return strings.Join(masters, ","), nil
+}
+
+// cachedKubectl - Get a kubectl using the cached kubeconfig, if there is
+// one, and the cluster still accepts it.
+// Kubeconfigs the cluster rejects are removed from the cache; if the cluster
+// can't be asked at all, the cached kubeconfig is still used, since fetching
+// another one is unlikely to do any better.
+func cachedKubectl(cacheKey string) *kubeutil.Kubectl {
+	if hope.KubeconfigCacheTTL == 0 {
+		return nil
+	}
+
+	contents, ok, err := hope.ReadCachedKubeconfig(cacheKey)
+	if err != nil {
+		log.Warn("Failed to read cached kubeconfig. ", err)
+		return nil
+	} else if !ok {
+		return nil
+	}
+
+	kubectl, err := kubeutil.NewKubectlFromContents(contents)
+	if err != nil {
+		log.Warn("Failed to use cached kubeconfig. ", err)
+		return nil
+	}
+
+	err = kubeutil.VerifyAccess(kubectl, kubeconfigVerifyTimeout)
+	if apierrors.IsUnauthorized(err) || apierrors.IsForbidden(err) {
+		log.Debug("Cached kubeconfig no longer works; fetching it again. ", err)
+		kubectl.Destroy()
+		if err := hope.InvalidateCachedKubeconfig(cacheKey); err != nil {
+			log.Warn("Failed to remove cached kubeconfig. ", err)
+		}
+		return nil
+	} else if err != nil {
+		log.Warn("Failed to check cached kubeconfig against the cluster; using it anyway. ", err)
+		return kubectl
+	}
+
+	log.Debug("Using cached kubeconfig")
+	return kubectl
+}
+
 func GetLoadBalancer() (hope.Node, error) {
 	nodes, err := getNodes()
 	if err != nil {
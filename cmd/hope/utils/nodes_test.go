@@ -168,6 +168,14 @@ func (m *MockHypervisor) VMIPAddress(string) (string, error) {
 	return "192.168.1.5", nil
 }
 
+func (m *MockHypervisor) VMPowerState(string) (string, error) {
+	return hypervisors.VMPowerStateOn, nil
+}
+
+func (m *MockHypervisor) DatastoreUsage() (uint64, uint64, error) {
+	return 100, 50, nil
+}
+
 func toHypervisorStub(node hope.Node) (hypervisors.Hypervisor, error) {
 	if !node.IsHypervisor() {
 		return nil, fmt.Errorf("Not a hypervisor")
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+import (
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+// parameterDecodeHook - Lets parameters in the hope file be given in their
+// NAME or NAME=value shorthand, alongside the fully described form.
+// Viper's own hooks are kept.
+var parameterDecodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	func(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+		if from.Kind() != reflect.String || to != reflect.TypeOf(hope.Parameter{}) {
+			return data, nil
+		}
+
+		return hope.ParseParameter(data.(string)), nil
+	},
+))
+
+// ParameterPrompt - Ask for parameters on the terminal, if there is one.
+// Secret parameters aren't echoed back as they're typed.
+func ParameterPrompt() hope.ParameterPrompt {
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		return nil
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	readSecret := func() (string, error) {
+		value, err := term.ReadPassword(stdin)
+		fmt.Fprintln(os.Stderr)
+		return string(value), err
+	}
+
+	return func(parameter hope.Parameter) (string, error) {
+		return promptParameter(in, os.Stderr, readSecret, parameter)
+	}
+}
+
+// promptParameter - Ask for the parameter's value until a valid one is
+// given.
+func promptParameter(in *bufio.Reader, out io.Writer, readSecret func() (string, error), parameter hope.Parameter) (string, error) {
+	question := parameter.Name
+	if parameter.Description != "" {
+		question = fmt.Sprintf("%s - %s", question, parameter.Description)
+	}
+
+	switch parameter.Type {
+	case hope.ParameterTypeBool:
+		question = fmt.Sprintf("%s [true/false]", question)
+	case hope.ParameterTypeEnum:
+		question = fmt.Sprintf("%s [%s]", question, strings.Join(parameter.Values, "/"))
+	}
+
+	for {
+		fmt.Fprintf(out, "%s: ", question)
+
+		var value string
+		var err error
+		if parameter.Secret {
+			value, err = readSecret()
+		} else {
+			value, err = in.ReadString('\n')
+			if errors.Is(err, io.EOF) && len(value) != 0 {
+				err = nil
+			}
+		}
+
+		if err != nil {
+			return "", fmt.Errorf("failed to read parameter %s: %w", parameter.Name, err)
+		}
+
+		value = strings.TrimRight(value, "\r\n")
+		if err := parameter.Validate(value); err != nil {
+			fmt.Fprintln(out, err)
+			continue
+		}
+
+		return value, nil
+	}
+}
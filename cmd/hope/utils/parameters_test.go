@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+)
+
+func TestPromptParameter(t *testing.T) {
+	noSecrets := func() (string, error) {
+		t.Fatal("non-secret parameter read as a secret")
+		return "", nil
+	}
+
+	var out bytes.Buffer
+	in := bufio.NewReader(strings.NewReader("maybe\ntrue\n"))
+	parameter := hope.Parameter{Name: "ENABLED", Type: hope.ParameterTypeBool, Description: "turn it on"}
+	value, err := promptParameter(in, &out, noSecrets, parameter)
+	assert.Nil(t, err)
+	assert.Equal(t, "true", value)
+	assert.Equal(t, "ENABLED - turn it on [true/false]: parameter ENABLED must be true or false, not \"maybe\"\nENABLED - turn it on [true/false]: ", out.String())
+
+	out.Reset()
+	in = bufio.NewReader(strings.NewReader("slow"))
+	parameter = hope.Parameter{Name: "MODE", Type: hope.ParameterTypeEnum, Values: []string{"fast", "slow"}}
+	value, err = promptParameter(in, &out, noSecrets, parameter)
+	assert.Nil(t, err)
+	assert.Equal(t, "slow", value)
+	assert.Equal(t, "MODE [fast/slow]: ", out.String())
+
+	out.Reset()
+	in = bufio.NewReader(strings.NewReader("not a secret\n"))
+	value, err = promptParameter(in, &out, func() (string, error) { return "hunter2", nil }, hope.Parameter{Name: "TOKEN", Secret: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "hunter2", value)
+
+	in = bufio.NewReader(strings.NewReader(""))
+	_, err = promptParameter(in, &out, noSecrets, hope.Parameter{Name: "NAME"})
+	assert.EqualError(t, err, "failed to read parameter NAME: EOF")
+}
+
+func TestParameterDecodeHook(t *testing.T) {
+	v := viper.New()
+	v.Set("parameters", []interface{}{
+		"FROM_ENV",
+		"FIXED=value",
+		map[string]interface{}{"name": "TYPED", "type": "enum", "values": []string{"a", "b"}, "default": "a"},
+	})
+
+	var parameters []hope.Parameter
+	assert.Nil(t, v.UnmarshalKey("parameters", &parameters, parameterDecodeHook))
+	assert.Equal(t, []hope.Parameter{
+		{Name: "FROM_ENV"},
+		hope.ParseParameter("FIXED=value"),
+		{Name: "TYPED", Type: "enum", Values: []string{"a", "b"}, Default: "a"},
+	}, parameters)
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/hope"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// SelectPod - Find the pod to work with from a target, as understood by
+// hope.FindPods.
+// When several pods match, the user picks one if there's a terminal to ask
+// on; otherwise, the first is used.
+func SelectPod(kubectl *kubeutil.Kubectl, namespace, target string) (string, error) {
+	pods, err := hope.FindPods(kubectl, namespace, target)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(pods) {
+	case 0:
+		return "", fmt.Errorf("no running pods found for %s in namespace %s", target, namespace)
+	case 1:
+		return pods[0], nil
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		log.Debug(len(pods), " pods found for ", target, "; using ", pods[0])
+		return pods[0], nil
+	}
+
+	return PickPod(bufio.NewReader(os.Stdin), os.Stderr, pods)
+}
+
+// PickPod - Ask which of the pods to use.
+// Either the number of a listed pod can be given, or some text to narrow the
+// list down to the pods whose names contain its characters, in order.
+func PickPod(in *bufio.Reader, out io.Writer, pods []string) (string, error) {
+	candidates := pods
+	for {
+		for i, pod := range candidates {
+			fmt.Fprintf(out, "%3d) %s\n", i+1, pod)
+		}
+		fmt.Fprint(out, "Pick a pod, or type to filter: ")
+
+		answer, err := in.ReadString('\n')
+		if err != nil && !(errors.Is(err, io.EOF) && len(answer) != 0) {
+			fmt.Fprintln(out)
+			return "", fmt.Errorf("no pod picked: %w", err)
+		}
+
+		answer = strings.TrimSpace(answer)
+		if index, err := strconv.Atoi(answer); err == nil && index >= 1 && index <= len(candidates) {
+			return candidates[index-1], nil
+		}
+
+		matches := []string{}
+		for _, pod := range pods {
+			if fuzzyMatch(answer, pod) {
+				matches = append(matches, pod)
+			}
+		}
+
+		switch len(matches) {
+		case 0:
+			fmt.Fprintf(out, "No pods match %q\n", answer)
+			candidates = pods
+		case 1:
+			return matches[0], nil
+		default:
+			candidates = matches
+		}
+	}
+}
+
+// fuzzyMatch - Whether every character of the pattern appears in the string,
+// in the same order.
+func fuzzyMatch(pattern, str string) bool {
+	remaining := str
+	for _, r := range pattern {
+		i := strings.IndexRune(remaining, r)
+		if i < 0 {
+			return false
+		}
+		remaining = remaining[i+len(string(r)):]
+	}
+
+	return true
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+var testPickerPods = []string{"web-6d4b-abcde", "web-6d4b-fghij", "worker-77c9-klmno"}
+
+func TestPickPod(t *testing.T) {
+	var tests = []struct {
+		name     string
+		answers  string
+		expected string
+		err      string
+	}{
+		{"Number", "2\n", "web-6d4b-fghij", ""},
+		{"Unique Filter", "wrk\n", "worker-77c9-klmno", ""},
+		{"Narrowed Then Number", "web\n1\n", "web-6d4b-abcde", ""},
+		{"Narrowed Then Filter", "web\nfgh\n", "web-6d4b-fghij", ""},
+		{"No Match Then Number", "zzz\n3\n", "worker-77c9-klmno", ""},
+		{"Out Of Range Number", "4\n1\n", "web-6d4b-abcde", ""},
+		{"No Answer", "", "", "no pod picked: EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			pod, err := PickPod(bufio.NewReader(strings.NewReader(tt.answers)), &out, testPickerPods)
+			if tt.err == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.expected, pod)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestPickPodOutput(t *testing.T) {
+	var out bytes.Buffer
+	_, err := PickPod(bufio.NewReader(strings.NewReader("web\n1\n")), &out, testPickerPods)
+	assert.Nil(t, err)
+	assert.Equal(t, strings.Join([]string{
+		"  1) web-6d4b-abcde",
+		"  2) web-6d4b-fghij",
+		"  3) worker-77c9-klmno",
+		"Pick a pod, or type to filter:   1) web-6d4b-abcde",
+		"  2) web-6d4b-fghij",
+		"Pick a pod, or type to filter: ",
+	}, "\n"), out.String())
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	assert.True(t, fuzzyMatch("", "web"))
+	assert.True(t, fuzzyMatch("wb", "web"))
+	assert.True(t, fuzzyMatch("web", "web"))
+	assert.False(t, fuzzyMatch("bw", "web"))
+	assert.False(t, fuzzyMatch("webs", "web"))
+}
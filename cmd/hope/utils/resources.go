@@ -20,7 +20,7 @@ import (
 
 func GetResources() (*[]hope.Resource, error) {
 	var resources []hope.Resource
-	err := viper.UnmarshalKey("resources", &resources)
+	err := viper.UnmarshalKey("resources", &resources, parameterDecodeHook)
 
 	nameMap := map[string]bool{}
 	for _, resource := range resources {
@@ -76,6 +76,30 @@ func GetIdentifiableResources(names *[]string, tags *[]string) (*[]hope.Resource
 	return &returnSlice, nil
 }
 
+// ResourceParameters - Resolve the values of the resource's parameters,
+// starting from the ones given, and add the contents of its file
+// parameters.
+// Parameters that can't be found otherwise are asked for, if possible.
+func ResourceParameters(resource *hope.Resource, given []string) ([]string, error) {
+	return resourceParameters(resource, given, ParameterPrompt())
+}
+
+// KnownResourceParameters - Resolve the values of the resource's parameters
+// like ResourceParameters does, without asking for any, for commands that
+// only inspect resources.
+func KnownResourceParameters(resource *hope.Resource) ([]string, error) {
+	return resourceParameters(resource, []string{}, nil)
+}
+
+func resourceParameters(resource *hope.Resource, given []string, prompt hope.ParameterPrompt) ([]string, error) {
+	parameters, err := hope.ResolveParameters(resource.Parameters, given, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return FlattenParameters(parameters, resource.FileParameters)
+}
+
 // For each parameter from a file, load the file and populate the base64
 // values of the files into the properties.
 //
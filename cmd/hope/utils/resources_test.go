@@ -9,6 +9,15 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
+func testParameters(params ...string) []hope.Parameter {
+	parameters := []hope.Parameter{}
+	for _, param := range params {
+		parameters = append(parameters, hope.ParseParameter(param))
+	}
+
+	return parameters
+}
+
 var testResources []hope.Resource = []hope.Resource{
 	{
 		Name: "calico",
@@ -23,7 +32,7 @@ var testResources []hope.Resource = []hope.Resource{
 	{
 		Name:       "load-balancer-config",
 		Inline:     "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  namespace: metallb-system\n  name: config\ndata:\n  config: |\n    address-pools:\n    - name: default\n      protocol: layer2\n      addresses:\n      - 192.168.1.16-192.168.1.24\n---\napiVersion: v1\ndata:\n  secretkey: ${METALLB_SYSTEM_MEMBERLIST_SECRET_KEY}\nkind: Secret\nmetadata:\n  creationTimestamp: null\n  name: memberlist\n  namespace: metallb-system\n",
-		Parameters: []string{"METALLB_SYSTEM_MEMBERLIST_SECRET_KEY"},
+		Parameters: testParameters("METALLB_SYSTEM_MEMBERLIST_SECRET_KEY"),
 		Tags:       []string{"network"},
 	},
 	{
@@ -50,9 +59,11 @@ var testResources []hope.Resource = []hope.Resource{
 		Tags: []string{"database"},
 	},
 	{
-		Name: "wait-for-some-kind-of-job",
-		Job:  "init-the-database",
-		Tags: []string{"database"},
+		Name:    "wait-for-some-kind-of-job",
+		Job:     "init-the-database",
+		Timeout: "15m",
+		LogFile: "logs/init-the-database.log",
+		Tags:    []string{"database"},
 	},
 	{
 		Name: "exec-in-a-running-pod",
@@ -66,7 +77,7 @@ var testResources []hope.Resource = []hope.Resource{
 	{
 		Name:           "configmap-with-file-keys",
 		Inline:         "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: file-keys\nbinaryData:\n  script.sh: ${SCRIPT_SH_FILE}\ndata:\n  something_else: ${SOME_OTHER_KEY}\n",
-		Parameters:     []string{"SOME_OTHER_KEY=abc"},
+		Parameters:     testParameters("SOME_OTHER_KEY=abc"),
 		FileParameters: []string{"SCRIPT_SH_FILE=test/script.sh"},
 		Tags:           []string{"another-tag"},
 	},
@@ -81,7 +92,71 @@ var testResources []hope.Resource = []hope.Resource{
 			Version:    "7.11.1",
 			ValuesFile: "test/kubernetes-dashboard-values.yaml",
 		},
-		Parameters: []string{"THE_PARAM=the-value"},
+		Parameters: testParameters("THE_PARAM=the-value"),
+	},
+	{
+		Name:       "upstream-app-overlay",
+		Kustomize:  "test/kustomize/overlays/production",
+		Parameters: testParameters("APP_HOSTNAME=app.internal.aleemhaji.com"),
+		Tags:       []string{"app1"},
+	},
+	{
+		Name: "ingress-nginx-helm",
+		Helm: hope.HelmSpec{
+			Namespace:   "ingress-nginx",
+			Release:     "ingress-nginx",
+			Chart:       "oci://ghcr.io/nginxinc/charts/nginx-ingress",
+			Version:     "2.0.1",
+			ValuesFiles: []string{"test/ingress-values.yaml", "test/ingress-values-production.yaml"},
+			Values:      "controller:\n  replicaCount: 2\n  defaultTLS:\n    secret: ${TLS_SECRET}\n",
+			Set:         []string{"controller.service.loadBalancerIP=${INGRESS_IP}"},
+			Atomic:      true,
+			Wait:        true,
+			Timeout:     "10m",
+		},
+		Parameters: testParameters("TLS_SECRET=ingress-nginx/default-tls", "INGRESS_IP=192.168.1.16"),
+		Tags:       []string{"ingress"},
+	},
+	{
+		Name: "build-multiarch-image",
+		Build: hope.BuildSpec{
+			Path:       "some-dir-with-dockerfile",
+			Dockerfile: "docker/Dockerfile.production",
+			Target:     "production",
+			Tag:        "registry.internal.aleemhaji.com/example-repo:production",
+			Builder:    "rootless",
+			Platforms:  []string{"linux/amd64", "linux/arm64"},
+			Secrets:    []string{"id=npmrc,src=secrets/npmrc"},
+			Cache:      "registry.internal.aleemhaji.com/example-repo:buildcache",
+		},
+		Parameters: testParameters("NODE_ENV=production"),
+		Tags:       []string{"app1"},
+	},
+	{
+		Name: "mirror-upstream-images",
+		Mirror: hope.MirrorSpec{
+			Registry:    "registry.internal.aleemhaji.com",
+			Concurrency: 8,
+			Images: []string{
+				"python:3.7",
+				"quay.io/prometheus/prometheus:v2.45.0",
+				"nginx:1.25@sha256:a484819eb60211f5299034ac80f6a681b06f89e65866ce91f356ed7c72af059c",
+			},
+		},
+		Tags: []string{"dockercache"},
+	},
+	{
+		Name: "flush-app-caches",
+		Exec: hope.ExecSpec{
+			Selector:  "app=app1",
+			Namespace: "app1",
+			Container: "app",
+			AllPods:   true,
+			Parallel:  true,
+			StdinFile: "test/script.sh",
+			Command:   []string{"sh", "-s"},
+		},
+		Tags: []string{"app1"},
 	},
 }
 
@@ -180,3 +255,17 @@ func TestFlattenParametersDirectory(t *testing.T) {
 	assert.Equal(t, "cannot resolve parameter A contents from directory: ../../../test", err.Error())
 	assert.Nil(t, params)
 }
+
+func TestKnownResourceParameters(t *testing.T) {
+	t.Setenv("FROM_ENV", "env")
+
+	resource := hope.Resource{Name: "a", Parameters: testParameters("A=B", "FROM_ENV")}
+	params, err := KnownResourceParameters(&resource)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"A=B", "FROM_ENV"}, params)
+
+	resource.Parameters = append(resource.Parameters, hope.Parameter{Name: "SECRET_NOT_SET", Secret: true})
+	params, err = KnownResourceParameters(&resource)
+	assert.Equal(t, "failed to find SECRET_NOT_SET in environment", err.Error())
+	assert.Nil(t, params)
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Number of changes kept around to show below a watched table.
+const watchEventLogLength = 20
+
+const (
+	ansiClearScreen string = "\033[H\033[2J"
+	ansiHighlight   string = "\033[1;33m"
+	ansiReset       string = "\033[0m"
+)
+
+// WatchRow - A single line in a watched table.
+// Rows are identified by their key, so that changes to a row can be found
+// between refreshes, even if rows are added or removed.
+type WatchRow struct {
+	Key     string
+	Columns []string
+}
+
+// WatchTable - The contents of a watched table at a single point in time.
+type WatchTable struct {
+	Headers []string
+	Rows    []WatchRow
+}
+
+// Watch - Call refresh every interval, redrawing the table it returns in
+// place, and keep a running log of every change seen between refreshes.
+// Rows that changed in the latest refresh are highlighted.
+// Runs until interrupted, and returns nil so that callers can clean up after
+// themselves.
+func Watch(interval time.Duration, refresh func() (*WatchTable, error)) error {
+	if interval <= 0 {
+		return fmt.Errorf("watch interval must be positive, not %s", interval)
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	var previous *WatchTable
+	events := []string{}
+
+	for {
+		table, err := refresh()
+		now := time.Now().Format("15:04:05")
+
+		changed := map[string]bool{}
+		if err != nil {
+			events = append(events, fmt.Sprintf("%s refresh failed: %s", now, err))
+		} else {
+			for _, change := range watchTableChanges(previous, table) {
+				changed[change.Key] = true
+				events = append(events, fmt.Sprintf("%s %s", now, change.Description))
+			}
+			previous = table
+		}
+
+		if len(events) > watchEventLogLength {
+			events = events[len(events)-watchEventLogLength:]
+		}
+
+		fmt.Print(ansiClearScreen)
+		fmt.Printf("Every %s; last refreshed at %s. Press Ctrl+C to exit.\n\n", interval, now)
+		if previous != nil {
+			writeWatchTable(os.Stdout, previous, changed)
+		}
+
+		if len(events) != 0 {
+			fmt.Println("\nEvents:")
+			for _, event := range events {
+				fmt.Println(event)
+			}
+		}
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+// WriteTable - Write a table once, without any highlighting.
+func WriteTable(w io.Writer, table *WatchTable) {
+	writeWatchTable(w, table, map[string]bool{})
+}
+
+func writeWatchTable(w io.Writer, table *WatchTable, highlight map[string]bool) {
+	// Escape sequences can't go through the tabwriter without throwing off
+	//   column widths, so lay out the table first, then highlight lines.
+	var buf bytes.Buffer
+	writer := tabwriter.NewWriter(&buf, 1, 1, 1, ' ', 0)
+	fmt.Fprintf(writer, "%s\t\n", strings.Join(table.Headers, "\t"))
+	for _, row := range table.Rows {
+		fmt.Fprintf(writer, "%s\t\n", strings.Join(row.Columns, "\t"))
+	}
+	writer.Flush()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	fmt.Fprintln(w, lines[0])
+	for i, row := range table.Rows {
+		if highlight[row.Key] {
+			fmt.Fprintf(w, "%s%s%s\n", ansiHighlight, lines[i+1], ansiReset)
+		} else {
+			fmt.Fprintln(w, lines[i+1])
+		}
+	}
+}
+
+// A change to a single row of a watched table.
+type watchChange struct {
+	Key         string
+	Description string
+}
+
+// Find everything that changed between two versions of a table, in the order
+// of the rows of the current table, followed by any rows that disappeared.
+// The first version of a table doesn't produce any changes.
+func watchTableChanges(previous, current *WatchTable) []watchChange {
+	changes := []watchChange{}
+	if previous == nil {
+		return changes
+	}
+
+	previousRows := map[string]WatchRow{}
+	for _, row := range previous.Rows {
+		previousRows[row.Key] = row
+	}
+
+	for _, row := range current.Rows {
+		previousRow, ok := previousRows[row.Key]
+		delete(previousRows, row.Key)
+		if !ok {
+			changes = append(changes, watchChange{row.Key, fmt.Sprintf("%s: appeared", row.Key)})
+			continue
+		}
+
+		for i, value := range row.Columns {
+			if i >= len(previousRow.Columns) || previousRow.Columns[i] == value {
+				continue
+			}
+
+			header := fmt.Sprintf("column %d", i)
+			if i < len(current.Headers) {
+				header = current.Headers[i]
+			}
+
+			change := fmt.Sprintf("%s: %s %s -> %s", row.Key, header, previousRow.Columns[i], value)
+			changes = append(changes, watchChange{row.Key, change})
+		}
+	}
+
+	// Preserve the order of the previous table for anything that's gone.
+	for _, row := range previous.Rows {
+		if _, ok := previousRows[row.Key]; ok {
+			changes = append(changes, watchChange{row.Key, fmt.Sprintf("%s: disappeared", row.Key)})
+		}
+	}
+
+	return changes
+}
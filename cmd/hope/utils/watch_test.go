@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchTableChanges(t *testing.T) {
+	previous := &WatchTable{
+		Headers: []string{"Node", "Status"},
+		Rows: []WatchRow{
+			{"a", []string{"a", "Healthy"}},
+			{"b", []string{"b", "Healthy"}},
+			{"c", []string{"c", "Healthy"}},
+		},
+	}
+
+	current := &WatchTable{
+		Headers: []string{"Node", "Status"},
+		Rows: []WatchRow{
+			{"a", []string{"a", "Healthy"}},
+			{"b", []string{"b", "Unavailable"}},
+			{"d", []string{"d", "Healthy"}},
+		},
+	}
+
+	assert.Equal(t, []watchChange{}, watchTableChanges(nil, current))
+	assert.Equal(t, []watchChange{
+		{"b", "b: Status Healthy -> Unavailable"},
+		{"d", "d: appeared"},
+		{"c", "c: disappeared"},
+	}, watchTableChanges(previous, current))
+}
+
+func TestWatchInterval(t *testing.T) {
+	refresh := func() (*WatchTable, error) {
+		t.Fatal("refreshed with an invalid interval")
+		return nil, nil
+	}
+
+	assert.EqualError(t, Watch(0, refresh), "watch interval must be positive, not 0s")
+	assert.EqualError(t, Watch(-time.Second, refresh), "watch interval must be positive, not -1s")
+}
+
+func TestWriteWatchTable(t *testing.T) {
+	table := &WatchTable{
+		Headers: []string{"Node", "Status"},
+		Rows: []WatchRow{
+			{"a", []string{"a", "Healthy"}},
+			{"bb", []string{"bb", "Unavailable"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	writeWatchTable(&buf, table, map[string]bool{})
+	assert.Equal(t, "Node Status      \na    Healthy     \nbb   Unavailable \n", buf.String())
+
+	buf.Reset()
+	writeWatchTable(&buf, table, map[string]bool{"a": true})
+	assert.Contains(t, buf.String(), ansiHighlight+"a    Healthy")
+}
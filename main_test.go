@@ -18,6 +18,10 @@ func TestHelpExecutes(t *testing.T) {
 		args []string
 	}{
 		{"Base Command", []string{}},
+		{"Jobs Base Command", []string{"jobs"}},
+		{"Jobs List", []string{"jobs", "list"}},
+		{"Jobs Logs", []string{"jobs", "logs"}},
+		{"Jobs Schedule", []string{"jobs", "schedule"}},
 		{"Node Base Command", []string{"node"}},
 		{"Node Hostname", []string{"node", "hostname"}},
 		{"Node Init", []string{"node", "init"}},
@@ -25,12 +29,18 @@ func TestHelpExecutes(t *testing.T) {
 		{"Node SSH", []string{"node", "ssh"}},
 		{"Unifi Base Command", []string{"unifi"}},
 		{"Unifi Access Point", []string{"unifi", "ap"}},
+		{"User Base Command", []string{"user"}},
+		{"User Create", []string{"user", "create"}},
+		{"User Revoke", []string{"user", "revoke"}},
+		{"Cp", []string{"cp"}},
 		{"Deploy", []string{"deploy"}},
+		{"Forward", []string{"forward"}},
 		{"Kubeconfig", []string{"kubeconfig"}},
 		{"List", []string{"list"}},
 		{"Remove", []string{"remove"}},
 		{"Run", []string{"run"}},
 		{"Shell", []string{"shell"}},
+		{"Status", []string{"status"}},
 		{"Token", []string{"token"}},
 		{"Version", []string{"version"}},
 	}
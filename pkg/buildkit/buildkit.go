@@ -0,0 +1,31 @@
+package buildkit
+
+import (
+	"os"
+	"os/exec"
+)
+
+type ExecBuildctlFunc func(args ...string) error
+
+// ExecBuildctl - Run buildctl against an already running buildkitd, found
+// through BUILDKIT_HOST, or buildctl's default address.
+var ExecBuildctl ExecBuildctlFunc = func(args ...string) error {
+	osCmd := exec.Command("buildctl", args...)
+	osCmd.Stdin = os.Stdin
+	osCmd.Stdout = os.Stdout
+	osCmd.Stderr = os.Stderr
+
+	return osCmd.Run()
+}
+
+// ExecBuildctlDaemonless - Run buildctl through the wrapper that starts a
+// rootless buildkitd for the duration of the command, so that builds don't
+// need any privileged daemon at all.
+var ExecBuildctlDaemonless ExecBuildctlFunc = func(args ...string) error {
+	osCmd := exec.Command("buildctl-daemonless.sh", args...)
+	osCmd.Stdin = os.Stdin
+	osCmd.Stdout = os.Stdout
+	osCmd.Stderr = os.Stderr
+
+	return osCmd.Run()
+}
@@ -0,0 +1,158 @@
+package docker
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Registry host the docker CLI stores Docker Hub credentials under.
+const dockerHubAuthKey = "https://index.docker.io/v1/"
+
+// Credentials - A username and password, or identity token, for a registry.
+type Credentials struct {
+	Username      string `json:"username,omitempty"`
+	Password      string `json:"password,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+	ServerAddress string `json:"serveraddress,omitempty"`
+}
+
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth          string `json:"auth"`
+		IdentityToken string `json:"identitytoken"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+type GetCredentialHelperFunc func(helper, registry string) (string, error)
+
+// GetCredentialHelper - Ask a docker credential helper for the credentials
+// it has for the given registry.
+var GetCredentialHelper GetCredentialHelperFunc = func(helper, registry string) (string, error) {
+	osCmd := exec.Command(fmt.Sprintf("docker-credential-%s", helper), "get")
+	osCmd.Stdin = strings.NewReader(registry)
+	osCmd.Stderr = os.Stderr
+
+	outputBytes, err := osCmd.Output()
+	return string(outputBytes), err
+}
+
+// ConfigPath - Location of the docker CLI's config file, honouring
+// DOCKER_CONFIG the same way the CLI does.
+func ConfigPath() (string, error) {
+	if configDir := os.Getenv("DOCKER_CONFIG"); configDir != "" {
+		return filepath.Join(configDir, "config.json"), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".docker", "config.json"), nil
+}
+
+// RegistryCredentials - Find the credentials the docker CLI would use for
+// the given registry host.
+// Credential helpers are consulted before credentials stored in the config
+// file itself. Registries without credentials get empty ones, rather than
+// an error, so they can be used anonymously.
+func RegistryCredentials(registry string) (*Credentials, error) {
+	configPath, err := ConfigPath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Credentials{ServerAddress: registry}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var config dockerConfig
+	if err := json.Unmarshal(contents, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configPath, err)
+	}
+
+	authKey := registry
+	if registry == "docker.io" || registry == "index.docker.io" {
+		authKey = dockerHubAuthKey
+	}
+
+	helper := config.CredsStore
+	if registryHelper, ok := config.CredHelpers[authKey]; ok {
+		helper = registryHelper
+	}
+
+	if helper != "" {
+		output, err := GetCredentialHelper(helper, authKey)
+		if err == nil {
+			var helperCredentials struct {
+				Username string `json:"Username"`
+				Secret   string `json:"Secret"`
+			}
+			if err := json.Unmarshal([]byte(output), &helperCredentials); err != nil {
+				return nil, fmt.Errorf("failed to parse credentials from docker-credential-%s: %w", helper, err)
+			}
+
+			// Helpers report identity tokens with a magic username.
+			if helperCredentials.Username == "<token>" {
+				return &Credentials{IdentityToken: helperCredentials.Secret, ServerAddress: registry}, nil
+			}
+
+			return &Credentials{Username: helperCredentials.Username, Password: helperCredentials.Secret, ServerAddress: registry}, nil
+		}
+	}
+
+	for key, auth := range config.Auths {
+		if normalizeRegistryHost(key) != normalizeRegistryHost(authKey) {
+			continue
+		}
+
+		credentials := &Credentials{IdentityToken: auth.IdentityToken, ServerAddress: registry}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode credentials for %s: %w", registry, err)
+			}
+
+			username, password, found := strings.Cut(string(decoded), ":")
+			if !found {
+				return nil, fmt.Errorf("credentials for %s aren't in the form username:password", registry)
+			}
+
+			credentials.Username = username
+			credentials.Password = password
+		}
+
+		return credentials, nil
+	}
+
+	return &Credentials{ServerAddress: registry}, nil
+}
+
+// ImageRegistry - The registry host an image reference is pulled from.
+// References without a registry component come from Docker Hub.
+func ImageRegistry(image string) string {
+	first, _, found := strings.Cut(image, "/")
+	if !found || (!strings.ContainsAny(first, ".:") && first != "localhost") {
+		return "docker.io"
+	}
+
+	return first
+}
+
+func normalizeRegistryHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	host, _, _ = strings.Cut(host, "/")
+	return host
+}
@@ -0,0 +1,72 @@
+package docker
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func writeDockerConfig(t *testing.T, contents string) {
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	assert.Nil(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(contents), 0600))
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	writeDockerConfig(t, `{"auths": {"https://registry.example.com": {"auth": "`+auth+`"}, "https://index.docker.io/v1/": {"auth": "`+auth+`"}}}`)
+
+	credentials, err := RegistryCredentials("registry.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{Username: "user", Password: "pass", ServerAddress: "registry.example.com"}, credentials)
+
+	credentials, err = RegistryCredentials("docker.io")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{Username: "user", Password: "pass", ServerAddress: "docker.io"}, credentials)
+
+	credentials, err = RegistryCredentials("other.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{ServerAddress: "other.example.com"}, credentials)
+}
+
+func TestRegistryCredentialsHelper(t *testing.T) {
+	writeDockerConfig(t, `{"credsStore": "desktop", "credHelpers": {"registry.example.com": "pass"}}`)
+
+	oldGetCredentialHelper := GetCredentialHelper
+	defer func() { GetCredentialHelper = oldGetCredentialHelper }()
+
+	helpers := []string{}
+	GetCredentialHelper = func(helper, registry string) (string, error) {
+		helpers = append(helpers, helper)
+		return `{"ServerURL": "` + registry + `", "Username": "<token>", "Secret": "identity"}`, nil
+	}
+
+	credentials, err := RegistryCredentials("registry.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{IdentityToken: "identity", ServerAddress: "registry.example.com"}, credentials)
+
+	_, err = RegistryCredentials("other.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"pass", "desktop"}, helpers)
+}
+
+func TestRegistryCredentialsMissingConfig(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	credentials, err := RegistryCredentials("registry.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, &Credentials{ServerAddress: "registry.example.com"}, credentials)
+}
+
+func TestImageRegistry(t *testing.T) {
+	assert.Equal(t, "docker.io", ImageRegistry("python:3.7"))
+	assert.Equal(t, "docker.io", ImageRegistry("library/python:3.7"))
+	assert.Equal(t, "registry.example.com", ImageRegistry("registry.example.com/app:1.0"))
+	assert.Equal(t, "localhost:5000", ImageRegistry("localhost:5000/app"))
+	assert.Equal(t, "localhost", ImageRegistry("localhost/app"))
+}
@@ -0,0 +1,432 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"github.com/moby/patternmatcher"
+	"github.com/moby/patternmatcher/ignorefile"
+)
+
+// Socket the Docker Engine listens on when DOCKER_HOST isn't set.
+const DefaultEngineHost = "unix:///var/run/docker.sock"
+
+// EngineClient - A minimal client for the Docker Engine API, covering what's
+// needed to build, tag, pull, and push images without the docker CLI.
+type EngineClient struct {
+	httpClient *http.Client
+	baseUrl    string
+}
+
+// EngineBuildOptions - Parameters of an image build through the Docker
+// Engine API.
+type EngineBuildOptions struct {
+	// Path of the Dockerfile, relative to the build context.
+	Dockerfile string
+	Tag        string
+	Target     string
+	Platform   string
+	BuildArgs  map[string]string
+	CacheFrom  []string
+	Pull       bool
+}
+
+type engineMessage struct {
+	Stream      string `json:"stream"`
+	Status      string `json:"status"`
+	ID          string `json:"id"`
+	Progress    string `json:"progress"`
+	Error       string `json:"error"`
+	ErrorDetail struct {
+		Message string `json:"message"`
+	} `json:"errorDetail"`
+	Aux json.RawMessage `json:"aux"`
+}
+
+// NewEngineClient - Create a client for the Docker Engine at the given host,
+// e.g. unix:///var/run/docker.sock, or tcp://127.0.0.1:2375.
+// An empty host uses DOCKER_HOST, falling back to the default socket.
+func NewEngineClient(host string) (*EngineClient, error) {
+	if host == "" {
+		host = os.Getenv("DOCKER_HOST")
+	}
+	if host == "" {
+		host = DefaultEngineHost
+	}
+
+	hostUrl, err := url.Parse(host)
+	if err != nil {
+		return nil, fmt.Errorf("invalid docker host %s: %w", host, err)
+	}
+
+	switch hostUrl.Scheme {
+	case "unix":
+		socketPath := hostUrl.Path
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", socketPath)
+			},
+		}
+		return &EngineClient{&http.Client{Transport: transport}, "http://docker"}, nil
+	case "tcp", "http":
+		return &EngineClient{&http.Client{}, fmt.Sprintf("http://%s", hostUrl.Host)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host %s", host)
+	}
+}
+
+// Build - Build the image in the given context directory, writing the
+// build's output to out.
+func (c *EngineClient) Build(ctx context.Context, contextDir string, options EngineBuildOptions, out io.Writer) error {
+	query := url.Values{}
+	query.Set("t", options.Tag)
+	query.Set("rm", "1")
+	if options.Dockerfile != "" {
+		query.Set("dockerfile", filepath.ToSlash(options.Dockerfile))
+	}
+	if options.Target != "" {
+		query.Set("target", options.Target)
+	}
+	if options.Platform != "" {
+		query.Set("platform", options.Platform)
+	}
+	if options.Pull {
+		query.Set("pull", "1")
+	}
+	if len(options.BuildArgs) != 0 {
+		buildArgs, err := json.Marshal(options.BuildArgs)
+		if err != nil {
+			return err
+		}
+		query.Set("buildargs", string(buildArgs))
+	}
+	if len(options.CacheFrom) != 0 {
+		cacheFrom, err := json.Marshal(options.CacheFrom)
+		if err != nil {
+			return err
+		}
+		query.Set("cachefrom", string(cacheFrom))
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(writeContextTar(contextDir, writer))
+	}()
+	defer reader.Close()
+
+	headers := http.Header{"Content-Type": {"application/x-tar"}}
+	_, err := c.stream(ctx, http.MethodPost, "/build", query, headers, reader, out)
+	return err
+}
+
+// Pull - Pull the image from its registry.
+func (c *EngineClient) Pull(ctx context.Context, image string, out io.Writer) error {
+	repository, tag := SplitImageTag(image)
+
+	query := url.Values{}
+	query.Set("fromImage", repository)
+	query.Set("tag", tag)
+
+	headers, err := registryAuthHeader(image)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.stream(ctx, http.MethodPost, "/images/create", query, headers, nil, out)
+	return err
+}
+
+// Tag - Give an existing image another name.
+func (c *EngineClient) Tag(ctx context.Context, source, target string) error {
+	repository, tag := SplitImageTag(target)
+
+	query := url.Values{}
+	query.Set("repo", repository)
+	query.Set("tag", tag)
+
+	_, err := c.stream(ctx, http.MethodPost, fmt.Sprintf("/images/%s/tag", source), query, http.Header{}, nil, io.Discard)
+	return err
+}
+
+// Push - Push the image to its registry, returning the digest of the pushed
+// manifest.
+func (c *EngineClient) Push(ctx context.Context, image string, out io.Writer) (string, error) {
+	repository, tag := SplitImageTag(image)
+
+	query := url.Values{}
+	query.Set("tag", tag)
+
+	headers, err := registryAuthHeader(image)
+	if err != nil {
+		return "", err
+	}
+
+	auxes, err := c.stream(ctx, http.MethodPost, fmt.Sprintf("/images/%s/push", repository), query, headers, nil, out)
+	if err != nil {
+		return "", err
+	}
+
+	for _, aux := range auxes {
+		var pushResult struct {
+			Digest string `json:"Digest"`
+		}
+		if err := json.Unmarshal(aux, &pushResult); err == nil && pushResult.Digest != "" {
+			return pushResult.Digest, nil
+		}
+	}
+
+	return "", nil
+}
+
+// ImageExists - Whether the engine has a local copy of the image.
+func (c *EngineClient) ImageExists(ctx context.Context, image string) (bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/images/%s/json", c.baseUrl, image), nil)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return false, engineConnectionError(err)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, engineResponseError(response)
+	}
+}
+
+// SplitImageTag - Separate an image reference into its repository and tag,
+// defaulting to the latest tag.
+// References with a digest are split into their repository and digest,
+// dropping any tag alongside the digest.
+func SplitImageTag(image string) (string, string) {
+	if name, digest, found := strings.Cut(image, "@"); found {
+		repository, _ := SplitImageTag(name)
+		return repository, digest
+	}
+
+	lastSlash := strings.LastIndex(image, "/")
+	lastColon := strings.LastIndex(image, ":")
+	if lastColon > lastSlash {
+		return image[:lastColon], image[lastColon+1:]
+	}
+
+	return image, "latest"
+}
+
+// stream - Send a request whose response is a stream of JSON progress
+// messages, writing progress to out and returning any auxiliary messages.
+func (c *EngineClient) stream(ctx context.Context, method, path string, query url.Values, headers http.Header, body io.Reader, out io.Writer) ([]json.RawMessage, error) {
+	requestUrl := fmt.Sprintf("%s%s?%s", c.baseUrl, path, query.Encode())
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl, body)
+	if err != nil {
+		return nil, err
+	}
+	request.Header = headers
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, engineConnectionError(err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return nil, engineResponseError(response)
+	}
+
+	auxes := []json.RawMessage{}
+	scanner := bufio.NewScanner(response.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+
+		var message engineMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			return nil, fmt.Errorf("unexpected message from docker engine: %s", string(line))
+		}
+
+		if message.Error != "" {
+			return nil, errors.New(message.Error)
+		}
+
+		if len(message.Aux) != 0 {
+			auxes = append(auxes, message.Aux)
+		}
+
+		if message.Stream != "" {
+			fmt.Fprint(out, message.Stream)
+		} else if message.Status != "" {
+			if message.ID != "" {
+				fmt.Fprintf(out, "%s: %s %s\n", message.ID, message.Status, message.Progress)
+			} else {
+				fmt.Fprintln(out, message.Status)
+			}
+		}
+	}
+
+	return auxes, scanner.Err()
+}
+
+func engineConnectionError(err error) error {
+	if errors.Is(err, fs.ErrPermission) {
+		return fmt.Errorf("permission denied connecting to the docker engine; add your user to the docker group, or use a rootless builder: %w", err)
+	}
+
+	return fmt.Errorf("failed to connect to the docker engine: %w", err)
+}
+
+func engineResponseError(response *http.Response) error {
+	var message struct {
+		Message string `json:"message"`
+	}
+
+	contents, _ := io.ReadAll(response.Body)
+	if err := json.Unmarshal(contents, &message); err != nil || message.Message == "" {
+		return fmt.Errorf("docker engine returned %s", response.Status)
+	}
+
+	return fmt.Errorf("docker engine returned %s: %s", response.Status, message.Message)
+}
+
+func registryAuthHeader(image string) (http.Header, error) {
+	credentials, err := RegistryCredentials(ImageRegistry(image))
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(credentials)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Header{"X-Registry-Auth": {base64.URLEncoding.EncodeToString(encoded)}}, nil
+}
+
+// writeContextTar - Write the build context directory as a tar stream.
+func writeContextTar(contextDir string, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	err := WalkBuildContext(contextDir, func(path, relPath string, entry fs.DirEntry) error {
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = relPath
+
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		_, err = io.Copy(tw, file)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// WalkBuildContext - Call fn for everything in a build context that would be
+// sent to the builder, in lexical order, skipping whatever the .dockerignore
+// at the root of the context excludes, with the same rules docker uses.
+// Paths are given both as they are on disk, and relative to the context,
+// slash separated.
+func WalkBuildContext(contextDir string, fn func(path, relPath string, entry fs.DirEntry) error) error {
+	matcher, err := readDockerignore(contextDir)
+	if err != nil {
+		return err
+	}
+
+	return filepath.WalkDir(contextDir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(contextDir, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		ignored, err := matcher.MatchesOrParentMatches(relPath)
+		if err != nil {
+			return err
+		}
+
+		if ignored {
+			// A later exclusion can bring back files from inside an
+			//   ignored directory, so directories can only be skipped
+			//   entirely when there aren't any.
+			if entry.IsDir() && !matcher.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		return fn(path, relPath, entry)
+	})
+}
+
+func readDockerignore(contextDir string) (*patternmatcher.PatternMatcher, error) {
+	file, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if errors.Is(err, os.ErrNotExist) {
+		return patternmatcher.New([]string{})
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	patterns, err := ignorefile.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .dockerignore of %s: %w", contextDir, err)
+	}
+
+	return patternmatcher.New(patterns)
+}
@@ -0,0 +1,185 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func testEngine(t *testing.T, handler http.HandlerFunc) *EngineClient {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewEngineClient(strings.Replace(server.URL, "http://", "tcp://", 1))
+	assert.Nil(t, err)
+	return client
+}
+
+func TestNewEngineClient(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+
+	client, err := NewEngineClient("")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://docker", client.baseUrl)
+
+	client, err = NewEngineClient("tcp://127.0.0.1:2375")
+	assert.Nil(t, err)
+	assert.Equal(t, "http://127.0.0.1:2375", client.baseUrl)
+
+	_, err = NewEngineClient("ssh://somewhere")
+	assert.Equal(t, "unsupported docker host ssh://somewhere", err.Error())
+}
+
+func TestEngineBuild(t *testing.T) {
+	contextDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, ".dockerignore"), []byte("secret.txt\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "secret.txt"), []byte("shh"), 0644))
+
+	var query map[string][]string
+	files := []string{}
+	client := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/build", r.URL.Path)
+		assert.Equal(t, "application/x-tar", r.Header.Get("Content-Type"))
+		query = r.URL.Query()
+
+		tr := tar.NewReader(r.Body)
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			assert.Nil(t, err)
+			files = append(files, header.Name)
+		}
+
+		w.Write([]byte(`{"stream":"Step 1/1 : FROM scratch\n"}` + "\n"))
+		w.Write([]byte(`{"aux":{"ID":"sha256:abc"}}` + "\n"))
+	})
+
+	var out bytes.Buffer
+	err := client.Build(context.Background(), contextDir, EngineBuildOptions{
+		Tag:       "registry.example.com/app:1.0",
+		Platform:  "linux/arm64",
+		BuildArgs: map[string]string{"VERSION": "1.0"},
+		CacheFrom: []string{"registry.example.com/app:cache"},
+	}, &out)
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{".dockerignore", "Dockerfile"}, files)
+	assert.Equal(t, "registry.example.com/app:1.0", query["t"][0])
+	assert.Equal(t, "linux/arm64", query["platform"][0])
+	assert.Equal(t, `{"VERSION":"1.0"}`, query["buildargs"][0])
+	assert.Equal(t, `["registry.example.com/app:cache"]`, query["cachefrom"][0])
+	assert.Equal(t, "Step 1/1 : FROM scratch\n", out.String())
+}
+
+func TestEngineBuildError(t *testing.T) {
+	client := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Write([]byte(`{"errorDetail":{"message":"no such file"},"error":"no such file"}` + "\n"))
+	})
+
+	err := client.Build(context.Background(), t.TempDir(), EngineBuildOptions{Tag: "app"}, io.Discard)
+	assert.Equal(t, "no such file", err.Error())
+}
+
+func TestEnginePush(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	client := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/images/registry.example.com/app/push", r.URL.Path)
+		assert.Equal(t, "1.0", r.URL.Query().Get("tag"))
+
+		decoded, err := base64.URLEncoding.DecodeString(r.Header.Get("X-Registry-Auth"))
+		assert.Nil(t, err)
+
+		var credentials Credentials
+		assert.Nil(t, json.Unmarshal(decoded, &credentials))
+		assert.Equal(t, "registry.example.com", credentials.ServerAddress)
+
+		w.Write([]byte(`{"status":"Pushed","id":"abc"}` + "\n"))
+		w.Write([]byte(`{"aux":{"Tag":"1.0","Digest":"sha256:def","Size":1}}` + "\n"))
+	})
+
+	var out bytes.Buffer
+	digest, err := client.Push(context.Background(), "registry.example.com/app:1.0", &out)
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:def", digest)
+	assert.Equal(t, "abc: Pushed \n", out.String())
+}
+
+func TestEngineImageExists(t *testing.T) {
+	client := testEngine(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/images/python:3.7/json" {
+			w.Write([]byte("{}"))
+			return
+		}
+
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	exists, err := client.ImageExists(context.Background(), "python:3.7")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+
+	exists, err = client.ImageExists(context.Background(), "python:3.8")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+}
+
+func TestSplitImageTag(t *testing.T) {
+	tests := []struct {
+		image      string
+		repository string
+		tag        string
+	}{
+		{"python", "python", "latest"},
+		{"python:3.7", "python", "3.7"},
+		{"registry.example.com:5000/app", "registry.example.com:5000/app", "latest"},
+		{"registry.example.com:5000/app:1.0", "registry.example.com:5000/app", "1.0"},
+		{"python@sha256:abc", "python", "sha256:abc"},
+		{"registry.example.com:5000/app:1.0@sha256:abc", "registry.example.com:5000/app", "sha256:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			repository, tag := SplitImageTag(tt.image)
+			assert.Equal(t, tt.repository, repository)
+			assert.Equal(t, tt.tag, tag)
+		})
+	}
+}
+
+func TestWalkBuildContext(t *testing.T) {
+	contextDir := t.TempDir()
+	dockerignore := "**/*.log\nvendor\n!vendor/keep.txt\n# comment\n/build/\n"
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, ".dockerignore"), []byte(dockerignore), 0644))
+	for _, file := range []string{"Dockerfile", "app.log", "src/main.go", "src/debug.log", "vendor/lib.go", "vendor/keep.txt", "build/app"} {
+		path := filepath.Join(contextDir, filepath.FromSlash(file))
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(file), 0644))
+	}
+
+	walked := []string{}
+	err := WalkBuildContext(contextDir, func(path, relPath string, entry fs.DirEntry) error {
+		walked = append(walked, relPath)
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{".dockerignore", "Dockerfile", "src", "src/main.go", "vendor/keep.txt"}, walked)
+}
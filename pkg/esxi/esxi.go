@@ -1,8 +1,11 @@
 package esxi
 
 import (
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -10,6 +13,9 @@ import (
 	"github.com/Eagerod/hope/pkg/ssh"
 )
 
+// ErrVmNotFound - Returned when the host has no VM with the given name.
+var ErrVmNotFound error = errors.New("failed to find a VM")
+
 const VmStatePoweredOn string = "Powered on"
 const VmStatePoweredOff string = "Powered off"
 
@@ -164,3 +170,70 @@ func PowerStateOfVmNamed(host string, vmName string) (string, error) {
 
 	return PowerStateOfVm(host, vmId)
 }
+
+// DatastoreUsage - Get the capacity and free space, in bytes, of the named
+// datastore.
+func DatastoreUsage(host string, datastore string) (uint64, uint64, error) {
+	output, err := ssh.GetSSH(host, "esxcli", "--formatter", "csv", "--format-param", "fields=VolumeName,Size,Free", "storage", "filesystem", "list")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return parseDatastoreUsage(output, datastore)
+}
+
+func parseDatastoreUsage(output string, datastore string) (uint64, uint64, error) {
+	reader := csv.NewReader(strings.NewReader(output))
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(records) == 0 {
+		return 0, 0, errors.New("no filesystems found in esxcli output")
+	}
+
+	// esxcli doesn't promise any particular column order, so find the
+	//   columns by name.
+	nameIndex, sizeIndex, freeIndex := -1, -1, -1
+	for i, header := range records[0] {
+		switch strings.TrimSpace(header) {
+		case "VolumeName":
+			nameIndex = i
+		case "Size":
+			sizeIndex = i
+		case "Free":
+			freeIndex = i
+		}
+	}
+
+	if nameIndex == -1 || sizeIndex == -1 || freeIndex == -1 {
+		return 0, 0, fmt.Errorf("failed to find filesystem columns in: %s", records[0])
+	}
+
+	for _, record := range records[1:] {
+		if len(record) <= nameIndex || len(record) <= sizeIndex || len(record) <= freeIndex {
+			continue
+		}
+
+		if strings.TrimSpace(record[nameIndex]) != datastore {
+			continue
+		}
+
+		size, err := strconv.ParseUint(strings.TrimSpace(record[sizeIndex]), 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		free, err := strconv.ParseUint(strings.TrimSpace(record[freeIndex]), 10, 64)
+		if err != nil {
+			return 0, 0, err
+		}
+
+		return size, free, nil
+	}
+
+	return 0, 0, fmt.Errorf("failed to find datastore %s", datastore)
+}
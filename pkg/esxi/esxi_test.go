@@ -0,0 +1,28 @@
+package esxi
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+const testFilesystemListString string = `Free,Size,VolumeName,
+412316860416,999922073600,Main,
+0,4293591040,,
+76155392,261853184,BOOTBANK1,
+`
+
+func TestParseDatastoreUsage(t *testing.T) {
+	size, free, err := parseDatastoreUsage(testFilesystemListString, "Main")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(999922073600), size)
+	assert.Equal(t, uint64(412316860416), free)
+
+	_, _, err = parseDatastoreUsage(testFilesystemListString, "Backup")
+	assert.Equal(t, "failed to find datastore Backup", err.Error())
+
+	_, _, err = parseDatastoreUsage("Name,Size\nMain,1\n", "Main")
+	assert.Error(t, err)
+}
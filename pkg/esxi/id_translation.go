@@ -26,7 +26,7 @@ func idFromName(host string, vmName string) (string, error) {
 		}
 	}
 
-	return "", fmt.Errorf("failed to vm named %s on %s", vmName, host)
+	return "", fmt.Errorf("%w named %s on %s", ErrVmNotFound, vmName, host)
 }
 
 func worldIdFromName(host string, vmName string) (string, error) {
@@ -1,9 +1,12 @@
 package helm
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"regexp"
 	"strings"
 )
 
@@ -38,7 +41,7 @@ func HasRepo(repo, aUrl string) (bool, error) {
 
 	for _, repoLine := range strings.Split(currentRepos, "\n") {
 		repoComponents := strings.Fields(repoLine)
-		if repoComponents[0] != repo {
+		if len(repoComponents) < 2 || repoComponents[0] != repo {
 			continue
 		}
 
@@ -52,3 +55,69 @@ func HasRepo(repo, aUrl string) (bool, error) {
 
 	return false, nil
 }
+
+// ErrReleaseNotFound - Returned when asking about a release that isn't
+// installed.
+var ErrReleaseNotFound = errors.New("release: not found")
+
+type listResult struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+}
+
+// ReleaseStatus - Get the status helm reports for the latest revision of a
+// release, e.g. "deployed", "failed", "pending-upgrade".
+// Releases are listed rather than asked for by name, so that a release that
+// isn't installed can be told apart from helm failing to reach the cluster.
+func ReleaseStatus(release, namespace string) (string, error) {
+	allArgs := []string{"list", "--all", "--filter", "^" + regexp.QuoteMeta(release) + "$", "-o", "json"}
+	if namespace != "" {
+		allArgs = append(allArgs, "--namespace", namespace)
+	}
+
+	output, err := GetHelm(allArgs...)
+	if err != nil {
+		return "", err
+	}
+
+	var results []listResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		return "", err
+	}
+
+	for _, result := range results {
+		if result.Name == release {
+			return result.Status, nil
+		}
+	}
+
+	return "", ErrReleaseNotFound
+}
+
+type searchResult struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HasChartVersion - Whether the local repo cache already knows about the
+// given version of a chart, meaning the repo doesn't need to be updated to
+// install it.
+func HasChartVersion(chart, version string) (bool, error) {
+	output, err := GetHelm("search", "repo", chart, "--version", version, "-o", "json")
+	if err != nil {
+		return false, err
+	}
+
+	var results []searchResult
+	if err := json.Unmarshal([]byte(output), &results); err != nil {
+		return false, err
+	}
+
+	for _, result := range results {
+		if result.Name == chart && strings.TrimPrefix(result.Version, "v") == strings.TrimPrefix(version, "v") {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
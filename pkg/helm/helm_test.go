@@ -1,6 +1,7 @@
 package helm
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -66,6 +67,11 @@ func (s *HelmTestSuite) TestHasRepo() {
 	assert.NoError(t, err)
 	assert.False(t, hasRepo)
 
+	r = "NAME     	URL\nother	https://example.com/other\n"
+	hasRepo, err = HasRepo("test", "https://example.com/charts")
+	assert.NoError(t, err)
+	assert.False(t, hasRepo)
+
 	r = "NAME     	URL\ntest	https://example.com/strahc"
 	hasRepo, err = HasRepo("test", "https://example.com/charts")
 	if assert.Error(t, err) {
@@ -73,3 +79,68 @@ func (s *HelmTestSuite) TestHasRepo() {
 	}
 	assert.False(t, hasRepo)
 }
+
+func (s *HelmTestSuite) TestReleaseStatus() {
+	t := s.T()
+
+	GetHelm = func(args ...string) (string, error) {
+		assert.Equal(t, []string{"list", "--all", "--filter", "^dashboard$", "-o", "json", "--namespace", "kubernetes-dashboard"}, args)
+		return `[{"name":"dashboard","namespace":"kubernetes-dashboard","revision":"1","status":"deployed"}]`, nil
+	}
+
+	status, err := ReleaseStatus("dashboard", "kubernetes-dashboard")
+	assert.NoError(t, err)
+	assert.Equal(t, "deployed", status)
+
+	GetHelm = func(args ...string) (string, error) {
+		assert.Equal(t, []string{"list", "--all", "--filter", "^dashboard$", "-o", "json"}, args)
+		return `[{"name":"dashboard","revision":"2","status":"pending-upgrade"}]`, nil
+	}
+
+	status, err = ReleaseStatus("dashboard", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "pending-upgrade", status)
+
+	GetHelm = func(args ...string) (string, error) {
+		return "[]", nil
+	}
+
+	_, err = ReleaseStatus("dashboard", "")
+	assert.Equal(t, ErrReleaseNotFound, err)
+
+	GetHelm = func(args ...string) (string, error) {
+		return "", errors.New("exit status 1")
+	}
+
+	_, err = ReleaseStatus("dashboard", "")
+	assert.EqualError(t, err, "exit status 1")
+}
+
+func (s *HelmTestSuite) TestHasChartVersion() {
+	t := s.T()
+
+	GetHelm = func(args ...string) (string, error) {
+		assert.Equal(t, []string{"search", "repo", "dashboard/dashboard", "--version", "7.11.1", "-o", "json"}, args)
+		return `[{"name":"dashboard/dashboard","version":"7.11.1","app_version":"","description":""},{"name":"dashboard/dashboard-extras","version":"7.11.1"}]`, nil
+	}
+
+	hasVersion, err := HasChartVersion("dashboard/dashboard", "7.11.1")
+	assert.NoError(t, err)
+	assert.True(t, hasVersion)
+
+	GetHelm = func(args ...string) (string, error) {
+		return `[{"name":"dashboard/dashboard-extras","version":"7.11.1"}]`, nil
+	}
+
+	hasVersion, err = HasChartVersion("dashboard/dashboard", "7.11.1")
+	assert.NoError(t, err)
+	assert.False(t, hasVersion)
+
+	GetHelm = func(args ...string) (string, error) {
+		return "[]", nil
+	}
+
+	hasVersion, err = HasChartVersion("dashboard/dashboard", "7.11.1")
+	assert.NoError(t, err)
+	assert.False(t, hasVersion)
+}
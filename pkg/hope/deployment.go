@@ -1,32 +1,121 @@
 package hope
 
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
 import (
 	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
-func KubectlApplyF(kubectl *kubeutil.Kubectl, path string) error {
-	return kubeutil.ExecKubectl(kubectl, "apply", "-f", path)
+// FieldManager - The field manager hope applies objects as.
+const FieldManager string = "hope"
+
+// ResourceLabel - The label applied to every object deployed by a resource,
+// holding the resource's name.
+const ResourceLabel string = "hope.resource"
+
+// ApplyResourceManifests - Server-side apply the rendered manifests of the
+// named resource, labelling every object with the resource's name.
+// Returns the objects that were applied.
+func ApplyResourceManifests(kubectl *kubeutil.Kubectl, resourceName string, manifests string) ([]ManifestObject, error) {
+	objects, err := kubeutil.DecodeManifests(manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, object := range objects {
+		labels := object.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+
+		labels[ResourceLabel] = resourceName
+		object.SetLabels(labels)
+	}
+
+	if err := kubeutil.ApplyObjects(kubectl, objects, FieldManager); err != nil {
+		return nil, err
+	}
+
+	return unstructuredManifestObjects(objects), nil
+}
+
+// StaleResourceObjects - Find the objects previously deployed by the named
+// resource that are no longer part of it.
+func StaleResourceObjects(kubectl *kubeutil.Kubectl, resourceName string, current []ManifestObject) ([]ManifestObject, error) {
+	selector := fmt.Sprintf("%s=%s", ResourceLabel, resourceName)
+	existing, err := kubeutil.ListAppliedObjects(kubectl, selector, FieldManager)
+	if err != nil {
+		return nil, err
+	}
+
+	return staleObjects(current, unstructuredManifestObjects(existing)), nil
 }
 
-func KubectlApplyStdIn(kubectl *kubeutil.Kubectl, stdin string) error {
-	return kubeutil.InKubectl(kubectl, stdin, "apply", "-f", "-")
+// PruneObjects - Delete each of the given objects.
+func PruneObjects(log *logrus.Entry, kubectl *kubeutil.Kubectl, objects []ManifestObject) error {
+	for _, object := range objects {
+		log.Info("Pruning ", object.String())
+		if err := DeleteManifestObject(kubectl, object); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func KubectlCreateStdIn(kubectl *kubeutil.Kubectl, stdin string) error {
-	return kubeutil.InKubectl(kubectl, stdin, "create", "-f", "-")
+// DeleteManifestObject - Delete the object, along with anything it owns.
+// Objects that don't exist are ignored.
+func DeleteManifestObject(kubectl *kubeutil.Kubectl, object ManifestObject) error {
+	return kubeutil.DeleteObject(kubectl, manifestObjectUnstructured(object))
 }
 
-func KubectlGetCreateStdIn(kubectl *kubeutil.Kubectl, stdin string, args ...string) (string, error) {
-	allArgs := []string{"create"}
-	allArgs = append(allArgs, args...)
-	allArgs = append(allArgs, "-f", "-")
-	return kubeutil.GetInKubectl(kubectl, stdin, allArgs...)
+// manifestObjectUnstructured - An object holding only what identifies the
+// manifest object, for asking the cluster about it.
+func manifestObjectUnstructured(object ManifestObject) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(object.APIVersion)
+	u.SetKind(object.Kind)
+	u.SetNamespace(object.Namespace)
+	u.SetName(object.Name)
+	return u
 }
 
-func KubectlDeleteF(kubectl *kubeutil.Kubectl, path string) error {
-	return kubeutil.ExecKubectl(kubectl, "delete", "--ignore-not-found", "-f", path)
+// staleObjects - The existing objects that aren't among the current ones.
+// Objects are compared without their API version, since the cluster may
+// report a different version of a type than the one it was applied with.
+func staleObjects(current []ManifestObject, existing []ManifestObject) []ManifestObject {
+	key := func(o ManifestObject) string {
+		return strings.Join([]string{o.Group(), o.Kind, o.Namespace, o.Name}, "/")
+	}
+
+	keep := map[string]bool{}
+	for _, object := range current {
+		keep[key(object)] = true
+	}
+
+	stale := []ManifestObject{}
+	for _, object := range existing {
+		if !keep[key(object)] {
+			stale = append(stale, object)
+		}
+	}
+
+	return stale
 }
 
-func KubectlDeleteStdIn(kubectl *kubeutil.Kubectl, stdin string) error {
-	return kubeutil.InKubectl(kubectl, stdin, "delete", "--ignore-not-found", "-f", "-")
+func unstructuredManifestObjects(objects []*unstructured.Unstructured) []ManifestObject {
+	manifestObjects := []ManifestObject{}
+	for _, object := range objects {
+		manifestObjects = append(manifestObjects, ManifestObject{object.GetAPIVersion(), object.GetKind(), object.GetName(), object.GetNamespace()})
+	}
+
+	return manifestObjects
 }
@@ -0,0 +1,91 @@
+package hope
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+func TestApplyResourceManifests(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Dynamic: dynamicClient, Mapper: mapper})
+	manifests := `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  labels:
+    app: web
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: web
+`
+
+	objects, err := ApplyResourceManifests(kubectl, "some-resource", manifests)
+	assert.Nil(t, err)
+	assert.Equal(t, []ManifestObject{
+		{"v1", "ConfigMap", "config", "default"},
+		{"apps/v1", "Deployment", "web", "web"},
+	}, objects)
+
+	labels := []map[string]string{}
+	for _, action := range dynamicClient.Actions() {
+		patch := action.(k8stesting.PatchAction)
+		assert.Equal(t, types.ApplyPatchType, patch.GetPatchType())
+		var object struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		}
+		assert.Nil(t, json.Unmarshal(patch.GetPatch(), &object))
+		labels = append(labels, object.Metadata.Labels)
+	}
+
+	assert.Equal(t, []map[string]string{
+		{"app": "web", "hope.resource": "some-resource"},
+		{"hope.resource": "some-resource"},
+	}, labels)
+}
+
+func TestStaleObjects(t *testing.T) {
+	current := []ManifestObject{
+		{"v1", "ConfigMap", "config", "default"},
+		{"apps/v1", "Deployment", "web", "web"},
+	}
+
+	existing := []ManifestObject{
+		{"v1", "ConfigMap", "config", "default"},
+		{"v1", "ConfigMap", "config", "web"},
+		{"apps/v1beta1", "Deployment", "web", "web"},
+		{"apps/v1", "StatefulSet", "web", "web"},
+	}
+
+	assert.Equal(t, []ManifestObject{
+		{"v1", "ConfigMap", "config", "web"},
+		{"apps/v1", "StatefulSet", "web", "web"},
+	}, staleObjects(current, existing))
+}
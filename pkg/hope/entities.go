@@ -3,6 +3,7 @@ package hope
 import (
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ResourceType enum to differentiate the types of resource definitions that
@@ -36,6 +37,14 @@ const (
 	// ResourceTypeHelm - Add a helm chart, and ensure the version specified
 	// is running in the cluster.
 	ResourceTypeHelm
+
+	// ResourceTypeKustomize - Build a kustomization directory, and apply the
+	//   rendered resources.
+	ResourceTypeKustomize
+
+	// ResourceTypeMirror - Copy images from upstream registries into another
+	//   registry.
+	ResourceTypeMirror
 )
 
 type NodeRole int
@@ -77,29 +86,115 @@ const (
 	NodeStatusDoesNotExist
 )
 
+type ResourceStatus int
+
+const (
+	// ResourceStatusUnknown - The state of the resource couldn't be
+	//   determined.
+	ResourceStatusUnknown ResourceStatus = iota
+
+	// ResourceStatusHealthy - Everything the resource defines exists, and
+	//   has finished rolling out.
+	ResourceStatusHealthy
+
+	// ResourceStatusProgressing - Everything the resource defines exists,
+	//   but some of it is still rolling out.
+	ResourceStatusProgressing
+
+	// ResourceStatusMissing - Some or all of what the resource defines
+	//   hasn't been deployed.
+	ResourceStatusMissing
+
+	// ResourceStatusFailed - The resource was deployed, but some part of it
+	//   has failed.
+	ResourceStatusFailed
+
+	// ResourceStatusNotApplicable - The resource doesn't leave anything
+	//   behind that can be checked.
+	ResourceStatusNotApplicable
+)
+
+// NodeStatusCheck - The outcome of one of the individual probes run against
+// a node to determine its status.
+type NodeStatusCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+// NodeStatusReport - The status of a node, along with why it was given that
+// status, and every check that was run to get there.
+type NodeStatusReport struct {
+	Status NodeStatus
+	Reason string
+	Checks []NodeStatusCheck
+}
+
+// ResourceStatusReport - The status of a resource, along with details about
+// the part of the resource that led to that status.
+type ResourceStatusReport struct {
+	Status ResourceStatus
+	Detail string
+}
+
 // BuildSpec - Properties of a ResourceTypeDockerBuild
 type BuildSpec struct {
-	Path   string
-	Source string
-	Tag    string
-	Pull   string
+	Path       string
+	Source     string
+	Tag        string
+	Pull       string
+	Builder    string
+	Dockerfile string
+	Target     string
+	Platforms  []string
+	Secrets    []string
+	Cache      string
 }
 
 // ExecSpec - Properties of a ResourceTypeExec
+// Selector names the object whose pod to run in, as kind/name or a pod's
+// name, or is a label selector; with AllPods, it must be a label selector,
+// and the command is run in every matching pod, one after the other unless
+// Parallel is set.
 type ExecSpec struct {
-	Selector string
-	Timeout  string
-	Command  []string
+	Selector  string
+	Namespace string
+	Container string
+	Timeout   string
+	Command   []string
+	AllPods   bool
+	Parallel  bool
+	StdinFile string
+	TTY       bool
+	ExitCode  int
 }
 
+// HelmSpec - Properties of a ResourceTypeHelm
+// Charts can come from a repo (given by Repo and Path), an oci:// reference,
+// or a local chart directory.
+// Values is a yaml string rather than a map, so that its keys aren't
+// lowercased when the hope file is read.
 type HelmSpec struct {
-	Namespace  string
-	Release    string
-	Repo       string
-	Path       string
-	Chart      string
-	Version    string
-	ValuesFile string
+	Namespace   string
+	Release     string
+	Repo        string
+	Path        string
+	Chart       string
+	Version     string
+	ValuesFile  string
+	ValuesFiles []string
+	Values      string
+	Set         []string
+	Atomic      bool
+	Wait        bool
+	Timeout     string
+}
+
+// MirrorSpec - Properties of a ResourceTypeMirror
+type MirrorSpec struct {
+	Registry    string
+	Images      []string
+	Concurrency int
 }
 
 // Resource - Properties that can appear in any resources.
@@ -108,23 +203,46 @@ type HelmSpec struct {
 type Resource struct {
 	Name           string
 	File           string
+	Sha256         string
 	Inline         string
-	Parameters     []string
+	Kustomize      string
+	Parameters     []Parameter
 	FileParameters []string
 	Build          BuildSpec
 	Job            string
 	Exec           ExecSpec
 	Tags           []string
 	Helm           HelmSpec
+	Mirror         MirrorSpec
+	Wait           string
+	Timeout        string
+	MaxAttempts    int
+	LogFile        string
 }
 
 // Job - Properties that can appear in any ephemeral job definition.
-// TODO: Allow jobs to define max retry parameters, or accept them on the
-// command line.
 type Job struct {
+	Name        string
+	File        string
+	Parameters  []Parameter
+	Timeout     string
+	MaxAttempts int
+	LogFile     string
+	TTL         string
+	Schedule    string
+}
+
+// Forward - A port of the pods behind a service, or matching a label
+// selector, to forward a local port to.
+// For services, RemotePort is the service's port, rather than its pods'.
+// LocalPort defaults to RemotePort.
+type Forward struct {
 	Name       string
-	File       string
-	Parameters []string
+	Namespace  string
+	Service    string
+	Selector   string
+	LocalPort  int
+	RemotePort int
 }
 
 // Node - Defines a networked resource on which operations will typically be
@@ -179,6 +297,10 @@ func (rt ResourceType) String() string {
 		return "exec"
 	case ResourceTypeHelm:
 		return "helm"
+	case ResourceTypeKustomize:
+		return "kustomize"
+	case ResourceTypeMirror:
+		return "mirror"
 	}
 
 	return fmt.Sprintf("%%!ResourceType(%d)", rt)
@@ -214,6 +336,37 @@ func (ns NodeStatus) String() string {
 	return fmt.Sprintf("%%!NodeStatus(%d)", ns)
 }
 
+func (rs ResourceStatus) String() string {
+	switch rs {
+	case ResourceStatusUnknown:
+		return "Unknown"
+	case ResourceStatusHealthy:
+		return "Healthy"
+	case ResourceStatusProgressing:
+		return "Progressing"
+	case ResourceStatusMissing:
+		return "Missing"
+	case ResourceStatusFailed:
+		return "Failed"
+	case ResourceStatusNotApplicable:
+		return "-"
+	}
+
+	return fmt.Sprintf("%%!ResourceStatus(%d)", rs)
+}
+
+// AddCheck - Record the outcome of a check.
+// The first failing check becomes the reason for the node's status, and
+// marks the node as unavailable.
+func (report *NodeStatusReport) AddCheck(name string, passed bool, detail string) {
+	report.Checks = append(report.Checks, NodeStatusCheck{name, passed, detail})
+
+	if !passed && report.Reason == "" {
+		report.Status = NodeStatusUnavailable
+		report.Reason = fmt.Sprintf("%s: %s", name, detail)
+	}
+}
+
 // GetType - Scan through defined properties, and return the resource type
 // that the resource appears to implement.
 func (resource *Resource) GetType() (ResourceType, error) {
@@ -224,6 +377,9 @@ func (resource *Resource) GetType() (ResourceType, error) {
 	if len(resource.Inline) != 0 {
 		detectedTypes = append(detectedTypes, ResourceTypeInline)
 	}
+	if len(resource.Kustomize) != 0 {
+		detectedTypes = append(detectedTypes, ResourceTypeKustomize)
+	}
 	if (len(resource.Build.Path) != 0 || len(resource.Build.Source) != 0) && len(resource.Build.Tag) != 0 {
 		detectedTypes = append(detectedTypes, ResourceTypeDockerBuild)
 	}
@@ -233,9 +389,12 @@ func (resource *Resource) GetType() (ResourceType, error) {
 	if len(resource.Exec.Selector) != 0 && len(resource.Exec.Command) != 0 {
 		detectedTypes = append(detectedTypes, ResourceTypeExec)
 	}
-	if len(resource.Helm.Repo) != 0 && len(resource.Helm.Path) != 0 && len(resource.Helm.Chart) != 0 && len(resource.Helm.Release) != 0 {
+	if len(resource.Helm.Chart) != 0 && len(resource.Helm.Release) != 0 {
 		detectedTypes = append(detectedTypes, ResourceTypeHelm)
 	}
+	if len(resource.Mirror.Registry) != 0 && len(resource.Mirror.Images) != 0 {
+		detectedTypes = append(detectedTypes, ResourceTypeMirror)
+	}
 
 	switch len(detectedTypes) {
 	case 0:
@@ -251,6 +410,27 @@ func (resource *Resource) GetType() (ResourceType, error) {
 	}
 }
 
+// WaitTimeout - Whether the resource should be waited on after being
+// deployed, and for how long.
+// Wait can be given as a boolean, to wait for the provided default duration,
+// or as a duration itself.
+func (resource *Resource) WaitTimeout(defaultTimeout time.Duration) (time.Duration, bool, error) {
+	// Booleans in the yaml file are weakly converted to "1" or "0".
+	switch strings.ToLower(resource.Wait) {
+	case "", "false", "0":
+		return 0, false, nil
+	case "true", "1":
+		return defaultTimeout, true, nil
+	}
+
+	timeout, err := time.ParseDuration(resource.Wait)
+	if err != nil {
+		return 0, false, fmt.Errorf("resource '%s' has invalid wait: %s", resource.Name, resource.Wait)
+	}
+
+	return timeout, true, nil
+}
+
 // ConnectionString - Get the node's connection string
 func (node *Node) ConnectionString() string {
 	if node.User != "" {
@@ -2,6 +2,7 @@ package hope
 
 import (
 	"testing"
+	"time"
 )
 
 import (
@@ -19,6 +20,9 @@ func TestResourceType(t *testing.T) {
 		{"ResourceTypeDockerBuild", ResourceTypeDockerBuild, "docker"},
 		{"ResourceTypeJob", ResourceTypeJob, "job"},
 		{"ResourceTypeExec", ResourceTypeExec, "exec"},
+		{"ResourceTypeHelm", ResourceTypeHelm, "helm"},
+		{"ResourceTypeKustomize", ResourceTypeKustomize, "kustomize"},
+		{"ResourceTypeMirror", ResourceTypeMirror, "mirror"},
 		{"Improper ResourceType", 25, "%!ResourceType(25)"},
 	}
 	for _, tt := range tests {
@@ -47,3 +51,67 @@ func TestNodeRole(t *testing.T) {
 		})
 	}
 }
+
+func TestNodeStatusReportAddCheck(t *testing.T) {
+	report := NodeStatusReport{Status: NodeStatusHealthy}
+
+	report.AddCheck("ssh", true, "reachable")
+	assert.Equal(t, NodeStatusHealthy, report.Status)
+	assert.Equal(t, "", report.Reason)
+
+	report.AddCheck("kubelet", false, "Ready is False")
+	report.AddCheck("disk-pressure", false, "DiskPressure is True")
+	assert.Equal(t, NodeStatusUnavailable, report.Status)
+	assert.Equal(t, "kubelet: Ready is False", report.Reason)
+	assert.Equal(t, 3, len(report.Checks))
+}
+
+func TestResourceStatus(t *testing.T) {
+	var tests = []struct {
+		name   string
+		value  ResourceStatus
+		strval string
+	}{
+		{"ResourceStatusUnknown", ResourceStatusUnknown, "Unknown"},
+		{"ResourceStatusHealthy", ResourceStatusHealthy, "Healthy"},
+		{"ResourceStatusProgressing", ResourceStatusProgressing, "Progressing"},
+		{"ResourceStatusMissing", ResourceStatusMissing, "Missing"},
+		{"ResourceStatusFailed", ResourceStatusFailed, "Failed"},
+		{"ResourceStatusNotApplicable", ResourceStatusNotApplicable, "-"},
+		{"Improper ResourceStatus", 25, "%!ResourceStatus(25)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.strval, tt.value.String())
+		})
+	}
+}
+
+func TestResourceWaitTimeout(t *testing.T) {
+	var tests = []struct {
+		name    string
+		wait    string
+		timeout time.Duration
+		ok      bool
+	}{
+		{"Unset", "", 0, false},
+		{"False", "false", 0, false},
+		{"Weak False", "0", 0, false},
+		{"True", "true", time.Minute, true},
+		{"Weak True", "1", time.Minute, true},
+		{"Duration", "10m", 10 * time.Minute, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := Resource{Name: "a", Wait: tt.wait}
+			timeout, ok, err := resource.WaitTimeout(time.Minute)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.timeout, timeout)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+
+	resource := Resource{Name: "a", Wait: "forever"}
+	_, _, err := resource.WaitTimeout(time.Minute)
+	assert.Equal(t, "resource 'a' has invalid wait: forever", err.Error())
+}
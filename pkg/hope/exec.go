@@ -0,0 +1,218 @@
+package hope
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// ExecResource - Run the exec resource's command in the pod, or pods, it
+// selects, waiting up to its timeout for them to be running.
+// Unless a TTY is requested, the command's output is written to the log,
+// and stdin is only given from the resource's stdin file.
+// The command is expected to exit with the resource's exit code, which
+// defaults to 0.
+// Running in pods one after the other stops at the first pod the command
+// fails in.
+func ExecResource(log *logrus.Entry, kubectl *kubeutil.Kubectl, resource *Resource) error {
+	spec := resource.Exec
+	if spec.TTY && spec.AllPods {
+		return fmt.Errorf("exec resource %s can't use a tty with all pods", resource.Name)
+	}
+
+	var stdin []byte
+	if spec.StdinFile != "" {
+		var err error
+		if stdin, err = os.ReadFile(spec.StdinFile); err != nil {
+			return err
+		}
+	}
+
+	namespace := spec.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	pods, err := execPods(log, kubectl, namespace, &spec)
+	if err != nil {
+		return err
+	}
+
+	run := func(pod string) error {
+		podLog := log.WithField("pod", pod)
+		options := kubeutil.ExecOptions{Container: spec.Container, Command: spec.Command, TTY: spec.TTY}
+		if spec.TTY {
+			options.Stdin = os.Stdin
+			options.Stdout = os.Stdout
+		} else {
+			stdout := &logWriter{log: podLog.Info}
+			stderr := &logWriter{log: podLog.Warn}
+			defer stdout.Flush()
+			defer stderr.Flush()
+
+			options.Stdout = stdout
+			options.Stderr = stderr
+		}
+
+		if stdin != nil {
+			options.Stdin = bytes.NewReader(stdin)
+		}
+
+		podLog.Info("Running ", strings.Join(spec.Command, " "))
+		return checkExitCode(kubeutil.ExecInPod(context.Background(), kubectl, namespace, pod, options), spec.ExitCode)
+	}
+
+	errs := []error{}
+	if spec.Parallel {
+		var wg sync.WaitGroup
+		var lock sync.Mutex
+		for _, pod := range pods {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := run(pod); err != nil {
+					lock.Lock()
+					errs = append(errs, fmt.Errorf("%s: %w", pod, err))
+					lock.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+	} else {
+		for _, pod := range pods {
+			if err := run(pod); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", pod, err))
+				break
+			}
+		}
+	}
+
+	if len(errs) == 1 && len(pods) == 1 {
+		return errs[0]
+	} else if len(errs) != 0 {
+		return fmt.Errorf("exec failed in %d of %d pods:\n%w", len(errs), len(pods), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// execPods - Find the pods the exec resource runs in, waiting up to its
+// timeout for any to be running.
+func execPods(log *logrus.Entry, kubectl *kubeutil.Kubectl, namespace string, spec *ExecSpec) ([]string, error) {
+	selector := spec.Selector
+	if spec.AllPods && !strings.Contains(selector, "=") {
+		return nil, fmt.Errorf("exec in all pods needs a label selector, not %s", selector)
+	}
+
+	var timeout time.Duration
+	if spec.Timeout != "" {
+		var err error
+		if timeout, err = time.ParseDuration(spec.Timeout); err != nil {
+			return nil, fmt.Errorf("invalid exec timeout %s: %w", spec.Timeout, err)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, err := FindPods(kubectl, namespace, selector)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(pods) != 0 {
+			if !spec.AllPods {
+				return pods[:1], nil
+			}
+			return pods, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("no running pods found for %s in namespace %s", spec.Selector, namespace)
+		}
+
+		log.Debug("Waiting for pods of ", spec.Selector, " to be running...")
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// FindPods - Find the pods a target refers to; the running pods matching a
+// label selector, or managed by an object given as kind/name, or a single pod
+// given by name, whether it's running or not.
+func FindPods(kubectl *kubeutil.Kubectl, namespace, target string) ([]string, error) {
+	selector := target
+	if !strings.Contains(target, "=") {
+		kind, name, found := strings.Cut(target, "/")
+		if !found {
+			return []string{kind}, nil
+		} else if kind == "pod" || kind == "pods" || kind == "po" {
+			return []string{name}, nil
+		}
+
+		var err error
+		if selector, err = kubeutil.PodSelectorFor(kubectl, namespace, target); err != nil {
+			return nil, err
+		}
+	}
+
+	return kubeutil.GetRunningPodNames(kubectl, namespace, selector)
+}
+
+// checkExitCode - Turn the result of running a command into an error, if the
+// command didn't exit with the expected code.
+func checkExitCode(err error, expected int) error {
+	code := 0
+	if err != nil {
+		var exitErr utilexec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		code = exitErr.ExitStatus()
+	}
+
+	if code != expected {
+		return fmt.Errorf("command exited with code %d, expected %d", code, expected)
+	}
+
+	return nil
+}
+
+// logWriter - Writes each complete line written to it to a log.
+type logWriter struct {
+	log    func(args ...interface{})
+	buffer []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.buffer = append(w.buffer, p...)
+	for {
+		i := bytes.IndexByte(w.buffer, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+
+		w.log(strings.TrimRight(string(w.buffer[:i]), "\r"))
+		w.buffer = w.buffer[i+1:]
+	}
+}
+
+// Flush - Log anything written after the last complete line.
+func (w *logWriter) Flush() {
+	if len(w.buffer) != 0 {
+		w.log(string(w.buffer))
+		w.buffer = nil
+	}
+}
@@ -0,0 +1,255 @@
+package hope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	utilexec "k8s.io/client-go/util/exec"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+type ExecTestSuite struct {
+	suite.Suite
+
+	originalExecInPod kubeutil.ExecInPodFunc
+
+	lock  sync.Mutex
+	execs []string
+}
+
+func (s *ExecTestSuite) SetupTest() {
+	s.originalExecInPod = kubeutil.ExecInPod
+	s.execs = []string{}
+}
+
+func (s *ExecTestSuite) TearDownTest() {
+	kubeutil.ExecInPod = s.originalExecInPod
+}
+
+func TestExec(t *testing.T) {
+	suite.Run(t, new(ExecTestSuite))
+}
+
+func testExecPod(name, namespace, app string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: map[string]string{"app": app}},
+		Status:     corev1.PodStatus{Phase: phase},
+	}
+}
+
+func (s *ExecTestSuite) testKubectl() *kubeutil.Kubectl {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "mysql", Namespace: "database"},
+		Spec:       appsv1.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "mysql"}}},
+	}
+
+	return kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		deployment,
+		testExecPod("mysql-b", "database", "mysql", corev1.PodRunning),
+		testExecPod("mysql-a", "database", "mysql", corev1.PodRunning),
+		testExecPod("mysql-c", "database", "mysql", corev1.PodPending),
+		testExecPod("web-a", "default", "web", corev1.PodRunning),
+		testExecPod("web-b", "default", "web", corev1.PodRunning),
+	)})
+}
+
+// Record each exec, writing the given output, and exiting with the exit
+// code for the pod, if there is one.
+func (s *ExecTestSuite) mockExec(output string, exitCodes map[string]int) {
+	kubeutil.ExecInPod = func(ctx context.Context, kubectl *kubeutil.Kubectl, namespace, pod string, options kubeutil.ExecOptions) error {
+		stdin := ""
+		if options.Stdin != nil {
+			bytes, _ := io.ReadAll(options.Stdin)
+			stdin = string(bytes)
+		}
+
+		s.lock.Lock()
+		s.execs = append(s.execs, fmt.Sprintf("%s/%s[%s] %v tty=%t stdin=%q", namespace, pod, options.Container, options.Command, options.TTY, stdin))
+		s.lock.Unlock()
+
+		if options.Stdout != nil {
+			options.Stdout.Write([]byte(output))
+		}
+
+		if code, ok := exitCodes[pod]; ok {
+			return utilexec.CodeExitError{Err: errors.New("command terminated with non-zero exit code"), Code: code}
+		}
+
+		return nil
+	}
+}
+
+func (s *ExecTestSuite) TestExecResource() {
+	t := s.T()
+	s.mockExec("first line\nsecond line", nil)
+
+	logger, hook := test.NewNullLogger()
+	resource := &Resource{Name: "exec", Exec: ExecSpec{
+		Selector:  "deploy/mysql",
+		Namespace: "database",
+		Container: "mysql",
+		Command:   []string{"mysql", "-e", "select 1;"},
+	}}
+
+	assert.Nil(t, ExecResource(logrus.NewEntry(logger), s.testKubectl(), resource))
+	assert.Equal(t, []string{`database/mysql-a[mysql] [mysql -e select 1;] tty=false stdin=""`}, s.execs)
+
+	messages := []string{}
+	for _, entry := range hook.AllEntries() {
+		assert.Equal(t, "mysql-a", entry.Data["pod"])
+		messages = append(messages, entry.Message)
+	}
+	assert.Equal(t, []string{"Running mysql -e select 1;", "first line", "second line"}, messages)
+}
+
+func (s *ExecTestSuite) TestExecResourceAllPods() {
+	t := s.T()
+	s.mockExec("", nil)
+
+	stdinFile := filepath.Join(t.TempDir(), "script.sh")
+	assert.Nil(t, os.WriteFile(stdinFile, []byte("echo hi"), 0644))
+
+	resource := &Resource{Name: "exec", Exec: ExecSpec{
+		Selector:  "app=mysql",
+		Namespace: "database",
+		Command:   []string{"sh", "-s"},
+		AllPods:   true,
+		StdinFile: stdinFile,
+	}}
+
+	assert.Nil(t, ExecResource(logrus.NewEntry(logrus.New()), s.testKubectl(), resource))
+	assert.Equal(t, []string{
+		`database/mysql-a[] [sh -s] tty=false stdin="echo hi"`,
+		`database/mysql-b[] [sh -s] tty=false stdin="echo hi"`,
+	}, s.execs)
+
+	s.execs = []string{}
+	resource.Exec.Parallel = true
+	resource.Exec.Namespace = ""
+	resource.Exec.Selector = "app=web"
+	resource.Exec.StdinFile = ""
+	assert.Nil(t, ExecResource(logrus.NewEntry(logrus.New()), s.testKubectl(), resource))
+
+	sort.Strings(s.execs)
+	assert.Equal(t, []string{
+		`default/web-a[] [sh -s] tty=false stdin=""`,
+		`default/web-b[] [sh -s] tty=false stdin=""`,
+	}, s.execs)
+}
+
+func (s *ExecTestSuite) TestExecResourceExitCodes() {
+	t := s.T()
+	log := logrus.NewEntry(logrus.New())
+	resource := &Resource{Name: "exec", Exec: ExecSpec{Selector: "app=web", Command: []string{"false"}, AllPods: true}}
+
+	s.mockExec("", map[string]int{"web-a": 1})
+	err := ExecResource(log, s.testKubectl(), resource)
+	assert.EqualError(t, err, "exec failed in 1 of 2 pods:\nweb-a: command exited with code 1, expected 0")
+	assert.Equal(t, 1, len(s.execs))
+
+	s.execs = []string{}
+	resource.Exec.Parallel = true
+	s.mockExec("", map[string]int{"web-a": 1, "web-b": 2})
+	err = ExecResource(log, s.testKubectl(), resource)
+	assert.ErrorContains(t, err, "exec failed in 2 of 2 pods:\n")
+	assert.ErrorContains(t, err, "web-a: command exited with code 1, expected 0")
+	assert.ErrorContains(t, err, "web-b: command exited with code 2, expected 0")
+
+	resource.Exec.ExitCode = 1
+	resource.Exec.Selector = "pod/web-a"
+	resource.Exec.AllPods = false
+	assert.Nil(t, ExecResource(log, s.testKubectl(), resource))
+
+	resource.Exec.Selector = "web-b"
+	err = ExecResource(log, s.testKubectl(), resource)
+	assert.EqualError(t, err, "web-b: command exited with code 2, expected 1")
+
+	kubeutil.ExecInPod = func(ctx context.Context, kubectl *kubeutil.Kubectl, namespace, pod string, options kubeutil.ExecOptions) error {
+		return errors.New("container not found")
+	}
+	err = ExecResource(log, s.testKubectl(), resource)
+	assert.EqualError(t, err, "web-b: container not found")
+}
+
+func (s *ExecTestSuite) TestExecResourceErrors() {
+	t := s.T()
+	s.mockExec("", nil)
+	log := logrus.NewEntry(logrus.New())
+
+	resource := &Resource{Name: "exec", Exec: ExecSpec{Selector: "app=web", Command: []string{"sh"}, AllPods: true, TTY: true}}
+	assert.EqualError(t, ExecResource(log, s.testKubectl(), resource), "exec resource exec can't use a tty with all pods")
+
+	resource.Exec.TTY = false
+	resource.Exec.Selector = "deploy/web"
+	assert.EqualError(t, ExecResource(log, s.testKubectl(), resource), "exec in all pods needs a label selector, not deploy/web")
+
+	resource.Exec.AllPods = false
+	resource.Exec.Selector = "app=missing"
+	assert.EqualError(t, ExecResource(log, s.testKubectl(), resource), "no running pods found for app=missing in namespace default")
+
+	resource.Exec.Timeout = "soon"
+	assert.ErrorContains(t, ExecResource(log, s.testKubectl(), resource), "invalid exec timeout soon")
+
+	assert.Equal(t, []string{}, s.execs)
+}
+
+func (s *ExecTestSuite) TestFindPods() {
+	t := s.T()
+	kubectl := s.testKubectl()
+
+	var tests = []struct {
+		target    string
+		namespace string
+		pods      []string
+	}{
+		{"app=mysql", "database", []string{"mysql-a", "mysql-b"}},
+		{"deploy/mysql", "database", []string{"mysql-a", "mysql-b"}},
+		{"pod/mysql-c", "database", []string{"mysql-c"}},
+		{"mysql-c", "database", []string{"mysql-c"}},
+		{"app=mysql", "default", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			pods, err := FindPods(kubectl, tt.namespace, tt.target)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.pods, pods)
+		})
+	}
+
+	_, err := FindPods(kubectl, "default", "deploy/mysql")
+	assert.EqualError(t, err, `deployments.apps "mysql" not found`)
+}
+
+func TestLogWriter(t *testing.T) {
+	lines := []string{}
+	w := &logWriter{log: func(args ...interface{}) { lines = append(lines, fmt.Sprint(args...)) }}
+
+	w.Write([]byte("one\ntw"))
+	w.Write([]byte("o\r\nthree"))
+	assert.Equal(t, []string{"one", "two"}, lines)
+
+	w.Flush()
+	w.Flush()
+	assert.Equal(t, []string{"one", "two", "three"}, lines)
+}
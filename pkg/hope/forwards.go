@@ -0,0 +1,173 @@
+package hope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// ForwardRetryInterval - How long to wait before reconnecting a forward that
+// lost its connection, or couldn't find a pod to connect to.
+var ForwardRetryInterval = 2 * time.Second
+
+// ValidateForwards - Check that each forward targets exactly one of a
+// service or selector, and that no two forwards use the same local port.
+func ValidateForwards(forwards []Forward) error {
+	localPorts := map[int]string{}
+	for _, forward := range forwards {
+		if (forward.Service == "") == (forward.Selector == "") {
+			return fmt.Errorf("forward %s must have exactly one of a service or selector", forward.Name)
+		}
+
+		if forward.RemotePort <= 0 {
+			return fmt.Errorf("forward %s has no remote port", forward.Name)
+		}
+
+		localPort := forward.LocalPortOrDefault()
+		if other, ok := localPorts[localPort]; ok {
+			return fmt.Errorf("forwards %s and %s both use local port %d", other, forward.Name, localPort)
+		}
+		localPorts[localPort] = forward.Name
+	}
+
+	return nil
+}
+
+// LocalPortOrDefault - The local port of the forward, defaulting to its
+// remote port.
+func (forward *Forward) LocalPortOrDefault() int {
+	if forward.LocalPort == 0 {
+		return forward.RemotePort
+	}
+	return forward.LocalPort
+}
+
+// ForwardAll - Keep all the given forwards running concurrently until the
+// context is done.
+// Forwards are validated, and their local ports checked to be free, before
+// any of them are started.
+func ForwardAll(ctx context.Context, log *logrus.Entry, kubectl *kubeutil.Kubectl, forwards []Forward) error {
+	if err := ValidateForwards(forwards); err != nil {
+		return err
+	}
+
+	for _, forward := range forwards {
+		localPort := forward.LocalPortOrDefault()
+		listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", localPort))
+		if err != nil {
+			return fmt.Errorf("local port %d of forward %s is unavailable: %w", localPort, forward.Name, err)
+		}
+		listener.Close()
+	}
+
+	var wg sync.WaitGroup
+	for _, forward := range forwards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			KeepForwarding(ctx, log.WithField("forward", forward.Name), kubectl, &forward)
+		}()
+	}
+	wg.Wait()
+
+	return nil
+}
+
+// KeepForwarding - Forward the forward's local port to one of its pods until
+// the context is done, reconnecting, possibly to another pod, whenever the
+// connection to the pod is lost.
+func KeepForwarding(ctx context.Context, log *logrus.Entry, kubectl *kubeutil.Kubectl, forward *Forward) {
+	for {
+		err := forwardOnce(ctx, log, kubectl, forward)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err == nil {
+			err = errors.New("connection closed")
+		}
+		log.Warn(err, "; reconnecting in ", ForwardRetryInterval)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ForwardRetryInterval):
+		}
+	}
+}
+
+// forwardOnce - Forward the forward's local port to one of its pods, until
+// the context is done, or the connection to the pod is lost.
+func forwardOnce(ctx context.Context, log *logrus.Entry, kubectl *kubeutil.Kubectl, forward *Forward) error {
+	namespace := forward.Namespace
+	if namespace == "" {
+		namespace = "default"
+	}
+
+	pod, remotePort, err := forwardTarget(kubectl, namespace, forward)
+	if err != nil {
+		return err
+	}
+
+	localPort := forward.LocalPortOrDefault()
+	podLog := log.WithField("pod", pod)
+	errOut := &logWriter{log: podLog.Warn}
+	defer errOut.Flush()
+
+	ready := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ready:
+			podLog.Infof("Forwarding localhost:%d to %s/%s:%d", localPort, namespace, pod, remotePort)
+		case <-done:
+		}
+	}()
+
+	return kubeutil.PortForward(ctx, kubectl, namespace, pod, localPort, remotePort, ready, errOut)
+}
+
+// forwardTarget - Find the pod a forward connects to, and the port of the pod
+// to connect to.
+func forwardTarget(kubectl *kubeutil.Kubectl, namespace string, forward *Forward) (string, int, error) {
+	selector := forward.Selector
+	if forward.Service != "" {
+		var err error
+		if selector, err = kubeutil.PodSelectorFor(kubectl, namespace, "svc/"+forward.Service); err != nil {
+			return "", 0, err
+		}
+	}
+
+	pods, err := kubeutil.GetRunningPodNames(kubectl, namespace, selector)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if len(pods) == 0 {
+		return "", 0, fmt.Errorf("no running pods found for %s in namespace %s", selector, namespace)
+	}
+
+	pod := pods[0]
+	if forward.Service == "" {
+		return pod, forward.RemotePort, nil
+	}
+
+	remotePort, err := kubeutil.ServiceTargetPort(kubectl, namespace, forward.Service, forward.RemotePort, pod)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return pod, remotePort, nil
+}
@@ -0,0 +1,200 @@
+package hope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+type ForwardsTestSuite struct {
+	suite.Suite
+
+	originalPortForward   kubeutil.PortForwardFunc
+	originalRetryInterval time.Duration
+
+	lock     sync.Mutex
+	forwards []string
+}
+
+func (s *ForwardsTestSuite) SetupTest() {
+	s.originalPortForward = kubeutil.PortForward
+	s.originalRetryInterval = ForwardRetryInterval
+	ForwardRetryInterval = time.Millisecond
+	s.forwards = []string{}
+}
+
+func (s *ForwardsTestSuite) TearDownTest() {
+	kubeutil.PortForward = s.originalPortForward
+	ForwardRetryInterval = s.originalRetryInterval
+}
+
+func TestForwards(t *testing.T) {
+	suite.Run(t, new(ForwardsTestSuite))
+}
+
+func (s *ForwardsTestSuite) testKubectl() *kubeutil.Kubectl {
+	grafana := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "grafana", Namespace: "monitoring"},
+		Spec: corev1.ServiceSpec{
+			Selector: map[string]string{"app": "grafana"},
+			Ports: []corev1.ServicePort{
+				{Port: 80, TargetPort: intstr.FromString("http")},
+				{Port: 9090, TargetPort: intstr.FromInt32(9091)},
+				{Port: 8080},
+			},
+		},
+	}
+
+	grafanaPod := testExecPod("grafana-a", "monitoring", "grafana", corev1.PodRunning)
+	grafanaPod.Spec.Containers = []corev1.Container{
+		{Name: "grafana", Ports: []corev1.ContainerPort{{Name: "http", ContainerPort: 3000}}},
+	}
+
+	return kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		grafana,
+		grafanaPod,
+		testExecPod("mysql-b", "default", "mysql", corev1.PodRunning),
+		testExecPod("mysql-a", "default", "mysql", corev1.PodPending),
+	)})
+}
+
+// Record each forward, returning the given errors in turn, and blocking
+// until the context is done once they've all been returned.
+func (s *ForwardsTestSuite) mockPortForward(errs ...error) {
+	kubeutil.PortForward = func(ctx context.Context, kubectl *kubeutil.Kubectl, namespace, pod string, localPort, remotePort int, ready chan struct{}, errOut io.Writer) error {
+		s.lock.Lock()
+		s.forwards = append(s.forwards, fmt.Sprintf("%d -> %s/%s:%d", localPort, namespace, pod, remotePort))
+		s.lock.Unlock()
+
+		close(ready)
+		if len(errs) != 0 {
+			err := errs[0]
+			errs = errs[1:]
+			return err
+		}
+
+		<-ctx.Done()
+		return nil
+	}
+}
+
+func (s *ForwardsTestSuite) TestForwardTarget() {
+	t := s.T()
+	kubectl := s.testKubectl()
+
+	var tests = []struct {
+		name    string
+		forward Forward
+		pod     string
+		port    int
+	}{
+		{"Named Target Port", Forward{Namespace: "monitoring", Service: "grafana", RemotePort: 80}, "grafana-a", 3000},
+		{"Numbered Target Port", Forward{Namespace: "monitoring", Service: "grafana", RemotePort: 9090}, "grafana-a", 9091},
+		{"No Target Port", Forward{Namespace: "monitoring", Service: "grafana", RemotePort: 8080}, "grafana-a", 8080},
+		{"Selector", Forward{Namespace: "default", Selector: "app=mysql", RemotePort: 3306}, "mysql-b", 3306},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pod, port, err := forwardTarget(kubectl, tt.forward.Namespace, &tt.forward)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.pod, pod)
+			assert.Equal(t, tt.port, port)
+		})
+	}
+
+	_, _, err := forwardTarget(kubectl, "monitoring", &Forward{Service: "grafana", RemotePort: 443})
+	assert.Equal(t, "service grafana has no port 443", err.Error())
+
+	_, _, err = forwardTarget(kubectl, "default", &Forward{Selector: "app=redis", RemotePort: 6379})
+	assert.Equal(t, "no running pods found for app=redis in namespace default", err.Error())
+}
+
+func (s *ForwardsTestSuite) TestKeepForwardingReconnects() {
+	t := s.T()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.mockPortForward(errors.New("lost connection to pod"), nil)
+	forward := &Forward{Name: "mysql", Selector: "app=mysql", LocalPort: 13306, RemotePort: 3306}
+
+	done := make(chan struct{})
+	go func() {
+		KeepForwarding(ctx, logrus.NewEntry(logrus.New()), s.testKubectl(), forward)
+		close(done)
+	}()
+
+	assert.Eventually(t, func() bool {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return len(s.forwards) == 3
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	<-done
+
+	assert.Equal(t, []string{
+		"13306 -> default/mysql-b:3306",
+		"13306 -> default/mysql-b:3306",
+		"13306 -> default/mysql-b:3306",
+	}, s.forwards)
+}
+
+func (s *ForwardsTestSuite) TestForwardAllLocalPortInUse() {
+	t := s.T()
+	s.mockPortForward()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	forwards := []Forward{{Name: "mysql", Selector: "app=mysql", LocalPort: port, RemotePort: 3306}}
+	err = ForwardAll(context.Background(), logrus.NewEntry(logrus.New()), s.testKubectl(), forwards)
+	assert.ErrorContains(t, err, fmt.Sprintf("local port %d of forward mysql is unavailable", port))
+	assert.Equal(t, []string{}, s.forwards)
+}
+
+func TestValidateForwards(t *testing.T) {
+	var tests = []struct {
+		name     string
+		forwards []Forward
+		err      string
+	}{
+		{"Valid", []Forward{{Name: "a", Service: "a", RemotePort: 80, LocalPort: 8080}, {Name: "b", Selector: "app=b", RemotePort: 80}}, ""},
+		{"Neither", []Forward{{Name: "a", RemotePort: 80}}, "forward a must have exactly one of a service or selector"},
+		{"Both", []Forward{{Name: "a", Service: "a", Selector: "app=a", RemotePort: 80}}, "forward a must have exactly one of a service or selector"},
+		{"No Port", []Forward{{Name: "a", Service: "a"}}, "forward a has no remote port"},
+		{"Same Local Port", []Forward{{Name: "a", Service: "a", RemotePort: 80}, {Name: "b", Service: "b", RemotePort: 8080, LocalPort: 80}}, "forwards a and b both use local port 80"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateForwards(tt.forwards)
+			if tt.err == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.Equal(t, tt.err, err.Error())
+			}
+		})
+	}
+}
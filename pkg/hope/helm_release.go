@@ -0,0 +1,157 @@
+package hope
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+)
+
+// IsOCIChart - Whether the chart is pulled from an OCI registry.
+func (spec *HelmSpec) IsOCIChart() bool {
+	return strings.HasPrefix(spec.Chart, "oci://")
+}
+
+// IsRepoChart - Whether the chart comes from a chart repo that has to be
+// added to helm before it can be installed.
+// Charts that are neither repo charts nor OCI charts are local directories.
+func (spec *HelmSpec) IsRepoChart() bool {
+	return len(spec.Repo) != 0 || len(spec.Path) != 0
+}
+
+// DeployHelmRelease - Install or upgrade the resource's helm release,
+// making sure the chart's repo is available and up to date first.
+func DeployHelmRelease(log *logrus.Entry, resource *Resource, parameters []string) error {
+	spec := resource.Helm
+	if spec.IsRepoChart() {
+		if len(spec.Repo) == 0 || len(spec.Path) == 0 {
+			return fmt.Errorf("helm resource '%s' must provide both a repo and a path", resource.Name)
+		}
+
+		if spec.IsOCIChart() {
+			return fmt.Errorf("helm resource '%s' can't use a repo with an oci chart", resource.Name)
+		}
+
+		if err := prepareHelmRepo(log, &spec); err != nil {
+			return err
+		}
+	}
+
+	allArgs, tempFiles, err := HelmUpgradeArgs(&spec, parameters)
+	for _, tempFile := range tempFiles {
+		defer os.Remove(tempFile)
+	}
+	if err != nil {
+		return err
+	}
+
+	return helm.ExecHelm(allArgs...)
+}
+
+// prepareHelmRepo - Add the chart's repo if it hasn't been yet, and update
+// it unless the requested version of the chart is already known.
+// Newly added repos already have a fresh index.
+func prepareHelmRepo(log *logrus.Entry, spec *HelmSpec) error {
+	hasRepo, err := helm.HasRepo(spec.Repo, spec.Path)
+	if err != nil {
+		return err
+	}
+
+	if !hasRepo {
+		return helm.ExecHelm("repo", "add", spec.Repo, spec.Path)
+	}
+
+	if len(spec.Version) != 0 {
+		hasVersion, err := helm.HasChartVersion(spec.Chart, spec.Version)
+		if err != nil {
+			return err
+		}
+
+		if hasVersion {
+			log.Debug("Chart ", spec.Chart, " version ", spec.Version, " already cached; skipping repo update.")
+			return nil
+		}
+	}
+
+	return helm.ExecHelm("repo", "update", spec.Repo)
+}
+
+// HelmUpgradeArgs - Build the helm arguments that install or upgrade the
+// release.
+// Values files and inline values have parameters substituted into temporary
+// copies, which are returned so that the caller can remove them, even if an
+// error occurs.
+// Values are given to helm in the order: values file, values files, inline
+// values, then set overrides; later values take precedence.
+func HelmUpgradeArgs(spec *HelmSpec, parameters []string) ([]string, []string, error) {
+	tempFiles := []string{}
+	allArgs := []string{"upgrade", "--install"}
+	if len(spec.Namespace) != 0 {
+		allArgs = append(allArgs, "--namespace", spec.Namespace, "--create-namespace")
+	}
+
+	valuesFiles := spec.ValuesFiles
+	if len(spec.ValuesFile) != 0 {
+		valuesFiles = append([]string{spec.ValuesFile}, valuesFiles...)
+	}
+
+	for _, valuesFile := range valuesFiles {
+		tempFile, err := ReplaceParametersInFileCopy(valuesFile, parameters)
+		if err != nil {
+			return nil, tempFiles, err
+		}
+
+		tempFiles = append(tempFiles, tempFile)
+		allArgs = append(allArgs, "--values", tempFile)
+	}
+
+	if len(spec.Values) != 0 {
+		tempFile, err := ReplaceParametersInStringCopy(spec.Values, parameters)
+		if err != nil {
+			return nil, tempFiles, err
+		}
+
+		tempFiles = append(tempFiles, tempFile)
+		allArgs = append(allArgs, "--values", tempFile)
+	}
+
+	for _, set := range spec.Set {
+		value, err := ReplaceParametersInString(set, parameters)
+		if err != nil {
+			return nil, tempFiles, err
+		}
+
+		allArgs = append(allArgs, "--set", value)
+	}
+
+	if len(spec.Version) != 0 {
+		allArgs = append(allArgs, "--version", spec.Version)
+	}
+
+	if spec.Atomic {
+		allArgs = append(allArgs, "--atomic")
+	}
+
+	if spec.Wait {
+		allArgs = append(allArgs, "--wait")
+	}
+
+	if len(spec.Timeout) != 0 {
+		if _, err := time.ParseDuration(spec.Timeout); err != nil {
+			return nil, tempFiles, fmt.Errorf("invalid helm timeout %s: %w", spec.Timeout, err)
+		}
+
+		allArgs = append(allArgs, "--timeout", spec.Timeout)
+	}
+
+	allArgs = append(allArgs, spec.Release, spec.Chart)
+	return allArgs, tempFiles, nil
+}
@@ -0,0 +1,202 @@
+package hope
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+)
+
+// Implemented as a suite to allow manipulating the helm wrapper funcs.
+type HelmReleaseTestSuite struct {
+	suite.Suite
+
+	originalExecHelm helm.ExecHelmFunc
+	originalGetHelm  helm.GetHelmFunc
+
+	commands []string
+}
+
+func (s *HelmReleaseTestSuite) SetupTest() {
+	s.originalExecHelm = helm.ExecHelm
+	s.originalGetHelm = helm.GetHelm
+
+	s.commands = []string{}
+	helm.ExecHelm = func(args ...string) error {
+		s.commands = append(s.commands, strings.Join(args, " "))
+		return nil
+	}
+}
+
+func (s *HelmReleaseTestSuite) TearDownTest() {
+	helm.ExecHelm = s.originalExecHelm
+	helm.GetHelm = s.originalGetHelm
+}
+
+// Actual test method to run the suite
+func TestHelmRelease(t *testing.T) {
+	suite.Run(t, new(HelmReleaseTestSuite))
+}
+
+func (s *HelmReleaseTestSuite) mockHelmCache(repos, searchResults string) {
+	helm.GetHelm = func(args ...string) (string, error) {
+		switch args[0] {
+		case "repo":
+			return repos, nil
+		case "search":
+			return searchResults, nil
+		}
+
+		s.T().Fatalf("unexpected helm invocation: %s", strings.Join(args, " "))
+		return "", nil
+	}
+}
+
+func (s *HelmReleaseTestSuite) TestHelmUpgradeArgs() {
+	t := s.T()
+
+	valuesFile := filepath.Join(t.TempDir(), "values.yaml")
+	assert.NoError(t, os.WriteFile(valuesFile, []byte("host: ${HOST}\n"), 0644))
+
+	spec := HelmSpec{
+		Namespace:   "web",
+		Release:     "web",
+		Chart:       "oci://registry.example.com/charts/web",
+		Version:     "1.2.3",
+		ValuesFiles: []string{valuesFile},
+		Values:      "replicaCount: 2\nimage:\n  tag: ${TAG}\n",
+		Set:         []string{"image.tag=${TAG}", "debug=true"},
+		Atomic:      true,
+		Wait:        true,
+		Timeout:     "10m",
+	}
+
+	allArgs, tempFiles, err := HelmUpgradeArgs(&spec, []string{"HOST=example.com", "TAG=v1"})
+	defer func() {
+		for _, tempFile := range tempFiles {
+			os.Remove(tempFile)
+		}
+	}()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tempFiles))
+
+	assert.Equal(t, []string{
+		"upgrade", "--install",
+		"--namespace", "web", "--create-namespace",
+		"--values", tempFiles[0],
+		"--values", tempFiles[1],
+		"--set", "image.tag=v1",
+		"--set", "debug=true",
+		"--version", "1.2.3",
+		"--atomic",
+		"--wait",
+		"--timeout", "10m",
+		"web", "oci://registry.example.com/charts/web",
+	}, allArgs)
+
+	contents, err := os.ReadFile(tempFiles[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "host: example.com\n", string(contents))
+
+	contents, err = os.ReadFile(tempFiles[1])
+	assert.NoError(t, err)
+	assert.Equal(t, "replicaCount: 2\nimage:\n  tag: v1\n", string(contents))
+
+	spec = HelmSpec{Release: "web", Chart: "./charts/web", Timeout: "ten minutes"}
+	_, _, err = HelmUpgradeArgs(&spec, []string{})
+	assert.Equal(t, "invalid helm timeout ten minutes: time: invalid duration \"ten minutes\"", err.Error())
+}
+
+func (s *HelmReleaseTestSuite) TestDeployHelmReleaseSkipsCachedVersion() {
+	t := s.T()
+
+	s.mockHelmCache("NAME\tURL\ndashboard\thttps://kubernetes.github.io/dashboard/\n", `[{"name":"dashboard/dashboard","version":"7.11.1"}]`)
+
+	resource := Resource{Name: "dashboard", Helm: HelmSpec{
+		Release: "dashboard",
+		Repo:    "dashboard",
+		Path:    "https://kubernetes.github.io/dashboard/",
+		Chart:   "dashboard/dashboard",
+		Version: "7.11.1",
+	}}
+
+	err := DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"upgrade --install --version 7.11.1 dashboard dashboard/dashboard"}, s.commands)
+}
+
+func (s *HelmReleaseTestSuite) TestDeployHelmReleaseUpdatesRepo() {
+	t := s.T()
+
+	s.mockHelmCache("NAME\tURL\ndashboard\thttps://kubernetes.github.io/dashboard/\n", "[]")
+
+	resource := Resource{Name: "dashboard", Helm: HelmSpec{
+		Release: "dashboard",
+		Repo:    "dashboard",
+		Path:    "https://kubernetes.github.io/dashboard/",
+		Chart:   "dashboard/dashboard",
+		Version: "7.11.2",
+	}}
+
+	err := DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"repo update dashboard",
+		"upgrade --install --version 7.11.2 dashboard dashboard/dashboard",
+	}, s.commands)
+}
+
+func (s *HelmReleaseTestSuite) TestDeployHelmReleaseAddsRepo() {
+	t := s.T()
+
+	s.mockHelmCache("NAME\tURL\nother\thttps://example.com/charts\n", "[]")
+
+	resource := Resource{Name: "dashboard", Helm: HelmSpec{
+		Release: "dashboard",
+		Repo:    "dashboard",
+		Path:    "https://kubernetes.github.io/dashboard/",
+		Chart:   "dashboard/dashboard",
+	}}
+
+	err := DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"repo add dashboard https://kubernetes.github.io/dashboard/",
+		"upgrade --install dashboard dashboard/dashboard",
+	}, s.commands)
+}
+
+func (s *HelmReleaseTestSuite) TestDeployHelmReleaseWithoutRepo() {
+	t := s.T()
+
+	s.mockHelmCache("", "")
+
+	resource := Resource{Name: "web", Helm: HelmSpec{Release: "web", Chart: "oci://registry.example.com/charts/web"}}
+	assert.NoError(t, DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{}))
+
+	resource = Resource{Name: "local", Helm: HelmSpec{Release: "local", Chart: "charts/local"}}
+	assert.NoError(t, DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{}))
+
+	assert.Equal(t, []string{
+		"upgrade --install web oci://registry.example.com/charts/web",
+		"upgrade --install local charts/local",
+	}, s.commands)
+
+	resource = Resource{Name: "web", Helm: HelmSpec{Release: "web", Repo: "charts", Chart: "charts/web"}}
+	err := DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.Equal(t, "helm resource 'web' must provide both a repo and a path", err.Error())
+
+	resource = Resource{Name: "web", Helm: HelmSpec{Release: "web", Repo: "charts", Path: "https://example.com", Chart: "oci://example.com/web"}}
+	err = DeployHelmRelease(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.Equal(t, "helm resource 'web' can't use a repo with an oci chart", err.Error())
+}
@@ -347,6 +347,28 @@ func (hyp *EsxiHypervisor) VMIPAddress(name string) (string, error) {
 	return ip, nil
 }
 
+func (hyp *EsxiHypervisor) VMPowerState(name string) (string, error) {
+	powerState, err := esxi.PowerStateOfVmNamed(hyp.node.ConnectionString(), name)
+	if errors.Is(err, esxi.ErrVmNotFound) {
+		return "", fmt.Errorf("%w: %w", ErrVMNotFound, err)
+	} else if err != nil {
+		return "", err
+	}
+
+	switch powerState {
+	case esxi.VmStatePoweredOn:
+		return VMPowerStateOn, nil
+	case esxi.VmStatePoweredOff:
+		return VMPowerStateOff, nil
+	}
+
+	return "", fmt.Errorf("unknown power state: %s", powerState)
+}
+
+func (hyp *EsxiHypervisor) DatastoreUsage() (uint64, uint64, error) {
+	return esxi.DatastoreUsage(hyp.node.ConnectionString(), hyp.node.Datastore)
+}
+
 func (hyp *EsxiHypervisor) StartVM(name string) error {
 	return esxi.PowerOnVmNamed(hyp.node.ConnectionString(), name)
 
@@ -1,6 +1,7 @@
 package hypervisors
 
 import (
+	"errors"
 	"testing"
 )
 
@@ -154,3 +155,28 @@ func (s *EsxiHypervisorTestSuite) TestCopyImage() {
 	assert.Equal(t, 1, scpExecutions)
 	assert.Equal(t, 1, sshExecutions)
 }
+
+func (s *EsxiHypervisorTestSuite) TestVMPowerStateNotFound() {
+	t := s.T()
+
+	oldGetSSH := ssh.GetSSH
+	defer func() { ssh.GetSSH = oldGetSSH }()
+
+	ssh.GetSSH = func(args ...string) (string, error) {
+		assert.Equal(t, []string{"root@192.168.10.40", "vim-cmd", "vmsvc/getallvms"}, args)
+		return "Vmid Name File Guest_OS Version Annotation\n1 other [Main] other/other.vmx ubuntu64Guest vmx-13\n", nil
+	}
+
+	esxi, err := ToHypervisor(s.hypervisorNode)
+	assert.NoError(t, err)
+
+	_, err = esxi.VMPowerState("missing")
+	assert.ErrorIs(t, err, ErrVMNotFound)
+
+	ssh.GetSSH = func(args ...string) (string, error) {
+		return "", errors.New("exit status 255")
+	}
+
+	_, err = esxi.VMPowerState("missing")
+	assert.NotErrorIs(t, err, ErrVMNotFound)
+}
@@ -12,6 +12,17 @@ import (
 
 var ErrCopyImageNotImplemented error = errors.New("CopyImage not implemented for this hypervisor")
 
+// ErrVMNotFound - Returned when asking a hypervisor about a VM it doesn't
+// have.
+var ErrVMNotFound error = errors.New("VM not found")
+
+// Normalized power states that hypervisors report for their VMs, so callers
+// don't need to know how each engine describes them.
+const (
+	VMPowerStateOn  string = "on"
+	VMPowerStateOff string = "off"
+)
+
 type CopyImageMode int
 
 const (
@@ -81,6 +92,12 @@ type Hypervisor interface {
 
 	// Get the IP address of the VM identified by the given value.
 	VMIPAddress(string) (string, error)
+
+	// Get the power state of the VM identified by the given value.
+	VMPowerState(string) (string, error)
+
+	// Get the total and free bytes of the storage VMs are created on.
+	DatastoreUsage() (uint64, uint64, error)
 }
 
 type EngineBuildPlan struct {
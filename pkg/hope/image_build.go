@@ -0,0 +1,565 @@
+package hope
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/buildkit"
+	"github.com/Eagerod/hope/pkg/docker"
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+const (
+	// ImageBuilderDocker - Build through the Docker Engine API socket.
+	ImageBuilderDocker = "docker"
+	// ImageBuilderBuildkit - Build with buildctl against a running buildkitd.
+	ImageBuilderBuildkit = "buildkit"
+	// ImageBuilderRootless - Build with buildctl, starting a rootless
+	// buildkitd just for the build.
+	ImageBuilderRootless = "rootless"
+)
+
+// Prefix of the parameters that pin the digests of built images.
+const imageDigestParameterPrefix = "IMAGE_DIGEST_"
+
+// ImageDigestCachePath - File the digests of pushed images are recorded in,
+// along with the inputs they were built from.
+// When empty, a file inside the user's cache directory is used.
+var ImageDigestCachePath string = ""
+
+type imageDigestRecord struct {
+	Inputs string `json:"inputs"`
+	Digest string `json:"digest"`
+}
+
+const (
+	PullConstraintAlways       = "always"
+	PullConstraintIfNotPresent = "if-not-present"
+)
+
+// ImageBuildRequest - Everything a builder needs to produce an image and
+// push it to its registry.
+// Requests either build a context, or copy a source image to a new tag.
+type ImageBuildRequest struct {
+	Context    string
+	Dockerfile string
+	Target     string
+	Source     string
+	Tag        string
+	PullAlways bool
+	BuildArgs  map[string]string
+	Platforms  []string
+	Secrets    []string
+	Cache      string
+}
+
+// ImageBuilder - Something that can build images, and push them to their
+// registry.
+type ImageBuilder interface {
+	BuildAndPush(log *logrus.Entry, request *ImageBuildRequest) error
+}
+
+type dockerEngineBuilder struct{}
+
+type buildkitBuilder struct {
+	buildctl buildkit.ExecBuildctlFunc
+}
+
+// NewImageBuilder - Get the builder with the given name, defaulting to the
+// Docker Engine.
+func NewImageBuilder(name string) (ImageBuilder, error) {
+	switch name {
+	case "", ImageBuilderDocker:
+		return &dockerEngineBuilder{}, nil
+	case ImageBuilderBuildkit:
+		return &buildkitBuilder{buildkit.ExecBuildctl}, nil
+	case ImageBuilderRootless:
+		return &buildkitBuilder{buildkit.ExecBuildctlDaemonless}, nil
+	default:
+		return nil, fmt.Errorf("unknown image builder: %s", name)
+	}
+}
+
+// NewImageBuildRequest - Create the build request described by a docker
+// resource.
+// The resource's parameters are all passed to the build as build args;
+// parameters given by name alone take their values from the environment.
+func NewImageBuildRequest(resource *Resource, parameters []string) (*ImageBuildRequest, error) {
+	spec := resource.Build
+	if len(spec.Source) != 0 && len(spec.Path) != 0 {
+		return nil, fmt.Errorf("docker build step %s cannot have a path and a source", resource.Name)
+	}
+
+	if len(spec.Source) == 0 && len(spec.Path) == 0 {
+		return nil, fmt.Errorf("docker build step %s must have a path or a source", resource.Name)
+	}
+
+	if spec.Pull != "" && spec.Pull != PullConstraintAlways && spec.Pull != PullConstraintIfNotPresent {
+		return nil, fmt.Errorf("unknown Docker image pull constraint: %s", spec.Pull)
+	}
+
+	buildArgs := map[string]string{}
+	for _, parameter := range parameters {
+		key, value, found := strings.Cut(parameter, "=")
+		if !found {
+			if value, found = os.LookupEnv(key); !found {
+				return nil, fmt.Errorf("build arg %s of %s isn't set in the environment", key, resource.Name)
+			}
+		}
+
+		buildArgs[key] = value
+	}
+
+	return &ImageBuildRequest{
+		Context:    spec.Path,
+		Dockerfile: spec.Dockerfile,
+		Target:     spec.Target,
+		Source:     spec.Source,
+		Tag:        spec.Tag,
+		PullAlways: spec.Pull == PullConstraintAlways,
+		BuildArgs:  buildArgs,
+		Platforms:  spec.Platforms,
+		Secrets:    spec.Secrets,
+		Cache:      spec.Cache,
+	}, nil
+}
+
+// BuildImage - Build the docker resource's image with its builder, and push
+// it, returning the digest the registry has for it.
+// Unless the resource always pulls, images whose registry digest matches the
+// one recorded when they were last pushed from the same inputs aren't built
+// or pushed again.
+func BuildImage(log *logrus.Entry, resource *Resource, parameters []string) (string, error) {
+	request, err := NewImageBuildRequest(resource, parameters)
+	if err != nil {
+		return "", err
+	}
+
+	builder, err := NewImageBuilder(resource.Build.Builder)
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	client := registry.NewClient()
+
+	sourceDigest := ""
+	if len(request.Source) != 0 {
+		digest, exists, err := client.ManifestDigest(ctx, request.Source)
+		if err != nil {
+			return "", err
+		}
+
+		if !exists {
+			return "", fmt.Errorf("failed to find image named %s", request.Source)
+		}
+
+		sourceDigest = digest
+	}
+
+	inputs, err := imageBuildInputs(resource.Build.Builder, request, sourceDigest)
+	if err != nil {
+		return "", err
+	}
+
+	records, err := readImageDigestRecords()
+	if err != nil {
+		return "", err
+	}
+
+	if !request.PullAlways {
+		digest, exists, err := client.ManifestDigest(ctx, request.Tag)
+		if err != nil {
+			return "", err
+		}
+
+		if record, ok := records[request.Tag]; exists && ok && record.Digest == digest && record.Inputs == inputs {
+			log.Info(request.Tag, " is up to date (", digest, "); skipping build")
+			return digest, nil
+		}
+	}
+
+	if err := builder.BuildAndPush(log, request); err != nil {
+		return "", err
+	}
+
+	digest, exists, err := client.ManifestDigest(ctx, request.Tag)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		return "", fmt.Errorf("%s not found in registry after pushing it", request.Tag)
+	}
+
+	log.Info(request.Tag, " pushed (", digest, ")")
+
+	records[request.Tag] = imageDigestRecord{inputs, digest}
+	return digest, writeImageDigestRecords(records)
+}
+
+// ImageDigestParameter - The name of the parameter later resources can use
+// to pin the digest of the image built by the named resource, e.g.
+// build-some-image becomes IMAGE_DIGEST_BUILD_SOME_IMAGE.
+func ImageDigestParameter(resourceName string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, resourceName)
+
+	return fmt.Sprintf("%s%s", imageDigestParameterPrefix, name)
+}
+
+// PinImageDigests - Find the digest of the image each image digest parameter
+// without a value refers to, returning them as NAME=digest pairs.
+// Parameters with a value set in the hope file, or in the environment, are
+// left alone.
+// Digests of images that weren't built during this deploy are looked up in
+// the registry, using the tag of the resource that builds them.
+func PinImageDigests(resources []Resource, parameters []Parameter, digests map[string]string) ([]string, error) {
+	pinned := []string{}
+	for _, parameter := range parameters {
+		name := parameter.Name
+		if parameter.Value != nil || !strings.HasPrefix(name, imageDigestParameterPrefix) {
+			continue
+		}
+
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+
+		digest, ok := digests[name]
+		if !ok {
+			for _, resource := range resources {
+				if resource.Build.Tag == "" || ImageDigestParameter(resource.Name) != name {
+					continue
+				}
+
+				found, exists, err := registry.NewClient().ManifestDigest(context.Background(), resource.Build.Tag)
+				if err != nil {
+					return nil, err
+				}
+
+				if !exists {
+					return nil, fmt.Errorf("cannot pin %s; %s not found in registry", name, resource.Build.Tag)
+				}
+
+				digest = found
+				digests[name] = digest
+				break
+			}
+		}
+
+		if digest != "" {
+			pinned = append(pinned, fmt.Sprintf("%s=%s", name, digest))
+		}
+	}
+
+	return pinned, nil
+}
+
+// imageBuildInputs - A digest of everything that goes into a build, so that
+// changes to any of it cause the image to be built again.
+// Secrets are identified by their ids only; their contents never make it to
+// the image.
+func imageBuildInputs(builder string, request *ImageBuildRequest, sourceDigest string) (string, error) {
+	hash := sha256.New()
+
+	buildArgKeys := []string{}
+	for key := range request.BuildArgs {
+		buildArgKeys = append(buildArgKeys, key)
+	}
+	sort.Strings(buildArgKeys)
+
+	fmt.Fprintf(hash, "builder=%s\ndockerfile=%s\ntarget=%s\nsource=%s@%s\n", builder, request.Dockerfile, request.Target, request.Source, sourceDigest)
+	for _, key := range buildArgKeys {
+		fmt.Fprintf(hash, "arg=%s=%s\n", key, request.BuildArgs[key])
+	}
+	for _, secret := range request.Secrets {
+		id, _, _ := strings.Cut(secret, ",")
+		fmt.Fprintf(hash, "secret=%s\n", id)
+	}
+	fmt.Fprintf(hash, "platforms=%s\ncache=%s\n", strings.Join(request.Platforms, ","), request.Cache)
+
+	if len(request.Context) != 0 {
+		// Only what gets sent to the builder can change the image.
+		err := docker.WalkBuildContext(request.Context, func(path, relPath string, entry fs.DirEntry) error {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(hash, "file=%s %s\n", relPath, info.Mode())
+			if !info.Mode().IsRegular() {
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+
+			_, err = io.Copy(hash, file)
+			return err
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+func imageDigestCachePath() (string, error) {
+	if ImageDigestCachePath != "" {
+		return ImageDigestCachePath, nil
+	}
+
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, "hope", "image-digests.json"), nil
+}
+
+func readImageDigestRecords() (map[string]imageDigestRecord, error) {
+	records := map[string]imageDigestRecord{}
+
+	cachePath, err := imageDigestCachePath()
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return records, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(contents, &records); err != nil {
+		return nil, fmt.Errorf("failed to parse image digest records at %s: %w", cachePath, err)
+	}
+
+	return records, nil
+}
+
+func writeImageDigestRecords(records map[string]imageDigestRecord) error {
+	cachePath, err := imageDigestCachePath()
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(cachePath), ".image-digests")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(contents); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), cachePath)
+}
+
+func (b *dockerEngineBuilder) BuildAndPush(log *logrus.Entry, request *ImageBuildRequest) error {
+	if len(request.Secrets) != 0 {
+		return fmt.Errorf("the %s builder doesn't support build secrets; use the %s or %s builder", ImageBuilderDocker, ImageBuilderBuildkit, ImageBuilderRootless)
+	}
+
+	if len(request.Platforms) > 1 {
+		return fmt.Errorf("the %s builder can't build multiple platforms; use the %s or %s builder", ImageBuilderDocker, ImageBuilderBuildkit, ImageBuilderRootless)
+	}
+
+	client, err := docker.NewEngineClient("")
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if len(request.Source) != 0 {
+		shouldPull := request.PullAlways
+		if !shouldPull {
+			exists, err := client.ImageExists(ctx, request.Source)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				log.Debugf("Docker image matching %s found, skipping upstream pull", request.Source)
+			} else {
+				log.Infof("Docker image %s not found locally, must pull from upstream", request.Source)
+				shouldPull = true
+			}
+		}
+
+		if shouldPull {
+			if err := client.Pull(ctx, request.Source, os.Stdout); err != nil {
+				return fmt.Errorf("failed to find image named %s: %w", request.Source, err)
+			}
+		}
+
+		if err := client.Tag(ctx, request.Source, request.Tag); err != nil {
+			return err
+		}
+	} else {
+		options := docker.EngineBuildOptions{
+			Dockerfile: request.Dockerfile,
+			Tag:        request.Tag,
+			Target:     request.Target,
+			BuildArgs:  request.BuildArgs,
+			Pull:       request.PullAlways,
+		}
+
+		if len(request.Platforms) == 1 {
+			options.Platform = request.Platforms[0]
+		}
+
+		// The engine can only use images it already has as a cache, so the
+		//   cache image gets pulled first, if it exists yet.
+		if len(request.Cache) != 0 {
+			if err := client.Pull(ctx, request.Cache, os.Stdout); err != nil {
+				log.Debug("No build cache found at ", request.Cache, ": ", err)
+			}
+			options.CacheFrom = []string{request.Cache}
+		}
+
+		log.Info("Building ", request.Tag, " from ", request.Context)
+		if err := client.Build(ctx, request.Context, options, os.Stdout); err != nil {
+			return fmt.Errorf("failed to build %s: %w", request.Tag, err)
+		}
+
+		if len(request.Cache) != 0 {
+			if err := client.Tag(ctx, request.Tag, request.Cache); err != nil {
+				return err
+			}
+
+			if _, err := client.Push(ctx, request.Cache, os.Stdout); err != nil {
+				return fmt.Errorf("failed to export build cache to %s: %w", request.Cache, err)
+			}
+		}
+	}
+
+	digest, err := client.Push(ctx, request.Tag, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to push %s: %w", request.Tag, err)
+	}
+
+	log.Debug("Pushed ", request.Tag, "@", digest)
+	return nil
+}
+
+func (b *buildkitBuilder) BuildAndPush(log *logrus.Entry, request *ImageBuildRequest) error {
+	// BuildKit has no notion of tagging an image it doesn't have, so copies
+	//   are builds of a Dockerfile that only names the source image.
+	if len(request.Source) != 0 {
+		tempDir, err := os.MkdirTemp("", "hope-image-copy")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(tempDir)
+
+		dockerfile := fmt.Sprintf("FROM %s\n", request.Source)
+		if err := os.WriteFile(filepath.Join(tempDir, "Dockerfile"), []byte(dockerfile), 0600); err != nil {
+			return err
+		}
+
+		copyRequest := *request
+		copyRequest.Context = tempDir
+		copyRequest.Dockerfile = ""
+		request = &copyRequest
+	}
+
+	log.Info("Building ", request.Tag, " from ", request.Context)
+	return b.buildctl(BuildctlArgs(request)...)
+}
+
+// BuildctlArgs - The buildctl arguments that build and push the requested
+// image.
+func BuildctlArgs(request *ImageBuildRequest) []string {
+	dockerfile := request.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	dockerfile = filepath.Join(request.Context, dockerfile)
+
+	allArgs := []string{
+		"build",
+		"--frontend", "dockerfile.v0",
+		"--local", fmt.Sprintf("context=%s", request.Context),
+		"--local", fmt.Sprintf("dockerfile=%s", filepath.Dir(dockerfile)),
+		"--opt", fmt.Sprintf("filename=%s", filepath.Base(dockerfile)),
+	}
+
+	if len(request.Target) != 0 {
+		allArgs = append(allArgs, "--opt", fmt.Sprintf("target=%s", request.Target))
+	}
+
+	buildArgKeys := []string{}
+	for key := range request.BuildArgs {
+		buildArgKeys = append(buildArgKeys, key)
+	}
+	sort.Strings(buildArgKeys)
+
+	for _, key := range buildArgKeys {
+		allArgs = append(allArgs, "--opt", fmt.Sprintf("build-arg:%s=%s", key, request.BuildArgs[key]))
+	}
+
+	if len(request.Platforms) != 0 {
+		allArgs = append(allArgs, "--opt", fmt.Sprintf("platform=%s", strings.Join(request.Platforms, ",")))
+	}
+
+	if request.PullAlways {
+		allArgs = append(allArgs, "--opt", "image-resolve-mode=pull")
+	}
+
+	for _, secret := range request.Secrets {
+		allArgs = append(allArgs, "--secret", secret)
+	}
+
+	if len(request.Cache) != 0 {
+		allArgs = append(allArgs,
+			"--import-cache", fmt.Sprintf("type=registry,ref=%s", request.Cache),
+			"--export-cache", fmt.Sprintf("type=registry,ref=%s,mode=max", request.Cache),
+		)
+	}
+
+	allArgs = append(allArgs, "--output", fmt.Sprintf("type=image,name=%s,push=true", request.Tag))
+	return allArgs
+}
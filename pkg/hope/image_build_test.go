@@ -0,0 +1,274 @@
+package hope
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/buildkit"
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+// testImageRegistry - Serve an anonymous registry whose tags point at the
+// digests in the given map, returning its host.
+func testImageRegistry(t *testing.T, tags map[string]string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, tag, _ := strings.Cut(r.URL.Path, "/manifests/")
+		digest, ok := tags[tag]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	registry.InsecureRegistries = []string{host}
+	t.Cleanup(func() { registry.InsecureRegistries = []string{} })
+
+	ImageDigestCachePath = filepath.Join(t.TempDir(), "image-digests.json")
+	t.Cleanup(func() { ImageDigestCachePath = "" })
+
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	return host
+}
+
+func TestNewImageBuildRequest(t *testing.T) {
+	resource := Resource{
+		Name: "some-image",
+		Build: BuildSpec{
+			Path:      "some-dir",
+			Tag:       "registry.example.com/app:1.0",
+			Pull:      "always",
+			Platforms: []string{"linux/amd64", "linux/arm64"},
+		},
+	}
+
+	t.Setenv("FROM_ENV", "from-env")
+
+	request, err := NewImageBuildRequest(&resource, []string{"VERSION=1.0", "EMPTY=", "FROM_ENV"})
+	assert.Nil(t, err)
+	assert.Equal(t, &ImageBuildRequest{
+		Context:    "some-dir",
+		Tag:        "registry.example.com/app:1.0",
+		PullAlways: true,
+		BuildArgs:  map[string]string{"VERSION": "1.0", "EMPTY": "", "FROM_ENV": "from-env"},
+		Platforms:  []string{"linux/amd64", "linux/arm64"},
+	}, request)
+
+	_, err = NewImageBuildRequest(&resource, []string{"HOPE_TEST_UNSET_PARAMETER"})
+	assert.EqualError(t, err, "build arg HOPE_TEST_UNSET_PARAMETER of some-image isn't set in the environment")
+}
+
+func TestNewImageBuildRequestInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  BuildSpec
+		error string
+	}{
+		{"Path and source", BuildSpec{Path: "a", Source: "b"}, "docker build step some-image cannot have a path and a source"},
+		{"Neither", BuildSpec{Tag: "a"}, "docker build step some-image must have a path or a source"},
+		{"Pull", BuildSpec{Path: "a", Pull: "never"}, "unknown Docker image pull constraint: never"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewImageBuildRequest(&Resource{Name: "some-image", Build: tt.spec}, []string{})
+			assert.Equal(t, tt.error, err.Error())
+		})
+	}
+}
+
+func TestNewImageBuilder(t *testing.T) {
+	_, err := NewImageBuilder("podman")
+	assert.Equal(t, "unknown image builder: podman", err.Error())
+}
+
+func TestBuildctlArgs(t *testing.T) {
+	request := ImageBuildRequest{
+		Context:    "some-dir",
+		Dockerfile: "docker/Dockerfile.production",
+		Target:     "production",
+		Tag:        "registry.example.com/app:1.0",
+		PullAlways: true,
+		BuildArgs:  map[string]string{"VERSION": "1.0", "NODE_ENV": "production"},
+		Platforms:  []string{"linux/amd64", "linux/arm64"},
+		Secrets:    []string{"id=npmrc,src=secrets/npmrc"},
+		Cache:      "registry.example.com/app:buildcache",
+	}
+
+	assert.Equal(t, []string{
+		"build",
+		"--frontend", "dockerfile.v0",
+		"--local", "context=some-dir",
+		"--local", "dockerfile=some-dir/docker",
+		"--opt", "filename=Dockerfile.production",
+		"--opt", "target=production",
+		"--opt", "build-arg:NODE_ENV=production",
+		"--opt", "build-arg:VERSION=1.0",
+		"--opt", "platform=linux/amd64,linux/arm64",
+		"--opt", "image-resolve-mode=pull",
+		"--secret", "id=npmrc,src=secrets/npmrc",
+		"--import-cache", "type=registry,ref=registry.example.com/app:buildcache",
+		"--export-cache", "type=registry,ref=registry.example.com/app:buildcache,mode=max",
+		"--output", "type=image,name=registry.example.com/app:1.0,push=true",
+	}, BuildctlArgs(&request))
+}
+
+func TestBuildkitBuilderCopiesSource(t *testing.T) {
+	var dockerfile string
+	builder := buildkitBuilder{func(args ...string) error {
+		assert.Equal(t, "--local", args[3])
+		contextDir := args[4][len("context="):]
+
+		contents, err := os.ReadFile(filepath.Join(contextDir, "Dockerfile"))
+		assert.Nil(t, err)
+		dockerfile = string(contents)
+		return nil
+	}}
+
+	request := ImageBuildRequest{
+		Source:    "python:3.7",
+		Tag:       "registry.example.com/python:3.7",
+		Platforms: []string{"linux/amd64", "linux/arm64"},
+	}
+
+	assert.Nil(t, builder.BuildAndPush(log.WithFields(log.Fields{}), &request))
+	assert.Equal(t, "FROM python:3.7\n", dockerfile)
+	assert.Equal(t, "", request.Context)
+}
+
+func TestDockerEngineBuilderUnsupported(t *testing.T) {
+	builder := dockerEngineBuilder{}
+
+	err := builder.BuildAndPush(log.WithFields(log.Fields{}), &ImageBuildRequest{Secrets: []string{"id=a"}})
+	assert.Equal(t, "the docker builder doesn't support build secrets; use the buildkit or rootless builder", err.Error())
+
+	err = builder.BuildAndPush(log.WithFields(log.Fields{}), &ImageBuildRequest{Platforms: []string{"linux/amd64", "linux/arm64"}})
+	assert.Equal(t, "the docker builder can't build multiple platforms; use the buildkit or rootless builder", err.Error())
+}
+
+func TestBuildImageSkipsUnchangedImages(t *testing.T) {
+	tags := map[string]string{}
+	host := testImageRegistry(t, tags)
+
+	oldExecBuildctl := buildkit.ExecBuildctl
+	defer func() { buildkit.ExecBuildctl = oldExecBuildctl }()
+
+	builds := 0
+	buildkit.ExecBuildctl = func(args ...string) error {
+		builds++
+		tags["1.0"] = fmt.Sprintf("sha256:%d", builds)
+		return nil
+	}
+
+	contextDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+
+	resource := Resource{
+		Name:  "some-image",
+		Build: BuildSpec{Path: contextDir, Tag: host + "/app:1.0", Builder: "buildkit"},
+	}
+
+	digest, err := BuildImage(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:1", digest)
+
+	digest, err = BuildImage(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:1", digest)
+	assert.Equal(t, 1, builds)
+
+	// Changing the build's inputs, or the image in the registry, builds again.
+	digest, err = BuildImage(log.WithFields(log.Fields{}), &resource, []string{"VERSION=1.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:2", digest)
+
+	tags["1.0"] = "sha256:somebody-else"
+	digest, err = BuildImage(log.WithFields(log.Fields{}), &resource, []string{"VERSION=1.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:3", digest)
+
+	// Pulling always builds every time.
+	resource.Build.Pull = "always"
+	_, err = BuildImage(log.WithFields(log.Fields{}), &resource, []string{"VERSION=1.0"})
+	assert.Nil(t, err)
+	assert.Equal(t, 4, builds)
+}
+
+func TestImageBuildInputsDockerignore(t *testing.T) {
+	contextDir := t.TempDir()
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "Dockerfile"), []byte("FROM scratch\n"), 0644))
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, ".dockerignore"), []byte("**/*.log\n"), 0644))
+	assert.Nil(t, os.MkdirAll(filepath.Join(contextDir, "logs"), 0755))
+
+	request := &ImageBuildRequest{Context: contextDir, Tag: "registry.example.com/app:1.0"}
+	inputs, err := imageBuildInputs("buildkit", request, "")
+	assert.Nil(t, err)
+
+	// Ignored files never reach the builder, so they can't change the image.
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "logs", "build.log"), []byte("noise"), 0644))
+	ignoredInputs, err := imageBuildInputs("buildkit", request, "")
+	assert.Nil(t, err)
+	assert.Equal(t, inputs, ignoredInputs)
+
+	assert.Nil(t, os.WriteFile(filepath.Join(contextDir, "main.go"), []byte("package main"), 0644))
+	changedInputs, err := imageBuildInputs("buildkit", request, "")
+	assert.Nil(t, err)
+	assert.NotEqual(t, inputs, changedInputs)
+}
+
+func TestBuildImageMissingSource(t *testing.T) {
+	host := testImageRegistry(t, map[string]string{})
+
+	resource := Resource{
+		Name:  "some-image",
+		Build: BuildSpec{Source: host + "/python:3.7", Tag: host + "/python:3.7-copy"},
+	}
+
+	_, err := BuildImage(log.WithFields(log.Fields{}), &resource, []string{})
+	assert.Equal(t, fmt.Sprintf("failed to find image named %s/python:3.7", host), err.Error())
+}
+
+func TestImageDigestParameter(t *testing.T) {
+	assert.Equal(t, "IMAGE_DIGEST_BUILD_SOME_IMAGE", ImageDigestParameter("build-some-image"))
+	assert.Equal(t, "IMAGE_DIGEST_APP_V2", ImageDigestParameter("app.v2"))
+}
+
+func TestPinImageDigests(t *testing.T) {
+	host := testImageRegistry(t, map[string]string{"latest": "sha256:registry"})
+	t.Setenv("IMAGE_DIGEST_FROM_ENV", "sha256:env")
+
+	resources := []Resource{
+		{Name: "built-now", Build: BuildSpec{Path: "a", Tag: host + "/a"}},
+		{Name: "built-before", Build: BuildSpec{Path: "b", Tag: host + "/b"}},
+	}
+
+	digests := map[string]string{"IMAGE_DIGEST_BUILT_NOW": "sha256:now"}
+	parameters, err := PinImageDigests(resources, []Parameter{
+		ParseParameter("A=B"),
+		ParseParameter("IMAGE_DIGEST_BUILT_NOW"),
+		ParseParameter("IMAGE_DIGEST_BUILT_BEFORE"),
+		ParseParameter("IMAGE_DIGEST_FROM_ENV"),
+		ParseParameter("IMAGE_DIGEST_UNKNOWN"),
+		ParseParameter("IMAGE_DIGEST_GIVEN=sha256:given"),
+	}, digests)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"IMAGE_DIGEST_BUILT_NOW=sha256:now",
+		"IMAGE_DIGEST_BUILT_BEFORE=sha256:registry",
+	}, parameters)
+}
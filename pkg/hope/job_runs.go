@@ -0,0 +1,227 @@
+package hope
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// Labels given to every job run created by hope, so that past runs can be
+// found again.
+const (
+	JobRunLabel    = "hope.job"
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+)
+
+// Job names are limited to the length of a label value, since they're used
+// as the job-name label of the job's pods.
+const maxJobNameLength = 63
+
+// Length of the random suffix given to each run of a job.
+const jobRunSuffixLength = 5
+
+// NewJobRun - Build a single run of the job from its rendered manifest.
+// Every run gets a unique name from the manifest's name, or generateName,
+// with a random suffix, so the same job can be run any number of times.
+// The namespace is only used if the manifest doesn't set one, and the ttl,
+// if given, has the cluster delete the run that long after it finishes.
+func NewJobRun(job *Job, manifest, namespace, ttl string) (*batchv1.Job, error) {
+	run, baseName, err := decodeJobManifest(job, manifest, namespace, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBaseLength := maxJobNameLength - jobRunSuffixLength - 1
+	if len(baseName) > maxBaseLength {
+		baseName = strings.TrimSuffix(baseName[:maxBaseLength], "-")
+	}
+
+	run.Name = fmt.Sprintf("%s-%s", baseName, utilrand.String(jobRunSuffixLength))
+	return run, nil
+}
+
+// decodeJobManifest - Decode the job's rendered manifest, labelling it as
+// one of hope's jobs, and find the name its runs are named after.
+func decodeJobManifest(job *Job, manifest, namespace, ttl string) (*batchv1.Job, string, error) {
+	objects, err := kubeutil.DecodeManifests(manifest)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(objects) != 1 {
+		return nil, "", fmt.Errorf("job %s must contain exactly one object, found %d", job.Name, len(objects))
+	}
+
+	if objects[0].GetKind() != "Job" {
+		return nil, "", fmt.Errorf("job %s must contain a Job, found %s", job.Name, objects[0].GetKind())
+	}
+
+	run := &batchv1.Job{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(objects[0].Object, run); err != nil {
+		return nil, "", err
+	}
+
+	if namespace != "" {
+		if run.Namespace != "" && run.Namespace != namespace {
+			return nil, "", fmt.Errorf("job %s is in namespace %s, not %s", job.Name, run.Namespace, namespace)
+		}
+		run.Namespace = namespace
+	}
+
+	baseName := run.Name
+	if baseName == "" {
+		baseName = strings.TrimSuffix(run.GenerateName, "-")
+	}
+	if baseName == "" {
+		baseName = job.Name
+	}
+
+	run.Name = ""
+	run.GenerateName = ""
+
+	if run.Labels == nil {
+		run.Labels = map[string]string{}
+	}
+	run.Labels[JobRunLabel] = job.Name
+	run.Labels[ManagedByLabel] = "hope"
+
+	if ttl != "" {
+		duration, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid job ttl %s: %w", ttl, err)
+		}
+
+		if duration < 0 {
+			return nil, "", fmt.Errorf("invalid job ttl %s: must not be negative", ttl)
+		}
+
+		seconds := int32(duration.Seconds())
+		run.Spec.TTLSecondsAfterFinished = &seconds
+	}
+
+	return run, baseName, nil
+}
+
+// CreateJobRun - Create the job run in the cluster, returning its namespaced
+// name.
+func CreateJobRun(kubectl *kubeutil.Kubectl, run *batchv1.Job) (string, error) {
+	created, err := kubeutil.CreateJob(kubectl, run)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s/%s", created.Namespace, created.Name), nil
+}
+
+// DeleteJobRun - Delete the job run, along with its pods.
+func DeleteJobRun(kubectl *kubeutil.Kubectl, nsRun string) error {
+	namespace, name := SplitNamespacedName(nsRun)
+	return kubeutil.DeleteJob(kubectl, namespace, name)
+}
+
+// ListJobRuns - Get the runs hope has created of the named job, or of every
+// job if no name is given, across all namespaces, newest first.
+func ListJobRuns(kubectl *kubeutil.Kubectl, job string) ([]kubeutil.JobSummary, error) {
+	selector := JobRunLabel
+	if job != "" {
+		selector = fmt.Sprintf("%s=%s", JobRunLabel, job)
+	}
+
+	return kubeutil.ListJobs(kubectl, "", selector)
+}
+
+// PrintJobRuns - Write a table of the job runs, with how long ago each
+// started, and how long each ran for, or has been running for.
+func PrintJobRuns(out io.Writer, runs []kubeutil.JobSummary, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tJOB\tSTATUS\tAGE\tDURATION")
+	for _, run := range runs {
+		status := "Running"
+		switch run.Result {
+		case kubeutil.JobResultComplete:
+			status = "Complete"
+		case kubeutil.JobResultFailed:
+			status = "Failed"
+		}
+
+		finished := run.Finished
+		if finished.IsZero() {
+			finished = now
+		}
+
+		age := now.Sub(run.Started).Round(time.Second)
+		duration := finished.Sub(run.Started).Round(time.Second)
+		fmt.Fprintf(w, "%s/%s\t%s\t%s\t%s\t%s\n", run.Namespace, run.Name, run.Labels[JobRunLabel], status, age, duration)
+	}
+}
+
+// FindJobRun - Find the namespaced name of a job run given either its
+// namespaced name, or just its name, if no other run shares it.
+func FindJobRun(kubectl *kubeutil.Kubectl, run string) (string, error) {
+	if strings.Contains(run, "/") {
+		return run, nil
+	}
+
+	runs, err := ListJobRuns(kubectl, "")
+	if err != nil {
+		return "", err
+	}
+
+	found := []string{}
+	for _, r := range runs {
+		if r.Name == run {
+			found = append(found, fmt.Sprintf("%s/%s", r.Namespace, r.Name))
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("job run %s not found", run)
+	case 1:
+		return found[0], nil
+	}
+
+	return "", fmt.Errorf("job run %s found in multiple namespaces (%s); include the namespace", run, strings.Join(found, ", "))
+}
+
+// PrintJobRunLogs - Write the logs of each of the job run's pods, oldest
+// first.
+// Logs are only available for as long as the run's pods are kept around.
+func PrintJobRunLogs(kubectl *kubeutil.Kubectl, nsRun string, out io.Writer) error {
+	namespace, name := SplitNamespacedName(nsRun)
+	pods, err := kubeutil.GetJobPods(kubectl, namespace, name)
+	if err != nil {
+		return err
+	}
+
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods found for job run %s; they may have been cleaned up", nsRun)
+	}
+
+	for _, pod := range pods {
+		if len(pods) > 1 {
+			fmt.Fprintf(out, "==> pod/%s (restarts: %d) <==\n", pod.Name, pod.Restarts)
+		}
+
+		if err := kubeutil.StreamPodLogs(context.Background(), kubectl, namespace, pod.Name, "", false, out); err != nil {
+			return fmt.Errorf("failed to fetch logs of pod %s: %w", pod.Name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+package hope
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+const testJobRunManifest = `apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: some-job-
+  labels:
+    app: some-job
+spec:
+  template:
+    spec:
+      restartPolicy: Never
+      containers:
+        - name: main
+          image: busybox
+`
+
+func testJobRun(namespace, name, job string, started time.Time, conditions ...batchv1.JobConditionType) *batchv1.Job {
+	run := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         namespace,
+		Labels:            map[string]string{JobRunLabel: job},
+		CreationTimestamp: metav1.NewTime(started),
+	}}
+	run.Status.StartTime = &metav1.Time{Time: started}
+	for _, condition := range conditions {
+		run.Status.Conditions = append(run.Status.Conditions, batchv1.JobCondition{
+			Type:               condition,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(started.Add(time.Minute)),
+		})
+	}
+
+	return run
+}
+
+func TestNewJobRun(t *testing.T) {
+	job := &Job{Name: "some-job"}
+
+	run, err := NewJobRun(job, testJobRunManifest, "", "")
+	assert.Nil(t, err)
+	assert.Regexp(t, "^some-job-[a-z0-9]{5}$", run.Name)
+	assert.Equal(t, "", run.GenerateName)
+	assert.Equal(t, "", run.Namespace)
+	assert.Equal(t, map[string]string{"app": "some-job", JobRunLabel: "some-job", ManagedByLabel: "hope"}, run.Labels)
+	assert.Nil(t, run.Spec.TTLSecondsAfterFinished)
+
+	other, err := NewJobRun(job, testJobRunManifest, "", "")
+	assert.Nil(t, err)
+	assert.NotEqual(t, run.Name, other.Name)
+
+	run, err = NewJobRun(job, testJobRunManifest, "jobs", "1h")
+	assert.Nil(t, err)
+	assert.Equal(t, "jobs", run.Namespace)
+	assert.Equal(t, int32(3600), *run.Spec.TTLSecondsAfterFinished)
+
+	named := strings.Replace(testJobRunManifest, "generateName: some-job-", "name: "+strings.Repeat("a", 70)+"\n  namespace: jobs", 1)
+	run, err = NewJobRun(job, named, "jobs", "")
+	assert.Nil(t, err)
+	assert.Equal(t, 63, len(run.Name))
+	assert.True(t, strings.HasPrefix(run.Name, strings.Repeat("a", 57)+"-"))
+
+	_, err = NewJobRun(job, named, "other", "")
+	assert.EqualError(t, err, "job some-job is in namespace jobs, not other")
+
+	_, err = NewJobRun(job, testJobRunManifest, "", "soon")
+	assert.ErrorContains(t, err, "invalid job ttl soon")
+
+	_, err = NewJobRun(job, "apiVersion: v1\nkind: Pod\nmetadata:\n  name: pod\n", "", "")
+	assert.EqualError(t, err, "job some-job must contain a Job, found Pod")
+
+	_, err = NewJobRun(job, testJobRunManifest+"---\n"+testJobRunManifest, "", "")
+	assert.EqualError(t, err, "job some-job must contain exactly one object, found 2")
+}
+
+func TestCreateAndDeleteJobRun(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset})
+
+	run, err := NewJobRun(&Job{Name: "some-job"}, testJobRunManifest, "", "")
+	assert.Nil(t, err)
+
+	nsRun, err := CreateJobRun(kubectl, run)
+	assert.Nil(t, err)
+	assert.Equal(t, "default/"+run.Name, nsRun)
+
+	runs, err := ListJobRuns(kubectl, "some-job")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(runs))
+
+	assert.Nil(t, DeleteJobRun(kubectl, nsRun))
+
+	runs, err = ListJobRuns(kubectl, "")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(runs))
+}
+
+func TestListAndPrintJobRuns(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		testJobRun("default", "some-job-aaaaa", "some-job", now.Add(-time.Hour), batchv1.JobComplete),
+		testJobRun("dev", "some-job-bbbbb", "some-job", now.Add(-time.Minute*30), batchv1.JobFailed),
+		testJobRun("default", "other-job-ccccc", "other-job", now.Add(-time.Minute*5)),
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "unrelated", Namespace: "default"}},
+	)})
+
+	runs, err := ListJobRuns(kubectl, "")
+	assert.Nil(t, err)
+
+	var out bytes.Buffer
+	PrintJobRuns(&out, runs, now)
+	assert.Equal(t, strings.Join([]string{
+		"NAME                      JOB         STATUS     AGE      DURATION",
+		"default/other-job-ccccc   other-job   Running    5m0s     5m0s",
+		"dev/some-job-bbbbb        some-job    Failed     30m0s    1m0s",
+		"default/some-job-aaaaa    some-job    Complete   1h0m0s   1m0s",
+		"",
+	}, "\n"), out.String())
+
+	runs, err = ListJobRuns(kubectl, "some-job")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(runs))
+}
+
+func TestFindJobRun(t *testing.T) {
+	now := time.Now()
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		testJobRun("default", "some-job-aaaaa", "some-job", now),
+		testJobRun("dev", "some-job-bbbbb", "some-job", now),
+		testJobRun("prod", "some-job-bbbbb", "some-job", now),
+	)})
+
+	nsRun, err := FindJobRun(kubectl, "some-job-aaaaa")
+	assert.Nil(t, err)
+	assert.Equal(t, "default/some-job-aaaaa", nsRun)
+
+	nsRun, err = FindJobRun(kubectl, "dev/some-job-bbbbb")
+	assert.Nil(t, err)
+	assert.Equal(t, "dev/some-job-bbbbb", nsRun)
+
+	_, err = FindJobRun(kubectl, "some-job-bbbbb")
+	assert.EqualError(t, err, "job run some-job-bbbbb found in multiple namespaces (dev/some-job-bbbbb, prod/some-job-bbbbb); include the namespace")
+
+	_, err = FindJobRun(kubectl, "some-job-ccccc")
+	assert.EqualError(t, err, "job run some-job-ccccc not found")
+}
+
+func TestPrintJobRunLogs(t *testing.T) {
+	pod := func(name string, created time.Time) runtime.Object {
+		return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			Labels:            map[string]string{"job-name": "some-job-aaaaa"},
+			CreationTimestamp: metav1.NewTime(created),
+		}}
+	}
+
+	now := time.Now()
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		pod("some-job-aaaaa-2", now),
+		pod("some-job-aaaaa-1", now.Add(-time.Minute)),
+	)})
+
+	var out bytes.Buffer
+	assert.Nil(t, PrintJobRunLogs(kubectl, "default/some-job-aaaaa", &out))
+	assert.Equal(t, "==> pod/some-job-aaaaa-1 (restarts: 0) <==\nfake logs==> pod/some-job-aaaaa-2 (restarts: 0) <==\nfake logs", out.String())
+
+	err := PrintJobRunLogs(kubectl, "default/some-job-bbbbb", &out)
+	assert.EqualError(t, err, "no pods found for job run default/some-job-bbbbb; they may have been cleaned up")
+}
@@ -0,0 +1,180 @@
+package hope
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+import (
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// CronJob names are kept short enough for the controller to add the
+// timestamp suffix given to each of the jobs it creates.
+const maxCronJobNameLength = 52
+
+// JobSchedule - A job set to run on a schedule in the cluster, along with
+// the Secret holding its secret parameters, if it has any.
+type JobSchedule struct {
+	CronJob *batchv1.CronJob
+	Secret  *corev1.Secret
+}
+
+// NewJobSchedule - Render the job into a CronJob that runs it on its
+// schedule.
+// Parameters are substituted into the job as they are for `hope run`,
+// except for secret parameters; their values are put into a Secret, and
+// references to them are replaced with $(NAME), which the cluster expands
+// from each container's environment.
+// That means secret parameters can only be used in the command, args, and
+// env of the job's containers.
+func NewJobSchedule(job *Job, parameters []string, namespace string) (*JobSchedule, error) {
+	if job.Schedule == "" {
+		return nil, fmt.Errorf("job %s has no schedule", job.Name)
+	}
+
+	secretParameters := []string{}
+	for _, parameter := range job.Parameters {
+		if parameter.Secret {
+			secretParameters = append(secretParameters, parameter.Name)
+		}
+	}
+
+	substitutions := []string{}
+	secretData := map[string]string{}
+	for _, param := range parameters {
+		name, value, hasValue := strings.Cut(param, "=")
+		if !slices.Contains(secretParameters, name) {
+			substitutions = append(substitutions, param)
+			continue
+		}
+
+		if !hasValue {
+			var exists bool
+			if value, exists = os.LookupEnv(name); !exists {
+				return nil, fmt.Errorf("failed to find %s in environment", name)
+			}
+		}
+
+		secretData[name] = value
+		substitutions = append(substitutions, fmt.Sprintf("%s=$(%s)", name, name))
+	}
+
+	manifest, err := ReplaceParametersInFile(job.File, substitutions)
+	if err != nil {
+		return nil, err
+	}
+
+	run, baseName, err := decodeJobManifest(job, manifest, namespace, job.TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(baseName) > maxCronJobNameLength {
+		baseName = strings.TrimSuffix(baseName[:maxCronJobNameLength], "-")
+	}
+
+	labels := map[string]string{JobRunLabel: job.Name, ManagedByLabel: "hope"}
+	schedule := &JobSchedule{}
+	schedule.CronJob = &batchv1.CronJob{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "batch/v1", Kind: "CronJob"},
+		ObjectMeta: metav1.ObjectMeta{Name: baseName, Namespace: run.Namespace, Labels: labels},
+		Spec: batchv1.CronJobSpec{
+			Schedule: job.Schedule,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: run.Labels, Annotations: run.Annotations},
+				Spec:       run.Spec,
+			},
+		},
+	}
+
+	if len(secretData) == 0 {
+		return schedule, nil
+	}
+
+	secretName := fmt.Sprintf("%s-params", baseName)
+	schedule.Secret = &corev1.Secret{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: run.Namespace, Labels: labels},
+		Type:       corev1.SecretTypeOpaque,
+		StringData: secretData,
+	}
+
+	// Secrets are listed first, so that the containers' own env can refer to
+	//   them too.
+	env := []corev1.EnvVar{}
+	for _, name := range secretParameters {
+		if _, ok := secretData[name]; !ok {
+			continue
+		}
+
+		env = append(env, corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: secretName},
+					Key:                  name,
+				},
+			},
+		})
+	}
+
+	podSpec := &schedule.CronJob.Spec.JobTemplate.Spec.Template.Spec
+	for i := range podSpec.InitContainers {
+		podSpec.InitContainers[i].Env = append(slices.Clone(env), podSpec.InitContainers[i].Env...)
+	}
+	for i := range podSpec.Containers {
+		podSpec.Containers[i].Env = append(slices.Clone(env), podSpec.Containers[i].Env...)
+	}
+
+	return schedule, nil
+}
+
+// Objects - The objects that make up the schedule, in the order they should
+// be applied.
+func (schedule *JobSchedule) Objects() ([]*unstructured.Unstructured, error) {
+	typed := []runtime.Object{}
+	if schedule.Secret != nil {
+		typed = append(typed, schedule.Secret)
+	}
+	typed = append(typed, schedule.CronJob)
+
+	objects := []*unstructured.Unstructured{}
+	for _, object := range typed {
+		content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+		if err != nil {
+			return nil, err
+		}
+
+		// Empty status and creation timestamps are meaningless to apply.
+		delete(content, "status")
+		unstructured.RemoveNestedField(content, "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "spec", "jobTemplate", "metadata", "creationTimestamp")
+		unstructured.RemoveNestedField(content, "spec", "jobTemplate", "spec", "template", "metadata", "creationTimestamp")
+
+		objects = append(objects, &unstructured.Unstructured{Object: content})
+	}
+
+	return objects, nil
+}
+
+// ApplyJobSchedule - Create or update the schedule's objects in the
+// cluster.
+func ApplyJobSchedule(kubectl *kubeutil.Kubectl, schedule *JobSchedule) error {
+	objects, err := schedule.Objects()
+	if err != nil {
+		return err
+	}
+
+	return kubeutil.ApplyObjects(kubectl, objects, FieldManager)
+}
@@ -0,0 +1,97 @@
+package hope
+
+import (
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func testScheduledJob() *Job {
+	return &Job{
+		Name: "prune-registry",
+		File: "../../test/scheduled-job.yaml",
+		Parameters: []Parameter{
+			{Name: "REGISTRY_HOST"},
+			{Name: "REGISTRY_TOKEN", Secret: true},
+			{Name: "KEEP_TAGS", Type: ParameterTypeInt},
+		},
+		Schedule: "0 4 * * 0",
+		TTL:      "1h",
+	}
+}
+
+func TestNewJobSchedule(t *testing.T) {
+	t.Setenv("REGISTRY_TOKEN", "hunter2")
+
+	job := testScheduledJob()
+	parameters := []string{"REGISTRY_HOST=registry.example.com", "REGISTRY_TOKEN", "KEEP_TAGS=10"}
+	schedule, err := NewJobSchedule(job, parameters, "")
+	assert.Nil(t, err)
+
+	cronJob := schedule.CronJob
+	assert.Equal(t, "prune-registry", cronJob.Name)
+	assert.Equal(t, "dev", cronJob.Namespace)
+	assert.Equal(t, "0 4 * * 0", cronJob.Spec.Schedule)
+	assert.Equal(t, map[string]string{JobRunLabel: "prune-registry", ManagedByLabel: "hope"}, cronJob.Labels)
+	assert.Equal(t, map[string]string{JobRunLabel: "prune-registry", ManagedByLabel: "hope"}, cronJob.Spec.JobTemplate.Labels)
+	assert.Equal(t, int32(3600), *cronJob.Spec.JobTemplate.Spec.TTLSecondsAfterFinished)
+
+	container := cronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0]
+	script := container.Command[2]
+	assert.Contains(t, script, "Bearer $(REGISTRY_TOKEN)")
+	assert.Contains(t, script, "https://registry.example.com/prune?keep=10")
+	assert.NotContains(t, script, "hunter2")
+
+	assert.Equal(t, []corev1.EnvVar{{
+		Name: "REGISTRY_TOKEN",
+		ValueFrom: &corev1.EnvVarSource{
+			SecretKeyRef: &corev1.SecretKeySelector{
+				LocalObjectReference: corev1.LocalObjectReference{Name: "prune-registry-params"},
+				Key:                  "REGISTRY_TOKEN",
+			},
+		},
+	}}, container.Env)
+
+	secret := schedule.Secret
+	assert.Equal(t, "prune-registry-params", secret.Name)
+	assert.Equal(t, "dev", secret.Namespace)
+	assert.Equal(t, map[string]string{"REGISTRY_TOKEN": "hunter2"}, secret.StringData)
+
+	objects, err := schedule.Objects()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(objects))
+	assert.Equal(t, "Secret", objects[0].GetKind())
+	assert.Equal(t, "CronJob", objects[1].GetKind())
+	assert.NotContains(t, objects[1].Object, "status")
+}
+
+func TestNewJobScheduleWithoutSecrets(t *testing.T) {
+	job := testScheduledJob()
+	job.Parameters[1].Secret = false
+
+	schedule, err := NewJobSchedule(job, []string{"REGISTRY_HOST=registry.example.com", "REGISTRY_TOKEN=token", "KEEP_TAGS=10"}, "")
+	assert.Nil(t, err)
+	assert.Nil(t, schedule.Secret)
+	assert.Contains(t, schedule.CronJob.Spec.JobTemplate.Spec.Template.Spec.Containers[0].Command[2], "Bearer token")
+
+	objects, err := schedule.Objects()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(objects))
+}
+
+func TestNewJobScheduleErrors(t *testing.T) {
+	job := testScheduledJob()
+	job.Schedule = ""
+	_, err := NewJobSchedule(job, []string{}, "")
+	assert.EqualError(t, err, "job prune-registry has no schedule")
+
+	job = testScheduledJob()
+	_, err = NewJobSchedule(job, []string{"REGISTRY_TOKEN"}, "")
+	assert.EqualError(t, err, "failed to find REGISTRY_TOKEN in environment")
+
+	_, err = NewJobSchedule(job, []string{"REGISTRY_TOKEN=token"}, "prod")
+	assert.EqualError(t, err, "job prune-registry is in namespace dev, not prod")
+}
@@ -0,0 +1,169 @@
+package hope
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// KubeconfigCacheTTL - How long a cached admin kubeconfig is used for before
+// it's fetched from a master again.
+// The cache is only used when this is set.
+var KubeconfigCacheTTL time.Duration = 0
+
+// KubeconfigCacheDir - Directory encrypted admin kubeconfigs are kept in.
+// When empty, a directory inside the user's cache directory is used.
+var KubeconfigCacheDir string = ""
+
+// KubeconfigCacheKeyPath - File holding the key cached kubeconfigs are
+// encrypted with, which is generated the first time it's needed.
+// When empty, a file inside the user's config directory is used, so that the
+// key isn't kept alongside what it encrypts.
+var KubeconfigCacheKeyPath string = ""
+
+const kubeconfigCacheKeySize = 32
+
+// ReadCachedKubeconfig - Get the cached admin kubeconfig of a cluster, if
+// there is one that hasn't expired.
+func ReadCachedKubeconfig(cluster string) ([]byte, bool, error) {
+	cachePath, err := kubeconfigCachePath(cluster)
+	if err != nil {
+		return nil, false, err
+	}
+
+	info, err := os.Stat(cachePath)
+	if os.IsNotExist(err) {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	if time.Since(info.ModTime()) >= KubeconfigCacheTTL {
+		return nil, false, nil
+	}
+
+	ciphertext, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	aead, err := kubeconfigCacheCipher()
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, false, fmt.Errorf("cached kubeconfig %s is corrupt", cachePath)
+	}
+
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	contents, err := aead.Open(nil, nonce, ciphertext, []byte(cluster))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decrypt cached kubeconfig %s: %w", cachePath, err)
+	}
+
+	return contents, true, nil
+}
+
+// WriteCachedKubeconfig - Encrypt and cache the admin kubeconfig of a
+// cluster.
+func WriteCachedKubeconfig(cluster string, contents []byte) error {
+	cachePath, err := kubeconfigCachePath(cluster)
+	if err != nil {
+		return err
+	}
+
+	aead, err := kubeconfigCacheCipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(cachePath, aead.Seal(nonce, nonce, contents, []byte(cluster)), 0600)
+}
+
+// InvalidateCachedKubeconfig - Remove the cached admin kubeconfig of a
+// cluster, if there is one.
+func InvalidateCachedKubeconfig(cluster string) error {
+	cachePath, err := kubeconfigCachePath(cluster)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func kubeconfigCachePath(cluster string) (string, error) {
+	dir := KubeconfigCacheDir
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(userCacheDir, "hope", "kubeconfigs")
+	}
+
+	return filepath.Join(dir, sha256Hex([]byte(cluster))), nil
+}
+
+// kubeconfigCacheCipher - The cipher cached kubeconfigs are encrypted with,
+// generating its key if there isn't one yet.
+func kubeconfigCacheCipher() (cipher.AEAD, error) {
+	keyPath := KubeconfigCacheKeyPath
+	if keyPath == "" {
+		userConfigDir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, err
+		}
+
+		keyPath = filepath.Join(userConfigDir, "hope", "kubeconfig-cache.key")
+	}
+
+	key, err := os.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		key = make([]byte, kubeconfigCacheKeySize)
+		if _, err := io.ReadFull(rand.Reader, key); err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+			return nil, err
+		}
+
+		if err := os.WriteFile(keyPath, key, 0600); err != nil {
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(key) != kubeconfigCacheKeySize {
+		return nil, errors.New("kubeconfig cache key must be 32 bytes")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
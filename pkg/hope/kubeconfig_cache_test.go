@@ -0,0 +1,94 @@
+package hope
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func useTestKubeconfigCache(t *testing.T) string {
+	originalTTL, originalDir, originalKey := KubeconfigCacheTTL, KubeconfigCacheDir, KubeconfigCacheKeyPath
+	t.Cleanup(func() {
+		KubeconfigCacheTTL, KubeconfigCacheDir, KubeconfigCacheKeyPath = originalTTL, originalDir, originalKey
+	})
+
+	dir := t.TempDir()
+	KubeconfigCacheTTL = time.Hour
+	KubeconfigCacheDir = filepath.Join(dir, "cache")
+	KubeconfigCacheKeyPath = filepath.Join(dir, "config", "key")
+	return dir
+}
+
+func TestCachedKubeconfig(t *testing.T) {
+	useTestKubeconfigCache(t)
+
+	contents, ok, err := ReadCachedKubeconfig("homelab")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, contents)
+
+	assert.Nil(t, WriteCachedKubeconfig("homelab", []byte("apiVersion: v1")))
+
+	contents, ok, err = ReadCachedKubeconfig("homelab")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "apiVersion: v1", string(contents))
+
+	_, ok, err = ReadCachedKubeconfig("work")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	cachePath, err := kubeconfigCachePath("homelab")
+	assert.Nil(t, err)
+
+	ciphertext, err := os.ReadFile(cachePath)
+	assert.Nil(t, err)
+	assert.NotContains(t, string(ciphertext), "apiVersion")
+
+	key, err := os.Stat(KubeconfigCacheKeyPath)
+	assert.Nil(t, err)
+	assert.Equal(t, os.FileMode(0600), key.Mode().Perm())
+
+	assert.Nil(t, InvalidateCachedKubeconfig("homelab"))
+	assert.Nil(t, InvalidateCachedKubeconfig("homelab"))
+
+	_, ok, err = ReadCachedKubeconfig("homelab")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestCachedKubeconfigExpires(t *testing.T) {
+	useTestKubeconfigCache(t)
+
+	assert.Nil(t, WriteCachedKubeconfig("homelab", []byte("apiVersion: v1")))
+
+	cachePath, err := kubeconfigCachePath("homelab")
+	assert.Nil(t, err)
+
+	old := time.Now().Add(-2 * time.Hour)
+	assert.Nil(t, os.Chtimes(cachePath, old, old))
+
+	_, ok, err := ReadCachedKubeconfig("homelab")
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestCachedKubeconfigWrongKey(t *testing.T) {
+	dir := useTestKubeconfigCache(t)
+
+	assert.Nil(t, WriteCachedKubeconfig("homelab", []byte("apiVersion: v1")))
+
+	KubeconfigCacheKeyPath = filepath.Join(dir, "config", "other-key")
+	_, ok, err := ReadCachedKubeconfig("homelab")
+	assert.False(t, ok)
+	assert.ErrorContains(t, err, "failed to decrypt cached kubeconfig")
+
+	assert.Nil(t, os.WriteFile(KubeconfigCacheKeyPath, []byte("short"), 0600))
+	_, _, err = ReadCachedKubeconfig("homelab")
+	assert.Equal(t, "kubeconfig cache key must be 32 bytes", err.Error())
+}
@@ -1,14 +1,20 @@
 package hope
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"strings"
+	"text/tabwriter"
 	"time"
 )
 
 import (
 	"github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 )
 
 import (
@@ -24,51 +30,220 @@ const (
 	JobStatusFailed
 )
 
+// SplitNamespacedName - Split a namespace/name string into its parts,
+// defaulting to the default namespace if none is given.
+func SplitNamespacedName(nsName string) (string, string) {
+	namespace, name, found := strings.Cut(nsName, "/")
+	if !found {
+		return "default", nsName
+	}
+
+	return namespace, name
+}
+
 // Check to see if the provided job has completed, or is still running.
 func GetJobStatus(log *logrus.Entry, kubectl *kubeutil.Kubectl, namespace, job string) (JobStatus, error) {
-	template := "template={{range .status.conditions}}{{.type}}\n{{end}}"
-	output, err := kubeutil.GetKubectl(kubectl, "get", "-n", namespace, "job", job, "-o", template)
+	result, err := kubeutil.GetJobResult(kubectl, namespace, job)
 	if err != nil {
 		return JobStatusUnknown, err
 	}
 
-	// Search for terminal statuses, and with none, assume running.
-	for _, status := range strings.Split(output, "\n") {
-		switch status {
-		case "Complete":
-			return JobStatusComplete, nil
-		case "Failed":
-			return JobStatusFailed, nil
-		}
+	switch result {
+	case kubeutil.JobResultComplete:
+		return JobStatusComplete, nil
+	case kubeutil.JobResultFailed:
+		return JobStatusFailed, nil
 	}
 
 	return JobStatusRunning, nil
 }
 
-func FollowLogsIfContainersRunning(kubectl *kubeutil.Kubectl, namespace, job string) error {
+func GetPodsForJob(kubectl *kubeutil.Kubectl, namespace, job string) (*[]string, error) {
 	jobSelector := fmt.Sprintf("job-name=%s", job)
-	return kubeutil.ExecKubectl(kubectl, "logs", "-n", namespace, "-f", "-l", jobSelector)
+	return GetPodsForSelector(kubectl, namespace, jobSelector)
 }
 
-func GetPodsForJob(kubectl *kubeutil.Kubectl, namespace, job string) (*[]string, error) {
-	jobSelector := fmt.Sprintf("job-name=%s", job)
-	output, err := kubeutil.GetKubectl(kubectl, "get", "-n", namespace, "pods", "-l", jobSelector, "-o", "template={{range .items}}{{.metadata.name}} {{end}}")
+// GetPodsForSelector - Get the names of the pods in the namespace matching
+// the label selector.
+func GetPodsForSelector(kubectl *kubeutil.Kubectl, namespace, selector string) (*[]string, error) {
+	pods, err := kubeutil.GetPodNames(kubectl, namespace, selector)
 	if err != nil {
 		return nil, err
 	}
 
-	pods := strings.Split(strings.TrimSpace(output), " ")
 	return &pods, nil
 }
 
-func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kubectl, nsJob string, maxAttempts int, failedPollDelayMaxSeconds int) error {
-	namespace := "default"
-	job := nsJob
+// PrintPodEvents - Print the events of each of the given pods.
+// Failures are ignored; events are only printed to help track down a
+// problem that's already been found.
+func PrintPodEvents(kubectl *kubeutil.Kubectl, namespace string, pods []string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "LAST SEEN\tTYPE\tREASON\tOBJECT\tMESSAGE")
+	for _, pod := range pods {
+		events, err := kubeutil.GetPodEvents(kubectl, namespace, pod)
+		if err != nil {
+			continue
+		}
+
+		for _, event := range events {
+			lastSeen := time.Since(event.LastSeen).Round(time.Second)
+			fmt.Fprintf(w, "%s\t%s\t%s\tpod/%s\t%s\n", lastSeen, event.Type, event.Reason, pod, event.Message)
+		}
+	}
+}
+
+// JobWaitOptions - How long to wait for a job to finish, and where its logs
+// are written besides stdout.
+// Without a timeout, waiting gives up after the maximum number of attempts;
+// with one, attempts are unlimited unless a maximum is given.
+type JobWaitOptions struct {
+	Timeout      time.Duration
+	MaxAttempts  int
+	MaxPollDelay time.Duration
+	LogFile      string
+}
+
+// Number of times a job is checked on before giving up, if not configured.
+const DefaultJobMaxAttempts = 10
+
+// NewJobWaitOptions - Build the options for waiting on a job from the
+// timeout, attempts, and log file given in the hope file.
+func NewJobWaitOptions(timeout string, maxAttempts int, logFile string, maxPollDelay time.Duration) (JobWaitOptions, error) {
+	options := JobWaitOptions{MaxAttempts: maxAttempts, MaxPollDelay: maxPollDelay, LogFile: logFile}
+	if timeout != "" {
+		duration, err := time.ParseDuration(timeout)
+		if err != nil {
+			return options, fmt.Errorf("invalid job timeout %s: %w", timeout, err)
+		}
+		options.Timeout = duration
+	}
+
+	if options.MaxAttempts < 0 {
+		return options, fmt.Errorf("invalid job max attempts %d", maxAttempts)
+	}
+
+	if options.MaxAttempts == 0 && options.Timeout == 0 {
+		options.MaxAttempts = DefaultJobMaxAttempts
+	}
+
+	return options, nil
+}
+
+// jobLogFollower - Streams the logs of each of a job's pods exactly once,
+// even as the job retries, and its pods are checked on again and again.
+// Logs go to out, while headers separating each pod's logs only go to the
+// log file, if there is one.
+type jobLogFollower struct {
+	kubectl   *kubeutil.Kubectl
+	namespace string
+	job       string
+	out       io.Writer
+	logFile   io.Writer
+	printed   map[string]bool
+}
 
-	components := strings.Split(nsJob, "/")
-	if len(components) == 2 {
-		namespace = components[0]
-		job = components[1]
+// podKey - Identifies one run of a pod's containers, so restarted containers
+// get their logs printed again.
+func (f *jobLogFollower) podKey(pod kubeutil.JobPod) string {
+	return fmt.Sprintf("%s/%d", pod.Name, pod.Restarts)
+}
+
+// follow - Stream the logs of every pod of the job that hasn't had its logs
+// printed yet, following them until their containers exit.
+func (f *jobLogFollower) follow(ctx context.Context, log *logrus.Entry) error {
+	pods, err := kubeutil.GetJobPods(f.kubectl, f.namespace, f.job)
+	if err != nil {
+		return err
+	}
+
+	if len(pods) == 0 {
+		return fmt.Errorf("no pods found for job %s", f.job)
+	}
+
+	for _, pod := range pods {
+		key := f.podKey(pod)
+		if f.printed[key] || pod.Phase == string(corev1.PodPending) {
+			continue
+		}
+
+		if len(f.printed) != 0 {
+			log.Info("Job ", f.job, " retried; following logs of pod ", pod.Name)
+		}
+
+		fmt.Fprintf(f.logFile, "==> pod/%s (restarts: %d) <==\n", pod.Name, pod.Restarts)
+		if err := kubeutil.StreamPodLogs(ctx, f.kubectl, f.namespace, pod.Name, "", true, f.out); err != nil {
+			return err
+		}
+
+		f.printed[key] = true
+	}
+
+	return nil
+}
+
+// failed - Print the logs of the job's failed pod, unless they were already
+// printed, and the pod's events, then describe the failure.
+func (f *jobLogFollower) failed(log *logrus.Entry) error {
+	pods, err := kubeutil.GetJobPods(f.kubectl, f.namespace, f.job)
+	if err != nil {
+		log.Warn(err)
+	}
+
+	var failedPod *kubeutil.JobPod
+	for i := range pods {
+		if pods[i].Phase == string(corev1.PodFailed) || failedPod == nil {
+			failedPod = &pods[i]
+		}
+	}
+
+	if failedPod != nil {
+		if !f.printed[f.podKey(*failedPod)] {
+			log.Info("Logs of failed pod ", failedPod.Name, ":")
+			fmt.Fprintf(f.logFile, "==> pod/%s (restarts: %d) <==\n", failedPod.Name, failedPod.Restarts)
+			if err := kubeutil.StreamPodLogs(context.Background(), f.kubectl, f.namespace, failedPod.Name, "", false, f.out); err != nil {
+				log.Warn("Failed to fetch logs of pod ", failedPod.Name, ": ", err)
+			}
+			f.printed[f.podKey(*failedPod)] = true
+		}
+
+		log.Info("Events of failed pod ", failedPod.Name, ":")
+		PrintPodEvents(f.kubectl, f.namespace, []string{failedPod.Name})
+	}
+
+	message, err := kubeutil.GetJobFailureMessage(f.kubectl, f.namespace, f.job)
+	if err != nil || message == "" {
+		return fmt.Errorf("job %s failed", f.job)
+	}
+
+	return fmt.Errorf("job %s failed: %s", f.job, message)
+}
+
+// FollowLogsAndPollUntilJobComplete - Wait for the job to finish, streaming
+// the logs of each of its pods, including retries, as they run.
+// If the job fails, the logs and events of its failed pod are printed.
+func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kubectl, nsJob string, options JobWaitOptions) error {
+	namespace, job := SplitNamespacedName(nsJob)
+
+	ctx := context.Background()
+	if options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, options.Timeout)
+		defer cancel()
+	}
+
+	follower := &jobLogFollower{kubectl, namespace, job, os.Stdout, io.Discard, map[string]bool{}}
+	if options.LogFile != "" {
+		file, err := os.OpenFile(options.LogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		follower.out = io.MultiWriter(os.Stdout, file)
+		follower.logFile = file
 	}
 
 	// Check the job status before anything.
@@ -82,18 +257,25 @@ func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kube
 
 	switch status {
 	case JobStatusFailed:
-		return fmt.Errorf("job %s failed", job)
+		return follower.failed(log)
 	case JobStatusComplete:
 		log.Debug("Job ", nsJob, " successful.")
 		return nil
 	}
 
-	for attempt := 0; attempt < maxAttempts; attempt++ {
-		attemptsDuration := math.Pow(2, float64(attempt))
+	timedOut := func() error {
+		return fmt.Errorf("job %s did not finish within %s. The job may still be running", job, options.Timeout)
+	}
+
+	failedPollDelayMaxSeconds := int(options.MaxPollDelay.Seconds())
+	for attempt := 0; options.MaxAttempts == 0 || attempt < options.MaxAttempts; attempt++ {
+		attemptsDuration := math.Pow(2, math.Min(float64(attempt), 30))
 		onFailureSleepSeconds := int(math.Min(attemptsDuration, float64(failedPollDelayMaxSeconds)))
 
-		logsErr := FollowLogsIfContainersRunning(kubectl, namespace, job)
-		if logsErr != nil {
+		logsErr := follower.follow(ctx, log)
+		if ctx.Err() != nil {
+			return timedOut()
+		} else if logsErr != nil {
 			log.Warn(logsErr)
 		}
 
@@ -107,7 +289,7 @@ func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kube
 
 		switch status {
 		case JobStatusFailed:
-			return fmt.Errorf("job %s failed", job)
+			return follower.failed(log)
 		case JobStatusComplete:
 			log.Debug("Job ", job, " successful.")
 			return nil
@@ -124,10 +306,7 @@ func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kube
 				// TODO: Keep track of which pods have been printed, and if
 				//   there have been no events for a given pod since the last
 				//   time we tried to print them, don't print anything.
-				for _, pods := range *pods {
-					involvedObject := fmt.Sprintf("involvedObject.name=%s", pods)
-					kubeutil.ExecKubectl(kubectl, "get", "events", "-n", namespace, "--field-selector", involvedObject)
-				}
+				PrintPodEvents(kubectl, namespace, *pods)
 			}
 		}
 		if logsErr != nil {
@@ -135,8 +314,29 @@ func FollowLogsAndPollUntilJobComplete(log *logrus.Entry, kubectl *kubeutil.Kube
 		} else {
 			log.Warn("Logs fetched, but job ", job, " is still running. Waiting ", onFailureSleepSeconds, " seconds and trying again.")
 		}
-		time.Sleep(time.Second * time.Duration(onFailureSleepSeconds))
+
+		// Rather than sleeping blindly, watch the job so that finishing
+		//   while waiting is noticed right away.
+		waitCtx, cancel := context.WithTimeout(ctx, time.Second*time.Duration(onFailureSleepSeconds))
+		result, err := kubeutil.WatchJob(waitCtx, kubectl, namespace, job)
+		if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+			log.Warn(err)
+			<-waitCtx.Done()
+		}
+		cancel()
+
+		switch result {
+		case kubeutil.JobResultFailed:
+			return follower.failed(log)
+		case kubeutil.JobResultComplete:
+			log.Debug("Job ", job, " successful.")
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			return timedOut()
+		}
 	}
 
-	return fmt.Errorf("job did not finish within %d attempts. The job may still be running", maxAttempts)
+	return fmt.Errorf("job did not finish within %d attempts. The job may still be running", options.MaxAttempts)
 }
@@ -1,13 +1,22 @@
 package hope
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 import (
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 import (
@@ -38,12 +47,107 @@ func TestKubectlJobs(t *testing.T) {
 func (s *KubectlJobsTestSuite) TestGetJobStatus() {
 	t := s.T()
 
-	kubeutil.GetKubectl = func(kubectl *kubeutil.Kubectl, args ...string) (string, error) {
-		return "SuccessCriteriaMet\nComplete", nil
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "imaginary-job", Namespace: "default"}}
+	job.Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobSuccessCriteriaMet, Status: corev1.ConditionTrue},
+		{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
 	}
 
-	kubectl := kubeutil.Kubectl{}
-	status, err := GetJobStatus(log.WithFields(log.Fields{}), &kubectl, "default", "imaginary-job")
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(&job)})
+	status, err := GetJobStatus(log.WithFields(log.Fields{}), kubectl, "default", "imaginary-job")
 	assert.Nil(t, err)
 	assert.Equal(t, status, JobStatusComplete)
+
+	_, err = GetJobStatus(log.WithFields(log.Fields{}), kubectl, "default", "missing-job")
+	assert.Error(t, err)
+}
+
+func jobPod(name, job string, phase corev1.PodPhase, restarts int32) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"job-name": job}},
+		Status: corev1.PodStatus{
+			Phase:             phase,
+			ContainerStatuses: []corev1.ContainerStatus{{RestartCount: restarts}},
+		},
+	}
+}
+
+func (s *KubectlJobsTestSuite) TestNewJobWaitOptions() {
+	t := s.T()
+
+	options, err := NewJobWaitOptions("", 0, "", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, JobWaitOptions{MaxAttempts: DefaultJobMaxAttempts, MaxPollDelay: time.Minute}, options)
+
+	options, err = NewJobWaitOptions("5m", 0, "job.log", time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, JobWaitOptions{Timeout: 5 * time.Minute, MaxPollDelay: time.Minute, LogFile: "job.log"}, options)
+
+	_, err = NewJobWaitOptions("soon", 0, "", time.Minute)
+	assert.Equal(t, "invalid job timeout soon: time: invalid duration \"soon\"", err.Error())
+
+	_, err = NewJobWaitOptions("", -1, "", time.Minute)
+	assert.Equal(t, "invalid job max attempts -1", err.Error())
+}
+
+func (s *KubectlJobsTestSuite) TestJobLogFollowerPrintsEachRetryOnce() {
+	t := s.T()
+
+	clientset := fake.NewSimpleClientset(
+		jobPod("some-job-a", "some-job", corev1.PodFailed, 0),
+		jobPod("some-job-b", "some-job", corev1.PodRunning, 0),
+		jobPod("some-job-c", "some-job", corev1.PodPending, 0),
+	)
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset})
+
+	var out, logFile bytes.Buffer
+	follower := jobLogFollower{kubectl, "default", "some-job", &out, &logFile, map[string]bool{}}
+
+	assert.Nil(t, follower.follow(context.Background(), log.WithFields(log.Fields{})))
+	assert.Nil(t, follower.follow(context.Background(), log.WithFields(log.Fields{})))
+	assert.Equal(t, "fake logsfake logs", out.String())
+	assert.Equal(t, "==> pod/some-job-a (restarts: 0) <==\n==> pod/some-job-b (restarts: 0) <==\n", logFile.String())
+
+	// Restarted containers, and pods that have started since, get printed.
+	_, err := clientset.CoreV1().Pods("default").Update(context.Background(), jobPod("some-job-b", "some-job", corev1.PodRunning, 1), metav1.UpdateOptions{})
+	assert.Nil(t, err)
+	_, err = clientset.CoreV1().Pods("default").Update(context.Background(), jobPod("some-job-c", "some-job", corev1.PodRunning, 0), metav1.UpdateOptions{})
+	assert.Nil(t, err)
+
+	assert.Nil(t, follower.follow(context.Background(), log.WithFields(log.Fields{})))
+	assert.Equal(t, "fake logsfake logsfake logsfake logs", out.String())
+}
+
+func (s *KubectlJobsTestSuite) TestFollowLogsAndPollUntilJobCompleteFailed() {
+	t := s.T()
+
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "default"}}
+	job.Status.Conditions = []batchv1.JobCondition{
+		{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded", Message: "Job has reached the specified backoff limit"},
+	}
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(
+		&job,
+		jobPod("some-job-a", "some-job", corev1.PodFailed, 0),
+	)})
+
+	logFile := filepath.Join(t.TempDir(), "job.log")
+	options := JobWaitOptions{MaxAttempts: 1, MaxPollDelay: time.Second, LogFile: logFile}
+	err := FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, "some-job", options)
+	assert.Equal(t, "job some-job failed: BackoffLimitExceeded: Job has reached the specified backoff limit", err.Error())
+
+	contents, err := os.ReadFile(logFile)
+	assert.Nil(t, err)
+	assert.Equal(t, "==> pod/some-job-a (restarts: 0) <==\nfake logs", string(contents))
+}
+
+func (s *KubectlJobsTestSuite) TestFollowLogsAndPollUntilJobCompleteTimeout() {
+	t := s.T()
+
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "default"}}
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(&job)})
+
+	options := JobWaitOptions{Timeout: 100 * time.Millisecond, MaxPollDelay: time.Second}
+	err := FollowLogsAndPollUntilJobComplete(log.WithFields(log.Fields{}), kubectl, "default/some-job", options)
+	assert.Equal(t, "job some-job did not finish within 100ms. The job may still be running", err.Error())
 }
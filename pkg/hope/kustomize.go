@@ -0,0 +1,23 @@
+package hope
+
+import (
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// BuildKustomization - Render the kustomization rooted at the given
+// directory, the same way `kubectl kustomize` would.
+func BuildKustomization(path string) (string, error) {
+	kustomizer := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := kustomizer.Run(filesys.MakeFsOnDisk(), path)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := resources.AsYaml()
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
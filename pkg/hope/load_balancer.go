@@ -2,6 +2,7 @@ package hope
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -15,6 +16,8 @@ import (
 	"github.com/Eagerod/hope/pkg/ssh"
 )
 
+var loadBalancerUpstreamRegexp *regexp.Regexp = regexp.MustCompile(`server\s+([^\s;]+):6443;`)
+
 // Just forwards to `SetLoadBalancerHosts`.
 // There may be a time where this does more.
 func InitLoadBalancer(log *logrus.Entry, node *Node, masters *[]Node) error {
@@ -41,6 +44,46 @@ func loadBalancerConfigurationFile(log *logrus.Entry, upstreams *[]Node) string
 	return fmt.Sprintf(NginxConfig, masterUpstreamContents)
 }
 
+// Pull the hosts out of the upstream block of an nginx.conf written by
+// `loadBalancerConfigurationFile`.
+// The black hole upstream used when there are no masters isn't included.
+func loadBalancerConfigurationUpstreams(config string) []string {
+	hosts := []string{}
+	for _, match := range loadBalancerUpstreamRegexp.FindAllStringSubmatch(config, -1) {
+		if match[1] == "0.0.0.0" {
+			continue
+		}
+		hosts = append(hosts, match[1])
+	}
+
+	return hosts
+}
+
+// GetLoadBalancerHosts - Read the nginx configuration on the load balancer,
+// and return the hosts currently set as its upstreams.
+func GetLoadBalancerHosts(log *logrus.Entry, node *Node) ([]string, error) {
+	config, err := ssh.GetSSH(node.ConnectionString(), "sudo", "cat", "/etc/nginx/nginx.conf")
+	if err != nil {
+		return nil, err
+	}
+
+	hosts := loadBalancerConfigurationUpstreams(config)
+	log.Tracef("Load balancer %s has upstreams: %s", node.Host, strings.Join(hosts, ", "))
+	return hosts, nil
+}
+
+// GetLoadBalancerContainer - Get the id of the nginx container serving the
+// API Server port on the load balancer, or an empty string if none is
+// running.
+func GetLoadBalancerContainer(node *Node) (string, error) {
+	output, err := ssh.GetSSH(node.ConnectionString(), "sudo", "docker", "ps", "--filter", "publish=6443", "--quiet")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
 func SetLoadBalancerHosts(log *logrus.Entry, node *Node, masters *[]Node) error {
 	if len(*masters) == 0 {
 		log.Warn("Setting empty load balancer hosts.")
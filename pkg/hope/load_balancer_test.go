@@ -22,3 +22,16 @@ func TestLoadBalancerConfigurationFileMasters(t *testing.T) {
 	config := loadBalancerConfigurationFile(log.WithFields(log.Fields{}), &masters)
 	assert.Contains(t, config, "192.168.1.254:6443")
 }
+
+func TestLoadBalancerConfigurationUpstreams(t *testing.T) {
+	masters := []Node{
+		Node{Host: "192.168.1.254"},
+		Node{Host: "192.168.1.253"},
+	}
+	config := loadBalancerConfigurationFile(log.WithFields(log.Fields{}), &masters)
+	assert.Equal(t, []string{"192.168.1.254", "192.168.1.253"}, loadBalancerConfigurationUpstreams(config))
+
+	masters = []Node{}
+	config = loadBalancerConfigurationFile(log.WithFields(log.Fields{}), &masters)
+	assert.Equal(t, []string{}, loadBalancerConfigurationUpstreams(config))
+}
@@ -0,0 +1,192 @@
+package hope
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"gopkg.in/yaml.v3"
+)
+
+// ManifestObject - The identifying properties of a single Kubernetes object
+// found in a manifest.
+type ManifestObject struct {
+	APIVersion string
+	Kind       string
+	Name       string
+	Namespace  string
+}
+
+type manifestObjectDocument struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name      string `yaml:"name"`
+		Namespace string `yaml:"namespace"`
+	} `yaml:"metadata"`
+	Items []manifestObjectDocument `yaml:"items"`
+}
+
+// Group - The API group of the object; empty for the core group.
+func (o *ManifestObject) Group() string {
+	group, _, found := strings.Cut(o.APIVersion, "/")
+	if !found {
+		return ""
+	}
+
+	return group
+}
+
+// KubectlType - The type of the object in a form kubectl will accept,
+// qualified with its group so that kinds with the same name in different
+// groups don't collide.
+func (o *ManifestObject) KubectlType() string {
+	if group := o.Group(); group != "" {
+		return fmt.Sprintf("%s.%s", strings.ToLower(o.Kind), group)
+	}
+
+	return strings.ToLower(o.Kind)
+}
+
+// KubectlName - The type and name of the object, as kubectl expects it.
+func (o *ManifestObject) KubectlName() string {
+	return fmt.Sprintf("%s/%s", o.KubectlType(), o.Name)
+}
+
+func (o ManifestObject) String() string {
+	if o.Namespace != "" {
+		return fmt.Sprintf("%s %s/%s", o.Kind, o.Namespace, o.Name)
+	}
+
+	return fmt.Sprintf("%s %s", o.Kind, o.Name)
+}
+
+// ParseManifestObjects - Find every object defined in a set of yaml/json
+// documents.
+// Lists are expanded into their items, and empty documents are skipped.
+// Objects that only provide generateName can't be identified ahead of time,
+// and are skipped too.
+func ParseManifestObjects(manifests string) ([]ManifestObject, error) {
+	objects := []ManifestObject{}
+
+	decoder := yaml.NewDecoder(strings.NewReader(manifests))
+	for {
+		var doc manifestObjectDocument
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		objects = append(objects, manifestDocumentObjects(doc)...)
+	}
+
+	return objects, nil
+}
+
+func manifestDocumentObjects(doc manifestObjectDocument) []ManifestObject {
+	if strings.HasSuffix(doc.Kind, "List") && len(doc.Items) != 0 {
+		objects := []ManifestObject{}
+		for _, item := range doc.Items {
+			objects = append(objects, manifestDocumentObjects(item)...)
+		}
+		return objects
+	}
+
+	if doc.Kind == "" || doc.Metadata.Name == "" {
+		return []ManifestObject{}
+	}
+
+	return []ManifestObject{
+		{
+			APIVersion: doc.APIVersion,
+			Kind:       doc.Kind,
+			Name:       doc.Metadata.Name,
+			Namespace:  doc.Metadata.Namespace,
+		},
+	}
+}
+
+// ResourceManifests - Render the manifests of a file, inline, or kustomize
+// resource, with its parameters substituted, the same way they would be
+// before being applied.
+// Directories are read the same way kubectl reads them; only the yaml and
+// json files directly inside of them are included.
+func ResourceManifests(resource *Resource, parameters []string) (string, error) {
+	resourceType, err := resource.GetType()
+	if err != nil {
+		return "", err
+	}
+
+	switch resourceType {
+	case ResourceTypeInline:
+		return ReplaceParametersInString(resource.Inline, parameters)
+	case ResourceTypeKustomize:
+		contents, err := BuildKustomization(resource.Kustomize)
+		if err != nil {
+			return "", err
+		}
+
+		return ReplaceParametersInString(contents, parameters)
+	case ResourceTypeFile:
+		if IsRemoteFile(resource.File) {
+			contents, err := FetchRemoteFile(resource.File, resource.Sha256)
+			if err != nil {
+				return "", err
+			}
+
+			return ReplaceParametersInString(string(contents), parameters)
+		}
+
+		if resource.Sha256 != "" {
+			return "", fmt.Errorf("resource '%s' has a sha256, but its file isn't a URL", resource.Name)
+		}
+
+		info, err := os.Stat(resource.File)
+		if err != nil {
+			return "", err
+		}
+
+		if !info.IsDir() {
+			return ReplaceParametersInFile(resource.File, parameters)
+		}
+
+		entries, err := os.ReadDir(resource.File)
+		if err != nil {
+			return "", err
+		}
+
+		var buf bytes.Buffer
+		for _, entry := range entries {
+			switch filepath.Ext(entry.Name()) {
+			case ".yaml", ".yml", ".json":
+			default:
+				continue
+			}
+
+			if entry.IsDir() {
+				continue
+			}
+
+			contents, err := ReplaceParametersInFile(filepath.Join(resource.File, entry.Name()), parameters)
+			if err != nil {
+				return "", err
+			}
+
+			buf.WriteString("---\n")
+			buf.WriteString(contents)
+			buf.WriteString("\n")
+		}
+
+		return buf.String(), nil
+	}
+
+	return "", fmt.Errorf("resource type (%s) does not have manifests", resourceType)
+}
@@ -0,0 +1,85 @@
+package hope
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifests string = `# Comment before any documents
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: mysql
+  namespace: database
+---
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: mysql
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: config
+  - apiVersion: rbac.authorization.k8s.io/v1
+    kind: ClusterRole
+    metadata:
+      name: reader
+---
+apiVersion: batch/v1
+kind: Job
+metadata:
+  generateName: some-job-
+`
+
+func TestParseManifestObjects(t *testing.T) {
+	objects, err := ParseManifestObjects(testManifests)
+	assert.NoError(t, err)
+	assert.Equal(t, []ManifestObject{
+		{"apps/v1", "Deployment", "mysql", "database"},
+		{"v1", "Service", "mysql", ""},
+		{"v1", "ConfigMap", "config", ""},
+		{"rbac.authorization.k8s.io/v1", "ClusterRole", "reader", ""},
+	}, objects)
+
+	_, err = ParseManifestObjects("kind: [")
+	assert.Error(t, err)
+}
+
+func TestManifestObjectKubectlName(t *testing.T) {
+	objects, err := ParseManifestObjects(testManifests)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "deployment.apps/mysql", objects[0].KubectlName())
+	assert.Equal(t, "service/mysql", objects[1].KubectlName())
+	assert.Equal(t, "clusterrole.rbac.authorization.k8s.io/reader", objects[3].KubectlName())
+	assert.Equal(t, "Deployment database/mysql", objects[0].String())
+	assert.Equal(t, "Service mysql", objects[1].String())
+}
+
+func TestResourceManifestsKustomize(t *testing.T) {
+	dir := t.TempDir()
+	kustomization := "namePrefix: prod-\nnamespace: web\nresources:\n  - config.yaml\n"
+	config := "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\ndata:\n  host: ${HOST}\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "kustomization.yaml"), []byte(kustomization), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(config), 0644))
+
+	resource := Resource{Name: "config", Kustomize: dir}
+	parameters := []string{"HOST=example.com"}
+	manifests, err := ResourceManifests(&resource, parameters)
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\ndata:\n  host: example.com\nkind: ConfigMap\nmetadata:\n  name: prod-config\n  namespace: web\n", manifests)
+
+	resource.Kustomize = filepath.Join(dir, "missing")
+	_, err = ResourceManifests(&resource, parameters)
+	assert.Error(t, err)
+}
@@ -0,0 +1,178 @@
+package hope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+// Number of images mirrored at the same time when a mirror doesn't say.
+const DefaultMirrorConcurrency = 4
+
+// MirrorImage - An image to copy, and where it's copied to.
+type MirrorImage struct {
+	Source string
+	Target string
+}
+
+// MirrorImages - The images the mirror resource copies, and the names they
+// have in the mirror's registry.
+// Images keep their repository path, minus Docker Hub's library prefix, so
+// python:3.7 becomes <registry>/python:3.7.
+// Images pinned to a digest are copied by that digest, and keep their tag
+// in the mirror, if they have one.
+func MirrorImages(spec *MirrorSpec) ([]MirrorImage, error) {
+	images := []MirrorImage{}
+	for _, image := range spec.Images {
+		ref, err := registry.ParseReference(image)
+		if err != nil {
+			return nil, err
+		}
+
+		repository := ref.Repository
+		if ref.Registry == "docker.io" {
+			repository = strings.TrimPrefix(repository, "library/")
+		}
+
+		name, digest, pinned := strings.Cut(image, "@")
+		tag := ref.Reference
+		if pinned {
+			lastSegment := name[strings.LastIndex(name, "/")+1:]
+			_, tag, _ = strings.Cut(lastSegment, ":")
+		}
+
+		target := fmt.Sprintf("%s/%s:%s", spec.Registry, repository, tag)
+		if tag == "" {
+			target = fmt.Sprintf("%s/%s@%s", spec.Registry, repository, digest)
+		}
+
+		images = append(images, MirrorImage{ref.String(), target})
+	}
+
+	return images, nil
+}
+
+// MirrorResource - Copy every image of the mirror resource into its
+// registry, several at a time.
+// Images already in the mirror with the same digest are skipped, and a
+// failure to copy one image doesn't stop the others from being copied.
+func MirrorResource(log *logrus.Entry, resource *Resource) error {
+	images, err := MirrorImages(&resource.Mirror)
+	if err != nil {
+		return err
+	}
+
+	concurrency := resource.Mirror.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultMirrorConcurrency
+	}
+
+	ctx := context.Background()
+	client := registry.NewClient()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	errs := []error{}
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			if err := mirrorImage(ctx, log, client, image); err != nil {
+				log.Error("Failed to mirror ", image.Source, ": ", err)
+
+				lock.Lock()
+				errs = append(errs, fmt.Errorf("%s: %w", image.Source, err))
+				lock.Unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to mirror %d of %d images:\n%w", len(errs), len(images), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func mirrorImage(ctx context.Context, log *logrus.Entry, client *registry.Client, image MirrorImage) error {
+	sourceDigest, exists, err := client.ManifestDigest(ctx, image.Source)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return fmt.Errorf("image not found")
+	}
+
+	targetDigest, exists, err := client.ManifestDigest(ctx, image.Target)
+	if err != nil {
+		return err
+	}
+
+	if exists && targetDigest == sourceDigest {
+		log.Debug(image.Target, " already mirrored (", sourceDigest, ")")
+		return nil
+	}
+
+	// Copy the exact digest that was checked, in case the tag moves while
+	//   the copy is happening.
+	ref, err := registry.ParseReference(image.Source)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Mirroring ", image.Source, " to ", image.Target)
+	digest, err := client.Copy(ctx, ref.WithReference(sourceDigest).String(), image.Target)
+	if err != nil {
+		return err
+	}
+
+	log.Info(image.Target, " mirrored (", digest, ")")
+	return nil
+}
+
+// MirrorStatus - How many of the mirror resource's images are missing from
+// its registry.
+// If the registry can't be asked about an image, the status is unknown.
+func MirrorStatus(resource *Resource) (ResourceStatusReport, error) {
+	images, err := MirrorImages(&resource.Mirror)
+	if err != nil {
+		return ResourceStatusReport{}, err
+	}
+
+	client := registry.NewClient()
+	missing := []string{}
+	for _, image := range images {
+		_, exists, err := client.ManifestDigest(context.Background(), image.Target)
+		if err != nil {
+			return ResourceStatusReport{ResourceStatusUnknown, fmt.Sprintf("%s: %s", image.Target, err)}, nil
+		}
+
+		if !exists {
+			missing = append(missing, image.Target)
+		}
+	}
+
+	if len(missing) != 0 {
+		return ResourceStatusReport{ResourceStatusMissing, fmt.Sprintf("%d of %d images missing: %s", len(missing), len(images), strings.Join(missing, ", "))}, nil
+	}
+
+	return ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%d images mirrored", len(images))}, nil
+}
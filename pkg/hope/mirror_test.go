@@ -0,0 +1,94 @@
+package hope
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+func TestMirrorImages(t *testing.T) {
+	spec := MirrorSpec{
+		Registry: "registry.example.com",
+		Images: []string{
+			"python:3.7",
+			"bitnami/redis",
+			"quay.io/prometheus/prometheus:v2.45.0",
+			"nginx:1.25@sha256:abc",
+			"alpine@sha256:def",
+		},
+	}
+
+	images, err := MirrorImages(&spec)
+	assert.Nil(t, err)
+	assert.Equal(t, []MirrorImage{
+		{"docker.io/library/python:3.7", "registry.example.com/python:3.7"},
+		{"docker.io/bitnami/redis:latest", "registry.example.com/bitnami/redis:latest"},
+		{"quay.io/prometheus/prometheus:v2.45.0", "registry.example.com/prometheus/prometheus:v2.45.0"},
+		{"docker.io/library/nginx@sha256:abc", "registry.example.com/nginx:1.25"},
+		{"docker.io/library/alpine@sha256:def", "registry.example.com/alpine@sha256:def"},
+	}, images)
+}
+
+func TestMirrorResource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/manifests/present"):
+			w.Header().Set("Docker-Content-Digest", "sha256:abc")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	registry.InsecureRegistries = []string{host}
+	defer func() { registry.InsecureRegistries = []string{} }()
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	resource := Resource{
+		Name: "mirror",
+		Mirror: MirrorSpec{
+			Registry: host + "/mirror",
+			Images:   []string{host + "/upstream:present", host + "/upstream:missing"},
+		},
+	}
+
+	err := MirrorResource(log.WithFields(log.Fields{}), &resource)
+	assert.Equal(t, "failed to mirror 1 of 2 images:\n"+host+"/upstream:missing: image not found", err.Error())
+
+	status, err := MirrorStatus(&resource)
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusReport{ResourceStatusMissing, "1 of 2 images missing: " + host + "/mirror/upstream:missing"}, status)
+}
+
+func TestMirrorStatusRegistryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	registry.InsecureRegistries = []string{host}
+	defer func() { registry.InsecureRegistries = []string{} }()
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	resource := Resource{
+		Name:   "mirror",
+		Mirror: MirrorSpec{Registry: host + "/mirror", Images: []string{"python:3.7"}},
+	}
+
+	status, err := MirrorStatus(&resource)
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusUnknown, status.Status)
+	assert.True(t, strings.HasPrefix(status.Detail, host+"/mirror/python:3.7: "), status.Detail)
+}
@@ -0,0 +1,193 @@
+package hope
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Types a parameter's value can be checked against.
+// Parameters without a type are strings.
+const (
+	ParameterTypeString = "string"
+	ParameterTypeInt    = "int"
+	ParameterTypeBool   = "bool"
+	ParameterTypeEnum   = "enum"
+)
+
+// Parameter - A value substituted into the files of a resource or job.
+// In the hope file, a parameter can be given as just its NAME, which is read
+// from the environment, or as NAME=value, which always uses that value.
+// Parameters can otherwise be fully described, with a type their value is
+// validated against, a default, a description shown when asking for it, and
+// whether it's secret.
+// Enum parameters must take one of the listed Values.
+type Parameter struct {
+	Name        string
+	Type        string
+	Default     interface{}
+	Description string
+	Values      []string
+	Secret      bool
+
+	// Value is only set by the NAME=value shorthand.
+	Value *string
+}
+
+// ParameterPrompt - Asks for the value of a parameter that wasn't given any
+// other way.
+type ParameterPrompt func(parameter Parameter) (string, error)
+
+// ParseParameter - Parse the NAME or NAME=value shorthand of a parameter.
+func ParseParameter(str string) Parameter {
+	name, value, found := strings.Cut(str, "=")
+	if !found {
+		return Parameter{Name: name}
+	}
+
+	return Parameter{Name: name, Value: &value}
+}
+
+// String - The parameter in the form given to parameter substitution; NAME
+// if it comes from the environment, or NAME=value.
+func (parameter Parameter) String() string {
+	if parameter.Value == nil {
+		return parameter.Name
+	}
+
+	return fmt.Sprintf("%s=%s", parameter.Name, *parameter.Value)
+}
+
+// DefaultValue - The parameter's default, if it has one.
+func (parameter Parameter) DefaultValue() (string, bool) {
+	if parameter.Default == nil {
+		return "", false
+	}
+
+	return fmt.Sprint(parameter.Default), true
+}
+
+// Validate - Check that the value is valid for the parameter's type.
+func (parameter Parameter) Validate(value string) error {
+	switch parameter.Type {
+	case "", ParameterTypeString:
+		return nil
+	case ParameterTypeInt:
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("parameter %s must be an integer, not %q", parameter.Name, value)
+		}
+	case ParameterTypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("parameter %s must be true or false, not %q", parameter.Name, value)
+		}
+	case ParameterTypeEnum:
+		if len(parameter.Values) == 0 {
+			return fmt.Errorf("enum parameter %s must list its values", parameter.Name)
+		}
+
+		if !slices.Contains(parameter.Values, value) {
+			return fmt.Errorf("parameter %s must be one of %s, not %q", parameter.Name, strings.Join(parameter.Values, ", "), value)
+		}
+	default:
+		return fmt.Errorf("parameter %s has unknown type %s", parameter.Name, parameter.Type)
+	}
+
+	return nil
+}
+
+// ResolveParameters - Work out the value of each parameter, validating each
+// one, and return them in the form given to parameter substitution.
+// Values come from, in order of precedence: the given NAME=value pairs, the
+// value set in the hope file, the environment, the parameter's default, and
+// finally the prompt, if there is one.
+// Parameters taken from the environment are left as just their name, so that
+// their values are only read by the substitution itself.
+func ResolveParameters(parameters []Parameter, given []string, prompt ParameterPrompt) ([]string, error) {
+	givenValues := map[string]string{}
+	for _, param := range given {
+		name, value, _ := strings.Cut(param, "=")
+		if !slices.ContainsFunc(parameters, func(p Parameter) bool { return p.Name == name }) {
+			return nil, fmt.Errorf("parameter: %s not recognized", name)
+		}
+
+		givenValues[name] = value
+	}
+
+	resolved := []string{}
+	for _, parameter := range parameters {
+		value, ok := givenValues[parameter.Name]
+		if !ok && parameter.Value != nil {
+			value, ok = *parameter.Value, true
+		}
+
+		if !ok {
+			if envValue, exists := os.LookupEnv(parameter.Name); exists {
+				if err := parameter.Validate(envValue); err != nil {
+					return nil, err
+				}
+
+				resolved = append(resolved, parameter.Name)
+				continue
+			}
+		}
+
+		if !ok {
+			value, ok = parameter.DefaultValue()
+		}
+
+		if !ok {
+			if prompt == nil {
+				return nil, fmt.Errorf("failed to find %s in environment", parameter.Name)
+			}
+
+			var err error
+			if value, err = prompt(parameter); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := parameter.Validate(value); err != nil {
+			return nil, err
+		}
+
+		resolved = append(resolved, fmt.Sprintf("%s=%s", parameter.Name, value))
+	}
+
+	return resolved, nil
+}
+
+// PrintParameters - Write a table describing each of the parameters.
+func PrintParameters(out io.Writer, parameters []Parameter) {
+	w := tabwriter.NewWriter(out, 0, 4, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "NAME\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, parameter := range parameters {
+		parameterType := parameter.Type
+		if parameterType == "" {
+			parameterType = ParameterTypeString
+		}
+		if parameterType == ParameterTypeEnum {
+			parameterType = fmt.Sprintf("enum(%s)", strings.Join(parameter.Values, "|"))
+		}
+		if parameter.Secret {
+			parameterType += ", secret"
+		}
+
+		value := "-"
+		if parameter.Value != nil {
+			value = fmt.Sprintf("%s (fixed)", *parameter.Value)
+		} else if defaultValue, ok := parameter.DefaultValue(); ok {
+			value = defaultValue
+		}
+		if parameter.Secret && value != "-" {
+			value = "****"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", parameter.Name, parameterType, value, parameter.Description)
+	}
+}
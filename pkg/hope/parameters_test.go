@@ -0,0 +1,134 @@
+package hope
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseParameter(t *testing.T) {
+	parameter := ParseParameter("NAME")
+	assert.Equal(t, Parameter{Name: "NAME"}, parameter)
+	assert.Equal(t, "NAME", parameter.String())
+
+	parameter = ParseParameter("NAME=a=b")
+	assert.Equal(t, "NAME", parameter.Name)
+	assert.Equal(t, "a=b", *parameter.Value)
+	assert.Equal(t, "NAME=a=b", parameter.String())
+
+	parameter = ParseParameter("NAME=")
+	assert.Equal(t, "", *parameter.Value)
+	assert.Equal(t, "NAME=", parameter.String())
+}
+
+func TestParameterValidate(t *testing.T) {
+	var tests = []struct {
+		name      string
+		parameter Parameter
+		value     string
+		err       string
+	}{
+		{"String", Parameter{Name: "P"}, "anything", ""},
+		{"Int", Parameter{Name: "P", Type: ParameterTypeInt}, "10", ""},
+		{"Bad Int", Parameter{Name: "P", Type: ParameterTypeInt}, "ten", `parameter P must be an integer, not "ten"`},
+		{"Bool", Parameter{Name: "P", Type: ParameterTypeBool}, "true", ""},
+		{"Bad Bool", Parameter{Name: "P", Type: ParameterTypeBool}, "yes", `parameter P must be true or false, not "yes"`},
+		{"Enum", Parameter{Name: "P", Type: ParameterTypeEnum, Values: []string{"a", "b"}}, "b", ""},
+		{"Bad Enum", Parameter{Name: "P", Type: ParameterTypeEnum, Values: []string{"a", "b"}}, "c", `parameter P must be one of a, b, not "c"`},
+		{"Enum Without Values", Parameter{Name: "P", Type: ParameterTypeEnum}, "a", "enum parameter P must list its values"},
+		{"Unknown Type", Parameter{Name: "P", Type: "float"}, "1.0", "parameter P has unknown type float"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parameter.Validate(tt.value)
+			if tt.err == "" {
+				assert.Nil(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestResolveParameters(t *testing.T) {
+	t.Setenv("FROM_ENV", "env")
+	t.Setenv("FIXED", "env")
+	t.Setenv("DEFAULTED", "env")
+	t.Setenv("BAD_INT", "ten")
+
+	parameters := []Parameter{
+		{Name: "FROM_ENV"},
+		ParseParameter("FIXED=fixed"),
+		{Name: "DEFAULTED", Default: "default"},
+		{Name: "COUNT", Type: ParameterTypeInt, Default: 3},
+		{Name: "ENABLED", Type: ParameterTypeBool, Default: true},
+		{Name: "GIVEN"},
+	}
+
+	resolved, err := ResolveParameters(parameters, []string{"GIVEN=given"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FROM_ENV", "FIXED=fixed", "DEFAULTED", "COUNT=3", "ENABLED=true", "GIVEN=given"}, resolved)
+
+	resolved, err = ResolveParameters(parameters, []string{"GIVEN=given", "FIXED=override", "COUNT=4"}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"FROM_ENV", "FIXED=override", "DEFAULTED", "COUNT=4", "ENABLED=true", "GIVEN=given"}, resolved)
+
+	_, err = ResolveParameters(parameters, []string{"UNKNOWN=1"}, nil)
+	assert.EqualError(t, err, "parameter: UNKNOWN not recognized")
+
+	_, err = ResolveParameters(parameters, []string{}, nil)
+	assert.EqualError(t, err, "failed to find GIVEN in environment")
+
+	_, err = ResolveParameters(parameters, []string{"GIVEN=given", "COUNT=four"}, nil)
+	assert.EqualError(t, err, `parameter COUNT must be an integer, not "four"`)
+
+	_, err = ResolveParameters([]Parameter{{Name: "BAD_INT", Type: ParameterTypeInt}}, []string{}, nil)
+	assert.EqualError(t, err, `parameter BAD_INT must be an integer, not "ten"`)
+}
+
+func TestResolveParametersPrompt(t *testing.T) {
+	prompted := []string{}
+	prompt := func(parameter Parameter) (string, error) {
+		prompted = append(prompted, parameter.Name)
+		if parameter.Name == "FAILS" {
+			return "", errors.New("no terminal")
+		}
+		return "prompted", nil
+	}
+
+	parameters := []Parameter{{Name: "GIVEN"}, {Name: "ASKED", Secret: true}, {Name: "DEFAULTED", Default: "default"}}
+	resolved, err := ResolveParameters(parameters, []string{"GIVEN=given"}, prompt)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"GIVEN=given", "ASKED=prompted", "DEFAULTED=default"}, resolved)
+	assert.Equal(t, []string{"ASKED"}, prompted)
+
+	_, err = ResolveParameters([]Parameter{{Name: "FAILS"}}, []string{}, prompt)
+	assert.EqualError(t, err, "no terminal")
+}
+
+func TestPrintParameters(t *testing.T) {
+	var out bytes.Buffer
+	PrintParameters(&out, []Parameter{
+		ParseParameter("HOST=example.com"),
+		{Name: "TOKEN", Secret: true, Description: "api token"},
+		{Name: "PASSWORD", Secret: true, Default: "hunter2"},
+		{Name: "COUNT", Type: ParameterTypeInt, Default: 10, Description: "how many"},
+		{Name: "MODE", Type: ParameterTypeEnum, Values: []string{"fast", "slow"}},
+	})
+
+	assert.Equal(t, strings.Join([]string{
+		"NAME       TYPE              DEFAULT               DESCRIPTION",
+		"HOST       string            example.com (fixed)   ",
+		"TOKEN      string, secret    -                     api token",
+		"PASSWORD   string, secret    ****                  ",
+		"COUNT      int               10                    how many",
+		"MODE       enum(fast|slow)   -                     ",
+		"",
+	}, "\n"), out.String())
+}
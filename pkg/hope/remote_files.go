@@ -0,0 +1,137 @@
+package hope
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// RemoteFileCacheDir - Directory downloaded remote files are kept in.
+// When empty, a directory inside the user's cache directory is used.
+var RemoteFileCacheDir string = ""
+
+// RemoteFileTimeout - How long downloading a remote file can take, from
+// connecting until its body has been read, before giving up on it.
+var RemoteFileTimeout time.Duration = 30 * time.Second
+
+type GetRemoteFileFunc func(url string) ([]byte, error)
+
+var GetRemoteFile GetRemoteFileFunc = func(url string) ([]byte, error) {
+	client := http.Client{Timeout: RemoteFileTimeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, response.Status)
+	}
+
+	return io.ReadAll(response.Body)
+}
+
+// IsRemoteFile - Whether the given file path is a URL kubectl would fetch,
+// rather than a path on the local machine.
+func IsRemoteFile(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// FetchRemoteFile - Get the contents of a remote file, keeping a copy in the
+// cache directory.
+// When a sha256 is given, a cached copy with that digest is used without
+// going to the network at all, and downloaded content that doesn't match it
+// is rejected, so upstream changes can't sneak into a deploy.
+// Without a sha256, the file is always downloaded, and the cached copy is
+// only used if the download fails.
+func FetchRemoteFile(url string, checksum string) ([]byte, error) {
+	checksum = strings.ToLower(checksum)
+
+	cachePath, err := remoteFileCachePath(url)
+	if err != nil {
+		return nil, err
+	}
+
+	cached, cacheErr := os.ReadFile(cachePath)
+	if cacheErr == nil && checksum != "" && sha256Hex(cached) == checksum {
+		log.Debug("Using cached copy of ", url)
+		return cached, nil
+	}
+
+	contents, err := GetRemoteFile(url)
+	if err != nil {
+		if cacheErr == nil && checksum == "" {
+			log.Warn("Failed to fetch ", url, "; using cached copy. ", err)
+			return cached, nil
+		}
+
+		return nil, err
+	}
+
+	if checksum != "" {
+		if actual := sha256Hex(contents); actual != checksum {
+			return nil, fmt.Errorf("remote file %s has changed; expected sha256 %s, got %s", url, checksum, actual)
+		}
+	}
+
+	if err := writeRemoteFileCache(cachePath, contents); err != nil {
+		log.Warn("Failed to cache ", url, ". ", err)
+	}
+
+	return contents, nil
+}
+
+func remoteFileCachePath(url string) (string, error) {
+	dir := RemoteFileCacheDir
+	if dir == "" {
+		userCacheDir, err := os.UserCacheDir()
+		if err != nil {
+			return "", err
+		}
+
+		dir = filepath.Join(userCacheDir, "hope", "remote-files")
+	}
+
+	return filepath.Join(dir, sha256Hex([]byte(url))), nil
+}
+
+// writeRemoteFileCache - Write through a temp file, so that a partially
+// written file is never mistaken for a cached copy.
+func writeRemoteFileCache(path string, contents []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tempFile.Write(contents); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), path)
+}
+
+func sha256Hex(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,137 @@
+package hope
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+const testRemoteFile string = "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: ${NAMESPACE}\n"
+const testRemoteFileSha256 string = "a9e9a2d2f2dbd3de9b8e6c837974547a83fd219913b82f5df392f1c570526c7a"
+
+func testRemoteFileServer(t *testing.T, contents *string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if contents == nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Write([]byte(*contents))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &requests
+}
+
+func setTestRemoteFileCacheDir(t *testing.T) {
+	original := RemoteFileCacheDir
+	RemoteFileCacheDir = t.TempDir()
+	t.Cleanup(func() {
+		RemoteFileCacheDir = original
+	})
+}
+
+func TestFetchRemoteFile(t *testing.T) {
+	setTestRemoteFileCacheDir(t)
+
+	contents := testRemoteFile
+	server, requests := testRemoteFileServer(t, &contents)
+	url := server.URL + "/namespace.yaml"
+
+	fetched, err := FetchRemoteFile(url, "")
+	assert.NoError(t, err)
+	assert.Equal(t, testRemoteFile, string(fetched))
+	assert.Equal(t, 1, *requests)
+
+	// Unpinned files are always downloaded again.
+	contents = "changed"
+	fetched, err = FetchRemoteFile(url, "")
+	assert.NoError(t, err)
+	assert.Equal(t, "changed", string(fetched))
+	assert.Equal(t, 2, *requests)
+}
+
+func TestFetchRemoteFileFallsBackToCache(t *testing.T) {
+	setTestRemoteFileCacheDir(t)
+
+	contents := testRemoteFile
+	server, _ := testRemoteFileServer(t, &contents)
+	url := server.URL + "/namespace.yaml"
+
+	_, err := FetchRemoteFile(url, "")
+	assert.NoError(t, err)
+
+	server.Close()
+	fetched, err := FetchRemoteFile(url, "")
+	assert.NoError(t, err)
+	assert.Equal(t, testRemoteFile, string(fetched))
+
+	_, err = FetchRemoteFile(server.URL+"/other.yaml", "")
+	assert.Error(t, err)
+}
+
+func TestGetRemoteFile(t *testing.T) {
+	server, _ := testRemoteFileServer(t, nil)
+
+	_, err := GetRemoteFile(server.URL + "/namespace.yaml")
+	assert.EqualError(t, err, "failed to fetch "+server.URL+"/namespace.yaml: 503 Service Unavailable")
+
+	original := RemoteFileTimeout
+	RemoteFileTimeout = 10 * time.Millisecond
+	defer func() { RemoteFileTimeout = original }()
+
+	block := make(chan bool)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-block
+	}))
+	defer slow.Close()
+	defer close(block)
+
+	_, err = GetRemoteFile(slow.URL + "/namespace.yaml")
+	assert.ErrorContains(t, err, "Client.Timeout exceeded")
+}
+
+func TestFetchRemoteFilePinned(t *testing.T) {
+	setTestRemoteFileCacheDir(t)
+
+	contents := testRemoteFile
+	server, requests := testRemoteFileServer(t, &contents)
+	url := server.URL + "/namespace.yaml"
+
+	fetched, err := FetchRemoteFile(url, testRemoteFileSha256)
+	assert.NoError(t, err)
+	assert.Equal(t, testRemoteFile, string(fetched))
+	assert.Equal(t, 1, *requests)
+
+	// A cached copy matching the digest doesn't need to be downloaded.
+	fetched, err = FetchRemoteFile(url, testRemoteFileSha256)
+	assert.NoError(t, err)
+	assert.Equal(t, testRemoteFile, string(fetched))
+	assert.Equal(t, 1, *requests)
+
+	_, err = FetchRemoteFile(server.URL+"/changed.yaml", testRemoteFileSha256[1:]+"0")
+	assert.Equal(t, "remote file "+server.URL+"/changed.yaml has changed; expected sha256 "+testRemoteFileSha256[1:]+"0, got "+testRemoteFileSha256, err.Error())
+}
+
+func TestResourceManifestsRemoteFile(t *testing.T) {
+	setTestRemoteFileCacheDir(t)
+
+	contents := testRemoteFile
+	server, _ := testRemoteFileServer(t, &contents)
+
+	resource := Resource{Name: "namespace", File: server.URL + "/namespace.yaml", Sha256: testRemoteFileSha256}
+	manifests, err := ResourceManifests(&resource, []string{"NAMESPACE=web"})
+	assert.NoError(t, err)
+	assert.Equal(t, "apiVersion: v1\nkind: Namespace\nmetadata:\n  name: web\n", manifests)
+
+	resource.File = "test/mysql.yaml"
+	_, err = ResourceManifests(&resource, []string{})
+	assert.Equal(t, "resource 'namespace' has a sha256, but its file isn't a URL", err.Error())
+}
@@ -1,6 +1,7 @@
 package hope
 
 import (
+	"bytes"
 	"errors"
 	"os"
 	"path"
@@ -8,19 +9,27 @@ import (
 
 import (
 	"github.com/sirupsen/logrus"
+	"k8s.io/client-go/tools/clientcmd"
 )
 
 import (
-	"github.com/Eagerod/hope/pkg/fileutil"
 	"github.com/Eagerod/hope/pkg/kubeutil"
 )
 
-func FetchKubeconfig(log *logrus.Entry, node *Node, merge bool) error {
-	kubeconfigFile, err := kubeutil.GetKubeConfigPath()
-	if err != nil {
-		return err
-	}
+// KubeconfigOptions - How to write the kubeconfig fetched from a master.
+// The kubeconfig's cluster, user, and context are all renamed to the
+// cluster name, if one is given, and its server is pointed at the server
+// host, if one is given.
+// With an output file, the kubeconfig is written there on its own, rather
+// than to the local kubeconfig.
+type KubeconfigOptions struct {
+	ClusterName string
+	ServerHost  string
+	Merge       bool
+	Output      string
+}
 
+func FetchKubeconfig(log *logrus.Entry, node *Node, options KubeconfigOptions) error {
 	connectionString := node.ConnectionString()
 	kubectl, err := kubeutil.NewKubectlFromNode(connectionString)
 	if err != nil {
@@ -29,46 +38,70 @@ func FetchKubeconfig(log *logrus.Entry, node *Node, merge bool) error {
 
 	defer kubectl.Destroy()
 
+	remoteConfig, err := clientcmd.LoadFromFile(kubectl.KubeconfigPath)
+	if err != nil {
+		return err
+	}
+
+	config, err := kubeutil.StandaloneKubeconfig(remoteConfig, options.ClusterName, options.ServerHost)
+	if err != nil {
+		return err
+	}
+
+	if options.Output != "" {
+		log.Debug("Writing kubeconfig from ", connectionString, " to ", options.Output)
+		return clientcmd.WriteToFile(*config, options.Output)
+	}
+
+	kubeconfigFile, err := kubeutil.GetKubeConfigPath()
+	if err != nil {
+		return err
+	}
+
 	// If the file already exists, and merge isn't provided, bail.
 	// TODO: Test if local kubeconfig path is actually pointing at multiple
 	//   files, and fail gracefully for that.
 	log.Trace("Local KUBECONFIG filepath: ", kubeconfigFile)
-	if _, err := os.Stat(kubeconfigFile); err == nil {
-		if same, _ := fileutil.FilesIdentical(kubeconfigFile, kubectl.KubeconfigPath); same {
-			log.Info("File pulled from remote identical to local file. Skipping overwrite.")
-			return nil
-		}
-
-		if !merge {
-			return errors.New("refusing to overwrite existing kubeconfig file")
-		}
-	} else if os.IsNotExist(err) {
+	if _, err := os.Stat(kubeconfigFile); os.IsNotExist(err) {
 		if err := os.MkdirAll(path.Dir(kubeconfigFile), 0700); err != nil {
 			return err
 		}
 
 		log.Debug("Local kubeconfig file does not exist. Writing new file.")
-		if err := fileutil.CopyFileMode(kubectl.KubeconfigPath, kubeconfigFile, 0600); err != nil {
-			return err
-		}
+		return clientcmd.WriteToFile(*config, kubeconfigFile)
+	} else if err != nil {
+		return err
+	}
 
-		return nil
-	} else {
+	existing, err := clientcmd.LoadFromFile(kubeconfigFile)
+	if err != nil {
 		return err
 	}
 
-	log.Debug("Merging existing KUBECONFIG file with file downloaded from ", connectionString)
+	merged, err := kubeutil.MergeKubeconfig(existing, config)
+	if err != nil {
+		return err
+	}
 
-	combinerKubeconfig := kubeutil.NewKubectl(kubeconfigFile + ":" + kubectl.KubeconfigPath)
-	kubeconfigContents, err := kubeutil.GetKubectl(combinerKubeconfig, "config", "view", "--raw")
+	existingContents, err := clientcmd.Write(*existing)
 	if err != nil {
 		return err
 	}
 
-	err = os.WriteFile(kubeconfigFile, []byte(kubeconfigContents), 0600)
+	mergedContents, err := clientcmd.Write(*merged)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	if bytes.Equal(existingContents, mergedContents) {
+		log.Info("Kubeconfig pulled from remote already present in local file. Skipping overwrite.")
+		return nil
+	}
+
+	if !options.Merge {
+		return errors.New("refusing to overwrite existing kubeconfig file")
+	}
+
+	log.Debug("Merging existing KUBECONFIG file with file downloaded from ", connectionString)
+	return clientcmd.WriteToFile(*merged, kubeconfigFile)
 }
@@ -0,0 +1,189 @@
+package hope
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// RemovalOptions - Controls what removing a resource deletes, beyond the
+// objects that make it up.
+// Namespaces and CRDs are kept by default, since deleting them takes
+// everything inside of them along, whether or not it was deployed by hope.
+type RemovalOptions struct {
+	KeepHistory bool
+	Namespaces  bool
+	CRDs        bool
+}
+
+// RemovalStep - A single deletion to perform while removing a resource.
+type RemovalStep struct {
+	Resource    string
+	Description string
+
+	remove func() error
+}
+
+// PlanResourceRemoval - Work out everything that removing the resource will
+// delete, without deleting any of it.
+// Objects are removed in the reverse of the order they're applied, with any
+// namespaces and CRDs going last.
+func PlanResourceRemoval(log *logrus.Entry, kubectl *kubeutil.Kubectl, resource *Resource, parameters []string, options RemovalOptions) ([]RemovalStep, error) {
+	resourceType, err := resource.GetType()
+	if err != nil {
+		return nil, err
+	}
+
+	switch resourceType {
+	case ResourceTypeFile, ResourceTypeInline, ResourceTypeKustomize:
+		manifests, err := ResourceManifests(resource, parameters)
+		if err != nil {
+			return nil, err
+		}
+
+		objects, err := ParseManifestObjects(manifests)
+		if err != nil {
+			return nil, err
+		}
+
+		slices.Reverse(objects)
+		return manifestRemovalSteps(log, kubectl, resource.Name, objects, options), nil
+	case ResourceTypeHelm:
+		return helmRemovalSteps(log, kubectl, resource, options)
+	case ResourceTypeDockerBuild:
+		log.Debug("Skipping removal of docker image.")
+	case ResourceTypeJob:
+		log.Debug("Skipping removal of job resource type.")
+	case ResourceTypeExec:
+		log.Debug("Skipping removal of exec resource type.")
+	case ResourceTypeMirror:
+		log.Debug("Skipping removal of mirrored images.")
+	default:
+		return nil, fmt.Errorf("resource type (%s) not implemented", resourceType)
+	}
+
+	return []RemovalStep{}, nil
+}
+
+// RemoveResources - Run every removal step, carrying on past failures so
+// that one broken resource doesn't leave everything after it in place.
+// Returns every failure that occurred.
+func RemoveResources(log *logrus.Entry, steps []RemovalStep) error {
+	errs := []error{}
+	for _, step := range steps {
+		log.Info("Deleting ", step.Description)
+		if err := step.remove(); err != nil {
+			log.Error("Failed to delete ", step.Description, ": ", err)
+			errs = append(errs, fmt.Errorf("%s: %s: %w", step.Resource, step.Description, err))
+		}
+	}
+
+	if len(errs) != 0 {
+		return fmt.Errorf("failed to remove %d of %d objects:\n%w", len(errs), len(steps), errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func manifestRemovalSteps(log *logrus.Entry, kubectl *kubeutil.Kubectl, resourceName string, objects []ManifestObject, options RemovalOptions) []RemovalStep {
+	steps := []RemovalStep{}
+	last := []RemovalStep{}
+	for _, object := range objects {
+		step := RemovalStep{resourceName, object.String(), func() error {
+			return DeleteManifestObject(kubectl, object)
+		}}
+
+		switch {
+		case isNamespace(object):
+			if !options.Namespaces {
+				log.Debug("Keeping ", object.String(), "; namespaces aren't being removed.")
+				continue
+			}
+			last = append(last, step)
+		case isCRD(object):
+			if !options.CRDs {
+				log.Debug("Keeping ", object.String(), "; CRDs aren't being removed.")
+				continue
+			}
+			last = append(last, step)
+		default:
+			steps = append(steps, step)
+		}
+	}
+
+	return append(steps, last...)
+}
+
+func helmRemovalSteps(log *logrus.Entry, kubectl *kubeutil.Kubectl, resource *Resource, options RemovalOptions) ([]RemovalStep, error) {
+	spec := resource.Helm
+	steps := []RemovalStep{}
+
+	description := fmt.Sprintf("helm release %s", spec.Release)
+	if len(spec.Namespace) != 0 {
+		description = fmt.Sprintf("helm release %s/%s", spec.Namespace, spec.Release)
+	}
+
+	if _, err := helm.ReleaseStatus(spec.Release, spec.Namespace); errors.Is(err, helm.ErrReleaseNotFound) {
+		log.Debug("Skipping ", description, "; release not found.")
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to find %s: %w", description, err)
+	} else {
+		steps = append(steps, RemovalStep{resource.Name, description, func() error {
+			allArgs := []string{"uninstall", spec.Release}
+			if len(spec.Namespace) != 0 {
+				allArgs = append(allArgs, "--namespace", spec.Namespace)
+			}
+
+			if options.KeepHistory {
+				allArgs = append(allArgs, "--keep-history")
+			}
+
+			return helm.ExecHelm(allArgs...)
+		}})
+	}
+
+	// Helm never removes the CRDs a chart installs, so they have to be found
+	//   from the chart itself.
+	if options.CRDs {
+		allArgs := []string{"show", "crds", spec.Chart}
+		if len(spec.Version) != 0 {
+			allArgs = append(allArgs, "--version", spec.Version)
+		}
+
+		crds, err := helm.GetHelm(allArgs...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find CRDs of chart %s: %w", spec.Chart, err)
+		}
+
+		objects, err := ParseManifestObjects(crds)
+		if err != nil {
+			return nil, err
+		}
+
+		steps = append(steps, manifestRemovalSteps(log, kubectl, resource.Name, objects, options)...)
+	}
+
+	if options.Namespaces && len(spec.Namespace) != 0 {
+		namespace := ManifestObject{"v1", "Namespace", spec.Namespace, ""}
+		steps = append(steps, manifestRemovalSteps(log, kubectl, resource.Name, []ManifestObject{namespace}, options)...)
+	}
+
+	return steps, nil
+}
+
+func isNamespace(object ManifestObject) bool {
+	return object.Group() == "" && object.Kind == "Namespace"
+}
+
+func isCRD(object ManifestObject) bool {
+	return object.Group() == "apiextensions.k8s.io" && object.Kind == "CustomResourceDefinition"
+}
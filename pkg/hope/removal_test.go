@@ -0,0 +1,218 @@
+package hope
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// Implemented as a suite to allow manipulating the helm wrapper funcs.
+type RemovalTestSuite struct {
+	suite.Suite
+
+	originalExecHelm helm.ExecHelmFunc
+	originalGetHelm  helm.GetHelmFunc
+
+	commands      []string
+	dynamicClient *dynamicfake.FakeDynamicClient
+	kubectl       *kubeutil.Kubectl
+}
+
+func (s *RemovalTestSuite) SetupTest() {
+	s.originalExecHelm = helm.ExecHelm
+	s.originalGetHelm = helm.GetHelm
+
+	s.commands = []string{}
+	helm.ExecHelm = func(args ...string) error {
+		s.commands = append(s.commands, strings.Join(args, " "))
+		return nil
+	}
+
+	helm.GetHelm = func(args ...string) (string, error) {
+		switch args[0] {
+		case "list":
+			return `[{"name": "widgets", "status": "deployed"}]`, nil
+		case "show":
+			return "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: widgets.example.com\n", nil
+		}
+
+		s.T().Fatalf("unexpected helm invocation: %s", strings.Join(args, " "))
+		return "", nil
+	}
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+	mapper.Add(schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}, meta.RESTScopeRoot)
+
+	s.dynamicClient = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	s.kubectl = kubeutil.NewKubectlWithClient(&kubeutil.Client{Dynamic: s.dynamicClient, Mapper: mapper})
+}
+
+func (s *RemovalTestSuite) TearDownTest() {
+	helm.ExecHelm = s.originalExecHelm
+	helm.GetHelm = s.originalGetHelm
+}
+
+// Actual test method to run the suite
+func TestRemoval(t *testing.T) {
+	suite.Run(t, new(RemovalTestSuite))
+}
+
+func stepDescriptions(steps []RemovalStep) []string {
+	descriptions := []string{}
+	for _, step := range steps {
+		descriptions = append(descriptions, step.Description)
+	}
+
+	return descriptions
+}
+
+func (s *RemovalTestSuite) TestPlanResourceRemovalManifests() {
+	t := s.T()
+
+	resource := Resource{
+		Name: "some-resource",
+		Inline: `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: web
+---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+  namespace: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: other-config
+  namespace: web
+`,
+	}
+
+	steps, err := PlanResourceRemoval(log.WithFields(log.Fields{}), s.kubectl, &resource, []string{}, RemovalOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ConfigMap web/other-config", "ConfigMap web/config"}, stepDescriptions(steps))
+
+	steps, err = PlanResourceRemoval(log.WithFields(log.Fields{}), s.kubectl, &resource, []string{}, RemovalOptions{Namespaces: true, CRDs: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"ConfigMap web/other-config",
+		"ConfigMap web/config",
+		"CustomResourceDefinition widgets.example.com",
+		"Namespace web",
+	}, stepDescriptions(steps))
+
+	assert.Nil(t, RemoveResources(log.WithFields(log.Fields{}), steps))
+
+	deleted := []string{}
+	for _, action := range s.dynamicClient.Actions() {
+		deleted = append(deleted, action.(k8stesting.DeleteAction).GetName())
+	}
+
+	assert.Equal(t, []string{"other-config", "config", "widgets.example.com", "web"}, deleted)
+}
+
+func (s *RemovalTestSuite) TestPlanResourceRemovalHelm() {
+	t := s.T()
+
+	resource := Resource{
+		Name: "some-release",
+		Helm: HelmSpec{
+			Namespace: "widgets",
+			Release:   "widgets",
+			Chart:     "oci://example.com/charts/widgets",
+			Version:   "1.0.0",
+		},
+	}
+
+	steps, err := PlanResourceRemoval(log.WithFields(log.Fields{}), s.kubectl, &resource, []string{}, RemovalOptions{KeepHistory: true, Namespaces: true, CRDs: true})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"helm release widgets/widgets",
+		"CustomResourceDefinition widgets.example.com",
+		"Namespace widgets",
+	}, stepDescriptions(steps))
+
+	assert.Nil(t, RemoveResources(log.WithFields(log.Fields{}), steps))
+	assert.Equal(t, []string{"uninstall widgets --namespace widgets --keep-history"}, s.commands)
+}
+
+func (s *RemovalTestSuite) TestPlanResourceRemovalMissingHelmRelease() {
+	t := s.T()
+
+	helm.GetHelm = func(args ...string) (string, error) {
+		return "[]", nil
+	}
+
+	resource := Resource{
+		Name: "some-release",
+		Helm: HelmSpec{Release: "widgets", Chart: "oci://example.com/charts/widgets"},
+	}
+
+	steps, err := PlanResourceRemoval(log.WithFields(log.Fields{}), s.kubectl, &resource, []string{}, RemovalOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []RemovalStep{}, steps)
+}
+
+func (s *RemovalTestSuite) TestPlanResourceRemovalHelmError() {
+	t := s.T()
+
+	helm.GetHelm = func(args ...string) (string, error) {
+		return "", errors.New("exit status 1")
+	}
+
+	resource := Resource{
+		Name: "some-release",
+		Helm: HelmSpec{Namespace: "widgets", Release: "widgets", Chart: "oci://example.com/charts/widgets"},
+	}
+
+	steps, err := PlanResourceRemoval(log.WithFields(log.Fields{}), s.kubectl, &resource, []string{}, RemovalOptions{Namespaces: true})
+	assert.EqualError(t, err, "failed to find helm release widgets/widgets: exit status 1")
+	assert.Nil(t, steps)
+}
+
+func (s *RemovalTestSuite) TestRemoveResourcesContinuesOnError() {
+	t := s.T()
+
+	removed := []string{}
+	step := func(name string, err error) RemovalStep {
+		return RemovalStep{"some-resource", name, func() error {
+			removed = append(removed, name)
+			return err
+		}}
+	}
+
+	err := RemoveResources(log.WithFields(log.Fields{}), []RemovalStep{
+		step("a", errors.New("boom")),
+		step("b", nil),
+		step("c", errors.New("bang")),
+	})
+
+	assert.Equal(t, []string{"a", "b", "c"}, removed)
+	assert.Equal(t, "failed to remove 2 of 3 objects:\nsome-resource: a: boom\nsome-resource: c: bang", err.Error())
+}
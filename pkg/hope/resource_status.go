@@ -0,0 +1,326 @@
+package hope
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+// How bad each status is when combining the statuses of several objects
+// into a single resource status; the worst one wins.
+var resourceStatusSeverity = map[ResourceStatus]int{
+	ResourceStatusNotApplicable: 0,
+	ResourceStatusHealthy:       1,
+	ResourceStatusUnknown:       2,
+	ResourceStatusProgressing:   3,
+	ResourceStatusMissing:       4,
+	ResourceStatusFailed:        5,
+}
+
+type objectCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// Only the fields needed to figure out whether any of the supported kinds
+// have finished rolling out.
+// Field names match the Kubernetes API's json keys case-insensitively.
+type rolloutObject struct {
+	Metadata struct {
+		Generation int64
+	}
+	Spec struct {
+		Replicas       *int32
+		UpdateStrategy struct {
+			Type string
+		}
+		Selector struct {
+			MatchLabels map[string]string
+		}
+	}
+	Status struct {
+		ObservedGeneration     int64
+		Replicas               int32
+		UpdatedReplicas        int32
+		ReadyReplicas          int32
+		AvailableReplicas      int32
+		CurrentRevision        string
+		UpdateRevision         string
+		DesiredNumberScheduled int32
+		UpdatedNumberScheduled int32
+		NumberAvailable        int32
+		Conditions             []objectCondition
+	}
+}
+
+func (o *rolloutObject) condition(conditionType string) (objectCondition, bool) {
+	for _, condition := range o.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition, true
+		}
+	}
+
+	return objectCondition{}, false
+}
+
+func (o *rolloutObject) desiredReplicas() int32 {
+	if o.Spec.Replicas == nil {
+		return 1
+	}
+
+	return *o.Spec.Replicas
+}
+
+// Build a label selector out of the object's spec.selector.matchLabels.
+// Returns an empty string if the object doesn't select pods with labels.
+func (o *rolloutObject) podSelector() string {
+	keys := []string{}
+	for key := range o.Spec.Selector.MatchLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	selectors := []string{}
+	for _, key := range keys {
+		selectors = append(selectors, fmt.Sprintf("%s=%s", key, o.Spec.Selector.MatchLabels[key]))
+	}
+
+	return strings.Join(selectors, ",")
+}
+
+// ObjectRolloutStatus - Given the json of an object that exists in the
+// cluster, determine whether it has finished rolling out.
+// Deployments, StatefulSets, DaemonSets, Jobs, and CustomResourceDefinitions
+// are inspected; anything else is healthy as long as it exists.
+func ObjectRolloutStatus(object ManifestObject, objectJson []byte) (ResourceStatusReport, error) {
+	var o rolloutObject
+	if err := json.Unmarshal(objectJson, &o); err != nil {
+		return ResourceStatusReport{}, err
+	}
+
+	progressing := func(format string, a ...interface{}) (ResourceStatusReport, error) {
+		return ResourceStatusReport{ResourceStatusProgressing, fmt.Sprintf(format, a...)}, nil
+	}
+
+	switch object.Kind {
+	case "Deployment", "StatefulSet", "DaemonSet":
+		if o.Status.ObservedGeneration < o.Metadata.Generation {
+			return progressing("waiting for update to be observed")
+		}
+	}
+
+	switch object.Kind {
+	case "Deployment":
+		if condition, ok := o.condition("Progressing"); ok && condition.Reason == "ProgressDeadlineExceeded" {
+			return ResourceStatusReport{ResourceStatusFailed, condition.Message}, nil
+		}
+
+		replicas := o.desiredReplicas()
+		if o.Status.UpdatedReplicas < replicas {
+			return progressing("%d of %d replicas updated", o.Status.UpdatedReplicas, replicas)
+		}
+		if o.Status.Replicas > o.Status.UpdatedReplicas {
+			return progressing("%d old replicas pending termination", o.Status.Replicas-o.Status.UpdatedReplicas)
+		}
+		if o.Status.AvailableReplicas < replicas {
+			return progressing("%d of %d replicas available", o.Status.AvailableReplicas, replicas)
+		}
+
+		return ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%d of %d replicas available", o.Status.AvailableReplicas, replicas)}, nil
+	case "StatefulSet":
+		replicas := o.desiredReplicas()
+		if o.Spec.UpdateStrategy.Type != "OnDelete" && o.Status.UpdateRevision != "" && o.Status.CurrentRevision != o.Status.UpdateRevision {
+			return progressing("%d of %d replicas updated", o.Status.UpdatedReplicas, replicas)
+		}
+		if o.Status.ReadyReplicas < replicas {
+			return progressing("%d of %d replicas ready", o.Status.ReadyReplicas, replicas)
+		}
+
+		return ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%d of %d replicas ready", o.Status.ReadyReplicas, replicas)}, nil
+	case "DaemonSet":
+		desired := o.Status.DesiredNumberScheduled
+		if o.Spec.UpdateStrategy.Type != "OnDelete" && o.Status.UpdatedNumberScheduled < desired {
+			return progressing("%d of %d pods updated", o.Status.UpdatedNumberScheduled, desired)
+		}
+		if o.Status.NumberAvailable < desired {
+			return progressing("%d of %d pods available", o.Status.NumberAvailable, desired)
+		}
+
+		return ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%d of %d pods available", o.Status.NumberAvailable, desired)}, nil
+	case "Job":
+		if condition, ok := o.condition("Failed"); ok && condition.Status == "True" {
+			return ResourceStatusReport{ResourceStatusFailed, condition.Message}, nil
+		}
+		if condition, ok := o.condition("Complete"); ok && condition.Status == "True" {
+			return ResourceStatusReport{ResourceStatusHealthy, "complete"}, nil
+		}
+
+		return progressing("running")
+	case "CustomResourceDefinition":
+		if condition, ok := o.condition("Established"); ok && condition.Status == "True" {
+			return ResourceStatusReport{ResourceStatusHealthy, "established"}, nil
+		}
+
+		return progressing("not established")
+	}
+
+	return ResourceStatusReport{ResourceStatusHealthy, "exists"}, nil
+}
+
+// Fetch the json of an object from the cluster.
+// Returns nil if the object doesn't exist.
+func getObjectJson(kubectl *kubeutil.Kubectl, object ManifestObject) ([]byte, error) {
+	existing, err := kubeutil.GetObject(kubectl, manifestObjectUnstructured(object))
+	if err != nil || existing == nil {
+		return nil, err
+	}
+
+	return existing.MarshalJSON()
+}
+
+// GetObjectStatus - Fetch an object from the cluster, and determine whether
+// it has finished rolling out.
+func GetObjectStatus(kubectl *kubeutil.Kubectl, object ManifestObject) (ResourceStatusReport, error) {
+	objectJson, err := getObjectJson(kubectl, object)
+	if err != nil {
+		return ResourceStatusReport{}, err
+	}
+
+	if objectJson == nil {
+		return ResourceStatusReport{ResourceStatusMissing, "not found"}, nil
+	}
+
+	return ObjectRolloutStatus(object, objectJson)
+}
+
+// GetResourceStatus - Determine whether a resource from the hope file has
+// been deployed, and whether it's healthy.
+// Failures to reach the cluster, or the other systems a resource depends on,
+// are reported as unknown statuses rather than errors, so that one resource
+// can't prevent the status of others from being reported.
+func GetResourceStatus(log *logrus.Entry, kubectl *kubeutil.Kubectl, resource *Resource, parameters []string) (ResourceStatusReport, error) {
+	resourceType, err := resource.GetType()
+	if err != nil {
+		return ResourceStatusReport{}, err
+	}
+
+	unknown := func(err error) (ResourceStatusReport, error) {
+		return ResourceStatusReport{ResourceStatusUnknown, err.Error()}, nil
+	}
+
+	switch resourceType {
+	case ResourceTypeFile, ResourceTypeInline, ResourceTypeKustomize:
+		manifests, err := ResourceManifests(resource, parameters)
+		if err != nil {
+			return unknown(err)
+		}
+
+		objects, err := ParseManifestObjects(manifests)
+		if err != nil {
+			return unknown(err)
+		}
+
+		return manifestObjectsStatus(log, kubectl, objects)
+	case ResourceTypeJob:
+		namespace, job := SplitNamespacedName(resource.Job)
+		result, err := kubeutil.GetJobResult(kubectl, namespace, job)
+		if apierrors.IsNotFound(err) {
+			return ResourceStatusReport{ResourceStatusMissing, "job not found"}, nil
+		} else if err != nil {
+			return unknown(err)
+		}
+
+		switch result {
+		case kubeutil.JobResultComplete:
+			return ResourceStatusReport{ResourceStatusHealthy, "complete"}, nil
+		case kubeutil.JobResultFailed:
+			return ResourceStatusReport{ResourceStatusFailed, "failed"}, nil
+		}
+
+		return ResourceStatusReport{ResourceStatusProgressing, "running"}, nil
+	case ResourceTypeHelm:
+		status, err := helm.ReleaseStatus(resource.Helm.Release, resource.Helm.Namespace)
+		if errors.Is(err, helm.ErrReleaseNotFound) {
+			return ResourceStatusReport{ResourceStatusMissing, "release not found"}, nil
+		} else if err != nil {
+			return unknown(err)
+		}
+
+		switch {
+		case status == "deployed":
+			return ResourceStatusReport{ResourceStatusHealthy, status}, nil
+		case status == "failed":
+			return ResourceStatusReport{ResourceStatusFailed, status}, nil
+		case strings.HasPrefix(status, "pending-"):
+			return ResourceStatusReport{ResourceStatusProgressing, status}, nil
+		}
+
+		return ResourceStatusReport{ResourceStatusUnknown, status}, nil
+	case ResourceTypeDockerBuild:
+		digest, exists, err := registry.NewClient().ManifestDigest(context.Background(), resource.Build.Tag)
+		if err != nil {
+			return unknown(err)
+		}
+
+		if !exists {
+			return ResourceStatusReport{ResourceStatusMissing, fmt.Sprintf("%s not found in registry", resource.Build.Tag)}, nil
+		}
+
+		return ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%s pushed (%s)", resource.Build.Tag, digest)}, nil
+	case ResourceTypeExec:
+		return ResourceStatusReport{ResourceStatusNotApplicable, ""}, nil
+	case ResourceTypeMirror:
+		return MirrorStatus(resource)
+	}
+
+	return ResourceStatusReport{}, fmt.Errorf("resource type (%s) not implemented", resourceType)
+}
+
+// Combine the statuses of every object into the status of the worst one,
+// noting how many others weren't healthy either.
+func manifestObjectsStatus(log *logrus.Entry, kubectl *kubeutil.Kubectl, objects []ManifestObject) (ResourceStatusReport, error) {
+	if len(objects) == 0 {
+		return ResourceStatusReport{ResourceStatusUnknown, "no objects found in manifests"}, nil
+	}
+
+	worst := ResourceStatusReport{ResourceStatusHealthy, fmt.Sprintf("%d objects", len(objects))}
+	unhealthy := 0
+	for _, object := range objects {
+		report, err := GetObjectStatus(kubectl, object)
+		if err != nil {
+			report = ResourceStatusReport{ResourceStatusUnknown, err.Error()}
+		}
+
+		log.Tracef("%s: %s (%s)", object, report.Status, report.Detail)
+		if report.Status == ResourceStatusHealthy {
+			continue
+		}
+
+		unhealthy++
+		if resourceStatusSeverity[report.Status] > resourceStatusSeverity[worst.Status] {
+			worst = ResourceStatusReport{report.Status, fmt.Sprintf("%s: %s", object, report.Detail)}
+		}
+	}
+
+	if unhealthy > 1 {
+		worst.Detail = fmt.Sprintf("%s (and %d more)", worst.Detail, unhealthy-1)
+	}
+
+	return worst, nil
+}
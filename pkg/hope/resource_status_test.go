@@ -0,0 +1,192 @@
+package hope
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/helm"
+	"github.com/Eagerod/hope/pkg/kubeutil"
+	"github.com/Eagerod/hope/pkg/registry"
+)
+
+func TestObjectRolloutStatus(t *testing.T) {
+	var tests = []struct {
+		name   string
+		kind   string
+		json   string
+		status ResourceStatus
+		detail string
+	}{
+		{
+			"Deployment Available",
+			"Deployment",
+			`{"metadata":{"generation":2},"spec":{"replicas":3},"status":{"observedGeneration":2,"replicas":3,"updatedReplicas":3,"availableReplicas":3}}`,
+			ResourceStatusHealthy,
+			"3 of 3 replicas available",
+		},
+		{
+			"Deployment Default Replicas",
+			"Deployment",
+			`{"metadata":{"generation":1},"spec":{},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1,"availableReplicas":1}}`,
+			ResourceStatusHealthy,
+			"1 of 1 replicas available",
+		},
+		{
+			"Deployment Not Observed",
+			"Deployment",
+			`{"metadata":{"generation":3},"spec":{"replicas":3},"status":{"observedGeneration":2,"replicas":3,"updatedReplicas":3,"availableReplicas":3}}`,
+			ResourceStatusProgressing,
+			"waiting for update to be observed",
+		},
+		{
+			"Deployment Old Replicas",
+			"Deployment",
+			`{"metadata":{"generation":1},"spec":{"replicas":2},"status":{"observedGeneration":1,"replicas":3,"updatedReplicas":2,"availableReplicas":2}}`,
+			ResourceStatusProgressing,
+			"1 old replicas pending termination",
+		},
+		{
+			"Deployment Deadline Exceeded",
+			"Deployment",
+			`{"metadata":{"generation":1},"spec":{"replicas":1},"status":{"observedGeneration":1,"conditions":[{"type":"Progressing","status":"False","reason":"ProgressDeadlineExceeded","message":"ReplicaSet \"a\" has timed out progressing."}]}}`,
+			ResourceStatusFailed,
+			"ReplicaSet \"a\" has timed out progressing.",
+		},
+		{
+			"StatefulSet Updating",
+			"StatefulSet",
+			`{"metadata":{"generation":1},"spec":{"replicas":2},"status":{"observedGeneration":1,"readyReplicas":2,"updatedReplicas":1,"currentRevision":"a","updateRevision":"b"}}`,
+			ResourceStatusProgressing,
+			"1 of 2 replicas updated",
+		},
+		{
+			"StatefulSet Ready",
+			"StatefulSet",
+			`{"metadata":{"generation":1},"spec":{"replicas":2},"status":{"observedGeneration":1,"readyReplicas":2,"currentRevision":"b","updateRevision":"b"}}`,
+			ResourceStatusHealthy,
+			"2 of 2 replicas ready",
+		},
+		{
+			"DaemonSet Unavailable",
+			"DaemonSet",
+			`{"metadata":{"generation":1},"status":{"observedGeneration":1,"desiredNumberScheduled":4,"updatedNumberScheduled":4,"numberAvailable":3}}`,
+			ResourceStatusProgressing,
+			"3 of 4 pods available",
+		},
+		{
+			"Job Failed",
+			"Job",
+			`{"status":{"conditions":[{"type":"Failed","status":"True","message":"BackoffLimitExceeded"}]}}`,
+			ResourceStatusFailed,
+			"BackoffLimitExceeded",
+		},
+		{
+			"CRD Established",
+			"CustomResourceDefinition",
+			`{"status":{"conditions":[{"type":"Established","status":"True"}]}}`,
+			ResourceStatusHealthy,
+			"established",
+		},
+		{
+			"ConfigMap",
+			"ConfigMap",
+			`{"data":{}}`,
+			ResourceStatusHealthy,
+			"exists",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := ObjectRolloutStatus(ManifestObject{Kind: tt.kind}, []byte(tt.json))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.status, report.Status)
+			assert.Equal(t, tt.detail, report.Detail)
+		})
+	}
+}
+
+func TestSplitNamespacedName(t *testing.T) {
+	namespace, name := SplitNamespacedName("dev/some-job")
+	assert.Equal(t, "dev", namespace)
+	assert.Equal(t, "some-job", name)
+
+	namespace, name = SplitNamespacedName("some-job")
+	assert.Equal(t, "default", namespace)
+	assert.Equal(t, "some-job", name)
+}
+
+func TestGetResourceStatusRegistryError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	registry.InsecureRegistries = []string{host}
+	defer func() { registry.InsecureRegistries = []string{} }()
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	resource := &Resource{Name: "image", Build: BuildSpec{Path: "a", Tag: host + "/a"}}
+	report, err := GetResourceStatus(logrus.NewEntry(logrus.New()), nil, resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusUnknown, report.Status)
+	assert.NotEmpty(t, report.Detail)
+}
+
+func TestGetResourceStatusHelm(t *testing.T) {
+	originalGetHelm := helm.GetHelm
+	defer func() { helm.GetHelm = originalGetHelm }()
+
+	resource := &Resource{Name: "release", Helm: HelmSpec{Release: "widgets", Chart: "oci://example.com/charts/widgets"}}
+
+	helm.GetHelm = func(args ...string) (string, error) {
+		return "[]", nil
+	}
+
+	report, err := GetResourceStatus(logrus.NewEntry(logrus.New()), nil, resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusReport{ResourceStatusMissing, "release not found"}, report)
+
+	helm.GetHelm = func(args ...string) (string, error) {
+		return "", errors.New("exit status 1")
+	}
+
+	report, err = GetResourceStatus(logrus.NewEntry(logrus.New()), nil, resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusReport{ResourceStatusUnknown, "exit status 1"}, report)
+}
+
+func TestGetResourceStatusJob(t *testing.T) {
+	resource := &Resource{Name: "job", Job: "dev/some-job"}
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset()})
+	report, err := GetResourceStatus(logrus.NewEntry(logrus.New()), kubectl, resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusReport{ResourceStatusMissing, "job not found"}, report)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "some-job", Namespace: "dev"},
+		Status: batchv1.JobStatus{
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		},
+	}
+
+	kubectl = kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset(job)})
+	report, err = GetResourceStatus(logrus.NewEntry(logrus.New()), kubectl, resource, []string{})
+	assert.Nil(t, err)
+	assert.Equal(t, ResourceStatusReport{ResourceStatusHealthy, "complete"}, report)
+}
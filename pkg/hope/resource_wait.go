@@ -0,0 +1,125 @@
+package hope
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// How long to wait between checks on objects that haven't finished rolling
+// out.
+var WaitPollInterval time.Duration = 2 * time.Second
+
+// WaitForObjects - Block until every object has finished rolling out, any
+// object has failed, or the timeout has passed.
+// When objects don't become healthy, the events of the pods they manage are
+// printed, to help find out why.
+func WaitForObjects(log *logrus.Entry, kubectl *kubeutil.Kubectl, objects []ManifestObject, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	pending := objects
+	lastDetails := map[ManifestObject]string{}
+
+	for {
+		stillPending := []ManifestObject{}
+		failed := []ManifestObject{}
+		reports := map[ManifestObject]ResourceStatusReport{}
+
+		for _, object := range pending {
+			report, err := GetObjectStatus(kubectl, object)
+			if err != nil {
+				log.Warn(err)
+				report = ResourceStatusReport{ResourceStatusUnknown, err.Error()}
+			}
+			reports[object] = report
+
+			switch report.Status {
+			case ResourceStatusHealthy:
+				log.Debugf("%s: %s", object, report.Detail)
+				continue
+			case ResourceStatusFailed:
+				failed = append(failed, object)
+			default:
+				stillPending = append(stillPending, object)
+			}
+
+			if lastDetails[object] != report.Detail {
+				log.Infof("Waiting for %s: %s", object, report.Detail)
+				lastDetails[object] = report.Detail
+			}
+		}
+
+		if len(failed) != 0 {
+			return objectWaitFailure(log, kubectl, failed, reports, "failed")
+		}
+
+		if len(stillPending) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return objectWaitFailure(log, kubectl, stillPending, reports, fmt.Sprintf("did not become ready within %s", timeout))
+		}
+
+		pending = stillPending
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// Log out each object that failed, along with the events of the pods it
+// manages, and build an error out of them.
+func objectWaitFailure(log *logrus.Entry, kubectl *kubeutil.Kubectl, objects []ManifestObject, reports map[ManifestObject]ResourceStatusReport, reason string) error {
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.String())
+		log.Errorf("%s %s: %s", object, reason, reports[object].Detail)
+
+		selector := objectPodSelector(kubectl, object)
+		if selector == "" {
+			continue
+		}
+
+		pods, err := GetPodsForSelector(kubectl, object.Namespace, selector)
+		if err != nil {
+			log.Warn(err)
+			continue
+		}
+
+		log.Infof("Events for pods of %s:", object)
+		PrintPodEvents(kubectl, object.Namespace, *pods)
+	}
+
+	return fmt.Errorf("%s %s", strings.Join(names, ", "), reason)
+}
+
+// Find the label selector of the pods managed by the given object, if it
+// manages any.
+func objectPodSelector(kubectl *kubeutil.Kubectl, object ManifestObject) string {
+	switch object.Kind {
+	case "Job":
+		return fmt.Sprintf("job-name=%s", object.Name)
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet":
+	default:
+		return ""
+	}
+
+	objectJson, err := getObjectJson(kubectl, object)
+	if err != nil || objectJson == nil {
+		return ""
+	}
+
+	var o rolloutObject
+	if err := json.Unmarshal(objectJson, &o); err != nil {
+		return ""
+	}
+
+	return o.podSelector()
+}
@@ -0,0 +1,121 @@
+package hope
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+import (
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// Implemented as a suite to allow manipulating the poll interval
+type ResourceWaitTestSuite struct {
+	suite.Suite
+
+	originalInterval time.Duration
+	mapper           meta.RESTMapper
+}
+
+func (s *ResourceWaitTestSuite) SetupTest() {
+	s.originalInterval = WaitPollInterval
+
+	WaitPollInterval = time.Millisecond
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, meta.RESTScopeNamespace)
+	s.mapper = mapper
+}
+
+func (s *ResourceWaitTestSuite) TearDownTest() {
+	WaitPollInterval = s.originalInterval
+}
+
+// Actual test method to run the suite
+func TestResourceWait(t *testing.T) {
+	suite.Run(t, new(ResourceWaitTestSuite))
+}
+
+// testClusterObject - The object as the cluster would return it, with the
+// given json merged over its identity.
+func testClusterObject(t *testing.T, object ManifestObject, objectJson string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	assert.NoError(t, json.Unmarshal([]byte(objectJson), &u.Object))
+	u.SetAPIVersion(object.APIVersion)
+	u.SetKind(object.Kind)
+	u.SetName(object.Name)
+	u.SetNamespace(object.Namespace)
+	return u
+}
+
+var testWaitObjects []ManifestObject = []ManifestObject{
+	{"v1", "ConfigMap", "config", ""},
+	{"apps/v1", "Deployment", "mysql", "database"},
+}
+
+func (s *ResourceWaitTestSuite) TestWaitForObjects() {
+	t := s.T()
+
+	config := ManifestObject{"v1", "ConfigMap", "config", "default"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), testClusterObject(t, config, `{"data":{}}`))
+
+	polls := 0
+	dynamicClient.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		assert.Equal(t, "database", action.GetNamespace())
+		assert.Equal(t, "mysql", action.(k8stesting.GetAction).GetName())
+
+		polls++
+		if polls < 3 {
+			return true, testClusterObject(t, testWaitObjects[1], `{"metadata":{"generation":1},"spec":{"replicas":1},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1}}`), nil
+		}
+		return true, testClusterObject(t, testWaitObjects[1], `{"metadata":{"generation":1},"spec":{"replicas":1},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1,"availableReplicas":1}}`), nil
+	})
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Dynamic: dynamicClient, Mapper: s.mapper})
+	err := WaitForObjects(log.WithFields(log.Fields{}), kubectl, testWaitObjects, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, polls)
+}
+
+func (s *ResourceWaitTestSuite) TestWaitForObjectsTimeout() {
+	t := s.T()
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		testClusterObject(t, testWaitObjects[1], `{"metadata":{"generation":1},"spec":{"replicas":1,"selector":{"matchLabels":{"app":"mysql"}}},"status":{"observedGeneration":1,"replicas":1,"updatedReplicas":1}}`),
+	)
+
+	clientset := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "mysql-abc", Namespace: "database", Labels: map[string]string{"app": "mysql"}}},
+	)
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset, Dynamic: dynamicClient, Mapper: s.mapper})
+	err := WaitForObjects(log.WithFields(log.Fields{}), kubectl, testWaitObjects, time.Millisecond*10)
+	assert.Equal(t, "ConfigMap config, Deployment database/mysql did not become ready within 10ms", err.Error())
+
+	actions := []string{}
+	for _, action := range clientset.Actions() {
+		listAction := action.(k8stesting.ListAction)
+		restrictions := listAction.GetListRestrictions()
+		actions = append(actions, fmt.Sprintf("%s %s %s %s", action.GetNamespace(), action.GetResource().Resource, restrictions.Labels, restrictions.Fields))
+	}
+	assert.Equal(t, []string{"database pods app=mysql ", "database events  involvedObject.name=mysql-abc"}, actions)
+}
@@ -0,0 +1,82 @@
+package hope
+
+import (
+	"fmt"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// ServiceAccountTokenSecretTimeout - How long to wait for the cluster to
+// fill in a newly created service account token Secret.
+var ServiceAccountTokenSecretTimeout = 30 * time.Second
+
+// TokenOptions - How to get a service account's token.
+// Long-lived tokens come from a service account token Secret, which is
+// created if the service account doesn't have one; Duration and Audiences
+// only apply to short-lived tokens.
+type TokenOptions struct {
+	Duration  time.Duration
+	Audiences []string
+	LongLived bool
+}
+
+// ServiceAccountToken - Get a token for a service account.
+func ServiceAccountToken(log *logrus.Entry, kubectl *kubeutil.Kubectl, namespace, serviceAccount string, options TokenOptions) (string, error) {
+	if !options.LongLived {
+		token, expires, err := kubeutil.CreateServiceAccountToken(kubectl, namespace, serviceAccount, options.Duration, options.Audiences)
+		if err != nil {
+			return "", err
+		}
+
+		log.Debug("Token for ", namespace, "/", serviceAccount, " expires at ", expires)
+		return token, nil
+	}
+
+	token, err := kubeutil.GetServiceAccountTokenSecret(kubectl, namespace, serviceAccount)
+	if err != nil || token != "" {
+		return token, err
+	}
+
+	secretName, err := kubeutil.CreateServiceAccountTokenSecret(kubectl, namespace, serviceAccount)
+	if err != nil {
+		return "", err
+	}
+
+	log.Info("Created Secret ", namespace, "/", secretName, " to hold a long-lived token for ", serviceAccount)
+
+	deadline := time.Now().Add(ServiceAccountTokenSecretTimeout)
+	for {
+		token, err := kubeutil.GetServiceAccountTokenSecret(kubectl, namespace, serviceAccount)
+		if err != nil || token != "" {
+			return token, err
+		}
+
+		if !time.Now().Before(deadline) {
+			return "", fmt.Errorf("timed out waiting for a token to be added to Secret %s/%s", namespace, secretName)
+		}
+
+		log.Debug("Waiting for a token to be added to ", namespace, "/", secretName, "...")
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// ServiceAccountKubeconfig - Build a kubeconfig that connects to the
+// cluster as a service account, defaulting to the service account's
+// namespace.
+func ServiceAccountKubeconfig(log *logrus.Entry, kubectl *kubeutil.Kubectl, cluster *kubeutil.KubeconfigCluster, namespace, serviceAccount string, options TokenOptions) ([]byte, error) {
+	token, err := ServiceAccountToken(log, kubectl, namespace, serviceAccount, options)
+	if err != nil {
+		return nil, err
+	}
+
+	authInfo := &clientcmdapi.AuthInfo{Token: token}
+	return kubeutil.NewKubeconfig(cluster, serviceAccount, authInfo, namespace)
+}
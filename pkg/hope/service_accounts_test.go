@@ -0,0 +1,122 @@
+package hope
+
+import (
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+func testServiceAccountClientset(objects ...runtime.Object) *fake.Clientset {
+	serviceAccount := &corev1.ServiceAccount{ObjectMeta: metav1.ObjectMeta{Name: "ci", Namespace: "kube-system"}}
+	clientset := fake.NewSimpleClientset(append(objects, serviceAccount)...)
+	clientset.PrependReactor("create", "serviceaccounts", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		request := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenRequest)
+		token := "short-lived"
+		for _, audience := range request.Spec.Audiences {
+			token += "-" + audience
+		}
+		if request.Spec.ExpirationSeconds != nil {
+			token += "-" + time.Duration(*request.Spec.ExpirationSeconds*int64(time.Second)).String()
+		}
+
+		request.Status.Token = token
+		return true, request, nil
+	})
+
+	return clientset
+}
+
+func testServiceAccountTokenSecret(name, serviceAccount, token string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "kube-system",
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+		Data: map[string][]byte{corev1.ServiceAccountTokenKey: []byte(token)},
+	}
+}
+
+func TestServiceAccountToken(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: testServiceAccountClientset(
+		testServiceAccountTokenSecret("other-token", "other", "other's token"),
+		testServiceAccountTokenSecret("ci-token", "ci", "long-lived"),
+	)})
+
+	var tests = []struct {
+		name    string
+		options TokenOptions
+		token   string
+	}{
+		{"Short Lived", TokenOptions{}, "short-lived"},
+		{"Duration And Audiences", TokenOptions{Duration: time.Hour, Audiences: []string{"vault", "ci"}}, "short-lived-vault-ci-1h0m0s"},
+		{"Long Lived", TokenOptions{Duration: time.Hour, LongLived: true}, "long-lived"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := ServiceAccountToken(log, kubectl, "kube-system", "ci", tt.options)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.token, token)
+		})
+	}
+}
+
+func TestServiceAccountTokenCreatesSecret(t *testing.T) {
+	clientset := testServiceAccountClientset()
+	clientset.PrependReactor("create", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		secret := action.(k8stesting.CreateAction).GetObject().(*corev1.Secret)
+		secret.Data = map[string][]byte{corev1.ServiceAccountTokenKey: []byte("created")}
+		return false, nil, nil
+	})
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset})
+	token, err := ServiceAccountToken(logrus.NewEntry(logrus.New()), kubectl, "kube-system", "ci", TokenOptions{LongLived: true})
+	assert.Nil(t, err)
+	assert.Equal(t, "created", token)
+
+	_, err = ServiceAccountToken(logrus.NewEntry(logrus.New()), kubectl, "kube-system", "missing", TokenOptions{LongLived: true})
+	assert.Equal(t, `serviceaccounts "missing" not found`, err.Error())
+}
+
+func TestServiceAccountTokenSecretTimeout(t *testing.T) {
+	originalTimeout := ServiceAccountTokenSecretTimeout
+	ServiceAccountTokenSecretTimeout = 0
+	defer func() { ServiceAccountTokenSecretTimeout = originalTimeout }()
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: testServiceAccountClientset()})
+	_, err := ServiceAccountToken(logrus.NewEntry(logrus.New()), kubectl, "kube-system", "ci", TokenOptions{LongLived: true})
+	assert.Equal(t, "timed out waiting for a token to be added to Secret kube-system/ci-token", err.Error())
+}
+
+func TestServiceAccountKubeconfig(t *testing.T) {
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: testServiceAccountClientset()})
+	cluster := &kubeutil.KubeconfigCluster{Name: "kubernetes", Server: "https://api.example.com:6443", CertificateAuthorityData: []byte("ca")}
+
+	kubeconfig, err := ServiceAccountKubeconfig(logrus.NewEntry(logrus.New()), kubectl, cluster, "kube-system", "ci", TokenOptions{})
+	assert.Nil(t, err)
+
+	config, err := clientcmd.Load(kubeconfig)
+	assert.Nil(t, err)
+	assert.Equal(t, "ci@kubernetes", config.CurrentContext)
+	assert.Equal(t, "kube-system", config.Contexts["ci@kubernetes"].Namespace)
+	assert.Equal(t, "https://api.example.com:6443", config.Clusters["kubernetes"].Server)
+	assert.Equal(t, "short-lived", config.AuthInfos["ci"].Token)
+}
@@ -41,12 +41,36 @@ func ReplaceParametersInFileCopy(path string, parameters []string) (string, erro
 		return "", err
 	}
 
+	return writeTempFile(str)
+}
+
+// ReplaceParametersInStringCopy - Replace parameters in the provided string,
+// and write the result to a temp file.
+// Returns the temp path to the file, and the caller must clean up that file
+// itself, unless an error occurs.
+func ReplaceParametersInStringCopy(str string, parameters []string) (string, error) {
+	str, err := ReplaceParametersInString(str, parameters)
+	if err != nil {
+		return "", err
+	}
+
+	return writeTempFile(str)
+}
+
+func writeTempFile(contents string) (string, error) {
 	tf, err := os.CreateTemp("", "")
 	if err != nil {
 		return "", err
 	}
 
-	if _, err := tf.WriteString(str); err != nil {
+	if _, err := tf.WriteString(contents); err != nil {
+		tf.Close()
+		os.Remove(tf.Name())
+		return "", err
+	}
+
+	if err := tf.Close(); err != nil {
+		os.Remove(tf.Name())
 		return "", err
 	}
 
@@ -0,0 +1,206 @@
+package hope
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// UserLabel - Label put on the objects created for a user, holding the
+// user's name, so they can all be found when the user is revoked.
+const UserLabel = "hope.user"
+
+// UserCertificateTimeout - How long to wait for the certificate of an
+// approved certificate signing request to be issued.
+var UserCertificateTimeout = 30 * time.Second
+
+// UserOptions - What a user's certificate holds, and what the user is bound
+// to.
+// Groups are put in the certificate, so they can't be taken away until the
+// certificate expires; anything bound to them stays available to the user
+// after the user is revoked.
+// With a cluster role, a binding is made to it for the user, in the
+// namespace if one is given, otherwise across the cluster.
+type UserOptions struct {
+	Groups      []string
+	ClusterRole string
+	Namespace   string
+	Duration    time.Duration
+}
+
+// NewUserCertificateRequest - Generate a private key for a user, and a
+// certificate request for it naming the user and its groups, both PEM
+// encoded.
+func NewUserCertificateRequest(name string, groups []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.CertificateRequest{Subject: pkix.Name{CommonName: name, Organization: groups}}
+	csrBytes, err := x509.CreateCertificateRequest(rand.Reader, template, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes})
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})
+	return keyPEM, csrPEM, nil
+}
+
+// CreateUser - Issue a client certificate for a user, bind it to its
+// cluster role if it has one, and build a kubeconfig that connects to the
+// cluster with it.
+func CreateUser(log *logrus.Entry, kubectl *kubeutil.Kubectl, cluster *kubeutil.KubeconfigCluster, name string, options UserOptions) ([]byte, error) {
+	if name == "" || strings.HasPrefix(name, "system:") {
+		return nil, fmt.Errorf("invalid user name %q", name)
+	}
+
+	if options.Namespace != "" && options.ClusterRole == "" {
+		return nil, errors.New("a namespace can only be given with a cluster role")
+	}
+
+	keyPEM, csrPEM, err := NewUserCertificateRequest(name, options.Groups)
+	if err != nil {
+		return nil, err
+	}
+
+	csr := userCertificateSigningRequest(name, csrPEM, options.Duration)
+	if _, err := kubeutil.CreateCertificateSigningRequest(kubectl, csr); err != nil {
+		return nil, err
+	}
+
+	log.Debug("Created certificate signing request ", csr.Name)
+	if err := kubeutil.ApproveCertificateSigningRequest(kubectl, csr.Name, "HopeUserCreate", "Approved by hope user create"); err != nil {
+		return nil, err
+	}
+
+	certificate, err := waitForCertificate(log, kubectl, csr.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	if options.ClusterRole != "" {
+		if err := bindUser(log, kubectl, name, options); err != nil {
+			return nil, err
+		}
+	}
+
+	authInfo := &clientcmdapi.AuthInfo{ClientCertificateData: certificate, ClientKeyData: keyPEM}
+	return kubeutil.NewKubeconfig(cluster, name, authInfo, options.Namespace)
+}
+
+// RevokeUser - Delete the role bindings, and certificate signing requests,
+// created for a user.
+// Kubernetes can't revoke client certificates, so the user's certificates
+// stay valid until they expire, along with any access given to their groups.
+func RevokeUser(log *logrus.Entry, kubectl *kubeutil.Kubectl, name string) error {
+	selector := fmt.Sprintf("%s=%s", UserLabel, name)
+	bindings, err := kubeutil.DeleteRoleBindings(kubectl, selector)
+	for _, binding := range bindings {
+		log.Info("Deleted ", binding)
+	}
+	if err != nil {
+		return err
+	}
+
+	csrs, err := kubeutil.DeleteCertificateSigningRequests(kubectl, selector)
+	for _, csr := range csrs {
+		log.Info("Deleted CertificateSigningRequest/", csr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(bindings) == 0 && len(csrs) == 0 {
+		return fmt.Errorf("no role bindings or certificate signing requests found for user %s", name)
+	}
+
+	return nil
+}
+
+func userLabels(name string) map[string]string {
+	return map[string]string{UserLabel: name, ManagedByLabel: "hope"}
+}
+
+// userCertificateSigningRequest - A request for a client certificate for the
+// user, named uniquely, so that certificates can be issued again without
+// touching the ones issued before.
+func userCertificateSigningRequest(name string, csrPEM []byte, duration time.Duration) *certificatesv1.CertificateSigningRequest {
+	csr := &certificatesv1.CertificateSigningRequest{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   fmt.Sprintf("hope-user-%s-%s", name, utilrand.String(5)),
+			Labels: userLabels(name),
+		},
+		Spec: certificatesv1.CertificateSigningRequestSpec{
+			Request:    csrPEM,
+			SignerName: certificatesv1.KubeAPIServerClientSignerName,
+			Usages:     []certificatesv1.KeyUsage{certificatesv1.UsageDigitalSignature, certificatesv1.UsageClientAuth},
+		},
+	}
+
+	if duration != 0 {
+		seconds := int32(duration.Seconds())
+		csr.Spec.ExpirationSeconds = &seconds
+	}
+
+	return csr
+}
+
+func waitForCertificate(log *logrus.Entry, kubectl *kubeutil.Kubectl, csr string) ([]byte, error) {
+	deadline := time.Now().Add(UserCertificateTimeout)
+	for {
+		certificate, err := kubeutil.GetIssuedCertificate(kubectl, csr)
+		if err != nil || len(certificate) != 0 {
+			return certificate, err
+		}
+
+		if !time.Now().Before(deadline) {
+			return nil, fmt.Errorf("timed out waiting for a certificate to be issued for %s", csr)
+		}
+
+		log.Debug("Waiting for a certificate to be issued for ", csr, "...")
+		time.Sleep(WaitPollInterval)
+	}
+}
+
+// bindUser - Bind the user to its cluster role, in its namespace if it has
+// one.
+func bindUser(log *logrus.Entry, kubectl *kubeutil.Kubectl, name string, options UserOptions) error {
+	meta := metav1.ObjectMeta{Name: "hope-user-" + name, Namespace: options.Namespace, Labels: userLabels(name)}
+	subjects := []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: name}}
+	roleRef := rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: options.ClusterRole}
+
+	if options.Namespace == "" {
+		log.Info("Binding ", name, " to cluster role ", options.ClusterRole)
+		return kubeutil.CreateOrReplaceClusterRoleBinding(kubectl, &rbacv1.ClusterRoleBinding{ObjectMeta: meta, Subjects: subjects, RoleRef: roleRef})
+	}
+
+	log.Info("Binding ", name, " to cluster role ", options.ClusterRole, " in namespace ", options.Namespace)
+	return kubeutil.CreateOrReplaceRoleBinding(kubectl, &rbacv1.RoleBinding{ObjectMeta: meta, Subjects: subjects, RoleRef: roleRef})
+}
@@ -0,0 +1,134 @@
+package hope
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/kubeutil"
+)
+
+// Clientset that issues a fake certificate for each certificate signing
+// request as soon as it's approved.
+func testUsersClientset(objects ...runtime.Object) *fake.Clientset {
+	clientset := fake.NewSimpleClientset(objects...)
+	clientset.PrependReactor("update", "certificatesigningrequests", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "approval" {
+			csr := action.(k8stesting.UpdateAction).GetObject().(*certificatesv1.CertificateSigningRequest)
+			csr.Status.Certificate = []byte("certificate for " + csr.Name)
+		}
+		return false, nil, nil
+	})
+
+	return clientset
+}
+
+func TestNewUserCertificateRequest(t *testing.T) {
+	keyPEM, csrPEM, err := NewUserCertificateRequest("jane", []string{"developers", "oncall"})
+	assert.Nil(t, err)
+
+	keyBlock, _ := pem.Decode(keyPEM)
+	_, err = x509.ParseECPrivateKey(keyBlock.Bytes)
+	assert.Nil(t, err)
+
+	csrBlock, _ := pem.Decode(csrPEM)
+	request, err := x509.ParseCertificateRequest(csrBlock.Bytes)
+	assert.Nil(t, err)
+	assert.Equal(t, "jane", request.Subject.CommonName)
+	assert.ElementsMatch(t, []string{"developers", "oncall"}, request.Subject.Organization)
+}
+
+func TestCreateUser(t *testing.T) {
+	clientset := testUsersClientset()
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset})
+	cluster := &kubeutil.KubeconfigCluster{Name: "kubernetes", Server: "https://api.example.com:6443", CertificateAuthorityData: []byte("ca")}
+
+	options := UserOptions{Groups: []string{"developers"}, ClusterRole: "edit", Namespace: "dev", Duration: 24 * time.Hour}
+	kubeconfig, err := CreateUser(logrus.NewEntry(logrus.New()), kubectl, cluster, "jane", options)
+	assert.Nil(t, err)
+
+	csrs, err := clientset.CertificatesV1().CertificateSigningRequests().List(context.Background(), metav1.ListOptions{LabelSelector: "hope.user=jane"})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(csrs.Items))
+
+	csr := csrs.Items[0]
+	assert.Equal(t, certificatesv1.KubeAPIServerClientSignerName, csr.Spec.SignerName)
+	assert.Equal(t, int32(86400), *csr.Spec.ExpirationSeconds)
+	assert.Equal(t, certificatesv1.CertificateApproved, csr.Status.Conditions[0].Type)
+
+	binding, err := clientset.RbacV1().RoleBindings("dev").Get(context.Background(), "hope-user-jane", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, rbacv1.RoleRef{Kind: "ClusterRole", APIGroup: rbacv1.GroupName, Name: "edit"}, binding.RoleRef)
+	assert.Equal(t, []rbacv1.Subject{{Kind: rbacv1.UserKind, APIGroup: rbacv1.GroupName, Name: "jane"}}, binding.Subjects)
+
+	config, err := clientcmd.Load(kubeconfig)
+	assert.Nil(t, err)
+	assert.Equal(t, "jane@kubernetes", config.CurrentContext)
+	assert.Equal(t, "dev", config.Contexts["jane@kubernetes"].Namespace)
+	assert.Equal(t, "https://api.example.com:6443", config.Clusters["kubernetes"].Server)
+	assert.Equal(t, "certificate for "+csr.Name, string(config.AuthInfos["jane"].ClientCertificateData))
+	assert.Contains(t, string(config.AuthInfos["jane"].ClientKeyData), "EC PRIVATE KEY")
+}
+
+func TestCreateUserErrors(t *testing.T) {
+	log := logrus.NewEntry(logrus.New())
+	cluster := &kubeutil.KubeconfigCluster{Name: "kubernetes"}
+
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: testUsersClientset()})
+	_, err := CreateUser(log, kubectl, cluster, "system:admin", UserOptions{})
+	assert.Equal(t, `invalid user name "system:admin"`, err.Error())
+
+	_, err = CreateUser(log, kubectl, cluster, "jane", UserOptions{Namespace: "dev"})
+	assert.Equal(t, "a namespace can only be given with a cluster role", err.Error())
+
+	originalTimeout := UserCertificateTimeout
+	UserCertificateTimeout = 0
+	defer func() { UserCertificateTimeout = originalTimeout }()
+
+	kubectl = kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: fake.NewSimpleClientset()})
+	_, err = CreateUser(log, kubectl, cluster, "jane", UserOptions{})
+	assert.Regexp(t, "^timed out waiting for a certificate to be issued for hope-user-jane-", err.Error())
+}
+
+func TestRevokeUser(t *testing.T) {
+	labels := userLabels("jane")
+	clientset := fake.NewSimpleClientset(
+		&certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "hope-user-jane-abcde", Labels: labels}},
+		&certificatesv1.CertificateSigningRequest{ObjectMeta: metav1.ObjectMeta{Name: "hope-user-john-abcde", Labels: userLabels("john")}},
+		&rbacv1.ClusterRoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "hope-user-jane", Labels: labels}},
+		&rbacv1.RoleBinding{ObjectMeta: metav1.ObjectMeta{Name: "hope-user-jane", Namespace: "dev", Labels: labels}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev"}},
+	)
+	kubectl := kubeutil.NewKubectlWithClient(&kubeutil.Client{Clientset: clientset})
+
+	assert.Nil(t, RevokeUser(logrus.NewEntry(logrus.New()), kubectl, "jane"))
+
+	csrs, err := clientset.CertificatesV1().CertificateSigningRequests().List(context.Background(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(csrs.Items))
+	assert.Equal(t, "hope-user-john-abcde", csrs.Items[0].Name)
+
+	roleBindings, err := clientset.RbacV1().RoleBindings("dev").List(context.Background(), metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(roleBindings.Items))
+
+	err = RevokeUser(logrus.NewEntry(logrus.New()), kubectl, "jane")
+	assert.Equal(t, "no role bindings or certificate signing requests found for user jane", err.Error())
+}
@@ -0,0 +1,1247 @@
+package kubeutil
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+import (
+	authenticationv1 "k8s.io/api/authentication/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// Client - Typed access to the Kubernetes API of the cluster a Kubectl
+// instance's kubeconfig points at.
+// Config is only needed for streaming operations like pod exec, and may be
+// left empty when the clients are constructed by hand.
+type Client struct {
+	Clientset kubernetes.Interface
+	Dynamic   dynamic.Interface
+	Mapper    meta.RESTMapper
+	Config    *rest.Config
+}
+
+// JobResult - The terminal state of a job, if it has reached one.
+type JobResult int
+
+const (
+	JobResultRunning JobResult = iota
+	JobResultComplete
+	JobResultFailed
+)
+
+// NewClient - Build a client from the given kubeconfig path.
+// An empty path uses the standard kubeconfig loading rules.
+func NewClient(kubeconfigPath string) (*Client, error) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if kubeconfigPath != "" {
+		loadingRules = &clientcmd.ClientConfigLoadingRules{ExplicitPath: kubeconfigPath}
+	}
+
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(clientset.Discovery()))
+	return &Client{clientset, dynamicClient, mapper, config}, nil
+}
+
+// Client - Get the API client for this kubectl's cluster, creating it from
+// the kubeconfig the first time it's needed.
+func (kubectl *Kubectl) Client() (*Client, error) {
+	if kubectl.client != nil {
+		return kubectl.client, nil
+	}
+
+	client, err := NewClient(kubectl.KubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kubectl.client = client
+	return client, nil
+}
+
+// Get the name by which the cluster recognizes a given host.
+// The host must exactly match the node's name, or one of its InternalIP
+// addresses.
+func NodeNameFromHost(kubectl *Kubectl, host string) (string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", err
+	}
+
+	nodes, err := client.Clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	if len(nodes.Items) == 0 {
+		return "", errors.New("no nodes found in this cluster")
+	}
+
+	for _, node := range nodes.Items {
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeInternalIP && address.Address == host {
+				return node.Name, nil
+			}
+		}
+	}
+
+	for _, node := range nodes.Items {
+		if node.Name == host {
+			return node.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("host: %s not found in this cluster", host)
+}
+
+// Get the status of each condition the named node reports, keyed by the
+// condition type.
+func NodeConditions(kubectl *Kubectl, node string) (map[string]string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err := client.Clientset.CoreV1().Nodes().Get(context.Background(), node, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := map[string]string{}
+	for _, condition := range n.Status.Conditions {
+		conditions[string(condition.Type)] = string(condition.Status)
+	}
+
+	return conditions, nil
+}
+
+// ServerSideApply - Apply each object in a multi-document manifest using
+// server-side apply, taking ownership of conflicting fields.
+func ServerSideApply(kubectl *Kubectl, manifests string, fieldManager string) error {
+	objects, err := DecodeManifests(manifests)
+	if err != nil {
+		return err
+	}
+
+	return ApplyObjects(kubectl, objects, fieldManager)
+}
+
+// ApplyObjects - Apply each object using server-side apply, taking ownership
+// of conflicting fields.
+// Objects without a namespace are placed in the default namespace if their
+// kind is namespaced, and are updated to reflect that.
+func ApplyObjects(kubectl *Kubectl, objects []*unstructured.Unstructured, fieldManager string) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	for _, object := range objects {
+		if err := applyObject(client, object, fieldManager); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func applyObject(client *Client, object *unstructured.Unstructured, fieldManager string) error {
+	resource, err := objectResource(client, object)
+	if err != nil {
+		return err
+	}
+
+	data, err := object.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	force := true
+	options := metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+	if _, err := resource.Patch(context.Background(), object.GetName(), types.ApplyPatchType, data, options); err != nil {
+		return fmt.Errorf("failed to apply %s %s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	return nil
+}
+
+// objectResource - Find the API resource an object is served from, setting
+// the object's namespace to the default one if it needs one and has none.
+func objectResource(client *Client, object *unstructured.Unstructured) (dynamic.ResourceInterface, error) {
+	gvk := object.GroupVersionKind()
+	mapping, err := client.Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find resource for %s %s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	if mapping.Scope.Name() != meta.RESTScopeNameNamespace {
+		return client.Dynamic.Resource(mapping.Resource), nil
+	}
+
+	if object.GetNamespace() == "" {
+		object.SetNamespace(metav1.NamespaceDefault)
+	}
+
+	return client.Dynamic.Resource(mapping.Resource).Namespace(object.GetNamespace()), nil
+}
+
+// ListAppliedObjects - Find every object in the cluster matching the label
+// selector that was server-side applied by the given field manager.
+// Resource types that can't be both listed and deleted are skipped, as are
+// API groups that fail discovery.
+func ListAppliedObjects(kubectl *Kubectl, selector string, fieldManager string) ([]*unstructured.Unstructured, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resourceLists, err := discovery.ServerPreferredResources(client.Clientset.Discovery())
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	objects := []*unstructured.Unstructured{}
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, apiResource := range resourceList.APIResources {
+			if strings.Contains(apiResource.Name, "/") || apiResource.Name == "events" {
+				continue
+			}
+
+			verbs := sets.New(apiResource.Verbs...)
+			if !verbs.HasAll("list", "delete") {
+				continue
+			}
+
+			gvr := gv.WithResource(apiResource.Name)
+			list, err := client.Dynamic.Resource(gvr).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+			if err != nil {
+				return nil, fmt.Errorf("failed to list %s: %w", gvr.GroupResource(), err)
+			}
+
+			for i := range list.Items {
+				object := &list.Items[i]
+				if !appliedBy(object, fieldManager) {
+					continue
+				}
+
+				object.SetAPIVersion(resourceList.GroupVersion)
+				object.SetKind(apiResource.Kind)
+				objects = append(objects, object)
+			}
+		}
+	}
+
+	return objects, nil
+}
+
+// appliedBy - Whether the field manager has applied any of the object's
+// fields.
+// Labels get copied around by controllers (e.g. Endpoints get their
+// Service's labels), so a matching label alone doesn't mean an object was
+// applied by anyone in particular.
+func appliedBy(object *unstructured.Unstructured, fieldManager string) bool {
+	for _, entry := range object.GetManagedFields() {
+		if entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetObject - Fetch the object as it is in the cluster.
+// Returns nil if the object doesn't exist.
+func GetObject(kubectl *Kubectl, object *unstructured.Unstructured) (*unstructured.Unstructured, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := objectResource(client, object)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := resource.Get(context.Background(), object.GetName(), metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get %s %s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	return existing, nil
+}
+
+// DeleteObject - Delete the object, along with anything it owns.
+// Objects that don't exist are ignored.
+func DeleteObject(kubectl *Kubectl, object *unstructured.Unstructured) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	resource, err := objectResource(client, object)
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	err = resource.Delete(context.Background(), object.GetName(), options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete %s %s: %w", object.GetKind(), object.GetName(), err)
+	}
+
+	return nil
+}
+
+// DecodeManifests - Decode a multi-document YAML or JSON manifest into its
+// objects, expanding any lists.
+func DecodeManifests(manifests string) ([]*unstructured.Unstructured, error) {
+	objects := []*unstructured.Unstructured{}
+	decoder := utilyaml.NewYAMLOrJSONDecoder(strings.NewReader(manifests), 4096)
+	for {
+		object := &unstructured.Unstructured{}
+		if err := decoder.Decode(&object.Object); err != nil {
+			if errors.Is(err, io.EOF) {
+				return objects, nil
+			}
+
+			return nil, err
+		}
+
+		if len(object.Object) == 0 {
+			continue
+		}
+
+		if object.IsList() {
+			list, err := object.ToList()
+			if err != nil {
+				return nil, err
+			}
+
+			for i := range list.Items {
+				objects = append(objects, &list.Items[i])
+			}
+			continue
+		}
+
+		objects = append(objects, object)
+	}
+}
+
+// GetJobResult - Check whether the given job has finished.
+func GetJobResult(kubectl *Kubectl, namespace, job string) (JobResult, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return JobResultRunning, err
+	}
+
+	j, err := client.Clientset.BatchV1().Jobs(namespace).Get(context.Background(), job, metav1.GetOptions{})
+	if err != nil {
+		return JobResultRunning, err
+	}
+
+	return jobResult(j), nil
+}
+
+// WatchJob - Block until the given job completes or fails, or the context
+// is cancelled.
+func WatchJob(ctx context.Context, kubectl *Kubectl, namespace, job string) (JobResult, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return JobResultRunning, err
+	}
+
+	options := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("metadata.name", job).String()}
+	watcher, err := client.Clientset.BatchV1().Jobs(namespace).Watch(ctx, options)
+	if err != nil {
+		return JobResultRunning, err
+	}
+	defer watcher.Stop()
+
+	// The watch only reports changes, so a job that finished before it
+	//   started needs to be checked directly.
+	if result, err := GetJobResult(kubectl, namespace, job); err != nil {
+		return JobResultRunning, err
+	} else if result != JobResultRunning {
+		return result, nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return JobResultRunning, ctx.Err()
+		case event, ok := <-watcher.ResultChan():
+			if !ok {
+				return JobResultRunning, fmt.Errorf("watch of job %s/%s closed", namespace, job)
+			}
+
+			switch event.Type {
+			case watch.Deleted:
+				return JobResultRunning, fmt.Errorf("job %s/%s was deleted", namespace, job)
+			case watch.Error:
+				return JobResultRunning, fmt.Errorf("watch of job %s/%s failed: %v", namespace, job, event.Object)
+			}
+
+			j, ok := event.Object.(*batchv1.Job)
+			if !ok {
+				continue
+			}
+
+			if result := jobResult(j); result != JobResultRunning {
+				return result, nil
+			}
+		}
+	}
+}
+
+func jobResult(job *batchv1.Job) JobResult {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != corev1.ConditionTrue {
+			continue
+		}
+
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return JobResultComplete
+		case batchv1.JobFailed:
+			return JobResultFailed
+		}
+	}
+
+	return JobResultRunning
+}
+
+// CreateJob - Create the job, returning it as the cluster created it.
+// Jobs without a namespace are created in the default namespace.
+func CreateJob(kubectl *Kubectl, job *batchv1.Job) (*batchv1.Job, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Namespace == "" {
+		job = job.DeepCopy()
+		job.Namespace = metav1.NamespaceDefault
+	}
+
+	created, err := client.Clientset.BatchV1().Jobs(job.Namespace).Create(context.Background(), job, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job %s: %w", job.Name, err)
+	}
+
+	return created, nil
+}
+
+// DeleteJob - Delete the job, along with its pods.
+// Jobs that don't exist are ignored.
+func DeleteJob(kubectl *Kubectl, namespace, job string) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	propagation := metav1.DeletePropagationBackground
+	options := metav1.DeleteOptions{PropagationPolicy: &propagation}
+	err = client.Clientset.BatchV1().Jobs(namespace).Delete(context.Background(), job, options)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete job %s/%s: %w", namespace, job, err)
+	}
+
+	return nil
+}
+
+// JobSummary - The state of a job, as listed from the cluster.
+// Finished is zero while the job is still running.
+type JobSummary struct {
+	Namespace string
+	Name      string
+	Labels    map[string]string
+	Result    JobResult
+	Started   time.Time
+	Finished  time.Time
+}
+
+// ListJobs - Get the jobs matching the label selector, newest first.
+// An empty namespace lists jobs across all namespaces.
+func ListJobs(kubectl *Kubectl, namespace, selector string) ([]JobSummary, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs, err := client.Clientset.BatchV1().Jobs(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := []JobSummary{}
+	for _, job := range jobs.Items {
+		summary := JobSummary{job.Namespace, job.Name, job.Labels, jobResult(&job), job.CreationTimestamp.Time, time.Time{}}
+		if job.Status.StartTime != nil {
+			summary.Started = job.Status.StartTime.Time
+		}
+
+		if job.Status.CompletionTime != nil {
+			summary.Finished = job.Status.CompletionTime.Time
+		} else if summary.Result != JobResultRunning {
+			// Failed jobs never get a completion time, so the time they
+			//   were marked as finished is the closest thing.
+			for _, condition := range job.Status.Conditions {
+				if condition.Type == batchv1.JobFailed || condition.Type == batchv1.JobComplete {
+					summary.Finished = condition.LastTransitionTime.Time
+				}
+			}
+		}
+
+		summaries = append(summaries, summary)
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Started.After(summaries[j].Started)
+	})
+
+	return summaries, nil
+}
+
+// GetJobFailureMessage - Why the job failed, as reported by its Failed
+// condition; empty if the job hasn't failed.
+func GetJobFailureMessage(kubectl *Kubectl, namespace, job string) (string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", err
+	}
+
+	j, err := client.Clientset.BatchV1().Jobs(namespace).Get(context.Background(), job, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, condition := range j.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			if condition.Message == "" {
+				return condition.Reason, nil
+			}
+			return fmt.Sprintf("%s: %s", condition.Reason, condition.Message), nil
+		}
+	}
+
+	return "", nil
+}
+
+// JobPod - One of the pods a job created to run, or retry, its work.
+// Restarts counts container restarts within the pod, which is how retries
+// of jobs with an OnFailure restart policy show up.
+type JobPod struct {
+	Name     string
+	Phase    string
+	Restarts int
+	Created  time.Time
+}
+
+// GetJobPods - Get the pods of the job, oldest first.
+func GetJobPods(kubectl *Kubectl, namespace, job string) ([]JobPod, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	selector := fmt.Sprintf("job-name=%s", job)
+	pods, err := client.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	jobPods := []JobPod{}
+	for _, pod := range pods.Items {
+		restarts := 0
+		for _, status := range pod.Status.ContainerStatuses {
+			restarts += int(status.RestartCount)
+		}
+
+		jobPods = append(jobPods, JobPod{pod.Name, string(pod.Status.Phase), restarts, pod.CreationTimestamp.Time})
+	}
+
+	sort.SliceStable(jobPods, func(i, j int) bool {
+		if jobPods[i].Created.Equal(jobPods[j].Created) {
+			return jobPods[i].Name < jobPods[j].Name
+		}
+		return jobPods[i].Created.Before(jobPods[j].Created)
+	})
+
+	return jobPods, nil
+}
+
+// GetPodNames - Get the names of the pods in the namespace matching the
+// label selector.
+func GetPodNames(kubectl *Kubectl, namespace, selector string) ([]string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := client.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, pod := range pods.Items {
+		names = append(names, pod.Name)
+	}
+
+	return names, nil
+}
+
+// GetRunningPodNames - Get the names of the running pods in the namespace
+// matching the label selector, sorted by name.
+// Pods that are being deleted aren't included.
+func GetRunningPodNames(kubectl *Kubectl, namespace, selector string) ([]string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := client.Clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			names = append(names, pod.Name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// PodSelectorFor - Get the label selector of the pods managed by an object,
+// given as kind/name, the way kubectl refers to objects.
+// Deployments, StatefulSets, DaemonSets, ReplicaSets, Jobs, and Services are
+// supported.
+func PodSelectorFor(kubectl *Kubectl, namespace, object string) (string, error) {
+	kind, name, found := strings.Cut(object, "/")
+	if !found {
+		return "", fmt.Errorf("object %s must be given as kind/name", object)
+	}
+
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	var selector *metav1.LabelSelector
+	switch strings.ToLower(kind) {
+	case "deploy", "deployment", "deployments":
+		o, err := client.Clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		selector = o.Spec.Selector
+	case "sts", "statefulset", "statefulsets":
+		o, err := client.Clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		selector = o.Spec.Selector
+	case "ds", "daemonset", "daemonsets":
+		o, err := client.Clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		selector = o.Spec.Selector
+	case "rs", "replicaset", "replicasets":
+		o, err := client.Clientset.AppsV1().ReplicaSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		selector = o.Spec.Selector
+	case "job", "jobs":
+		o, err := client.Clientset.BatchV1().Jobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+		selector = o.Spec.Selector
+	case "svc", "service", "services":
+		o, err := client.Clientset.CoreV1().Services(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return "", err
+		}
+
+		if len(o.Spec.Selector) == 0 {
+			return "", fmt.Errorf("service %s has no selector", name)
+		}
+		return labels.SelectorFromSet(o.Spec.Selector).String(), nil
+	default:
+		return "", fmt.Errorf("can't find pods of kind %s", kind)
+	}
+
+	if selector == nil {
+		return "", fmt.Errorf("%s has no selector", object)
+	}
+
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return "", err
+	}
+
+	return s.String(), nil
+}
+
+// PodEvent - An event recorded against a pod.
+type PodEvent struct {
+	Type     string
+	Reason   string
+	Message  string
+	LastSeen time.Time
+}
+
+// GetPodEvents - Get the events recorded against the named pod, oldest
+// first.
+func GetPodEvents(kubectl *Kubectl, namespace, pod string) ([]PodEvent, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	options := metav1.ListOptions{FieldSelector: fields.OneTermEqualSelector("involvedObject.name", pod).String()}
+	events, err := client.Clientset.CoreV1().Events(namespace).List(context.Background(), options)
+	if err != nil {
+		return nil, err
+	}
+
+	podEvents := []PodEvent{}
+	for _, event := range events.Items {
+		lastSeen := event.LastTimestamp.Time
+		if lastSeen.IsZero() {
+			lastSeen = event.EventTime.Time
+		}
+
+		podEvents = append(podEvents, PodEvent{event.Type, event.Reason, event.Message, lastSeen})
+	}
+
+	sort.SliceStable(podEvents, func(i, j int) bool {
+		return podEvents[i].LastSeen.Before(podEvents[j].LastSeen)
+	})
+
+	return podEvents, nil
+}
+
+// StreamPodLogs - Copy the logs of a pod's container to the writer,
+// following them until the container exits if requested.
+// An empty container name uses the pod's only container.
+func StreamPodLogs(ctx context.Context, kubectl *Kubectl, namespace, pod, container string, follow bool, w io.Writer) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	options := corev1.PodLogOptions{Container: container, Follow: follow}
+	stream, err := client.Clientset.CoreV1().Pods(namespace).GetLogs(pod, &options).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(w, stream)
+	return err
+}
+
+// ExecOptions - The process to run inside a pod's container, and where its
+// standard streams go.
+// Stdin may be nil, and Stderr is ignored when a TTY is requested.
+type ExecOptions struct {
+	Container string
+	Command   []string
+	Stdin     io.Reader
+	Stdout    io.Writer
+	Stderr    io.Writer
+	TTY       bool
+}
+
+type ExecInPodFunc func(ctx context.Context, kubectl *Kubectl, namespace, pod string, options ExecOptions) error
+
+// ExecInPod - Run a command in a pod's container, returning an error if the
+// command exits unsuccessfully.
+// Commands that exit with a non-zero status return an exec.ExitError from
+// k8s.io/client-go/util/exec, holding that status.
+var ExecInPod ExecInPodFunc = func(ctx context.Context, kubectl *Kubectl, namespace, pod string, options ExecOptions) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	if client.Config == nil {
+		return errors.New("pod exec requires a client built from a kubeconfig")
+	}
+
+	restClient, err := rest.RESTClientFor(execRestConfig(client.Config))
+	if err != nil {
+		return err
+	}
+
+	req := restClient.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: options.Container,
+			Command:   options.Command,
+			Stdin:     options.Stdin != nil,
+			Stdout:    options.Stdout != nil,
+			Stderr:    options.Stderr != nil && !options.TTY,
+			TTY:       options.TTY,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(client.Config, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	streamOptions := remotecommand.StreamOptions{
+		Stdin:  options.Stdin,
+		Stdout: options.Stdout,
+		Tty:    options.TTY,
+	}
+	if !options.TTY {
+		streamOptions.Stderr = options.Stderr
+	}
+
+	return executor.StreamWithContext(ctx, streamOptions)
+}
+
+// GetExecInPod - Run a command in a pod's container, and return what it
+// wrote to stdout.
+func GetExecInPod(ctx context.Context, kubectl *Kubectl, namespace, pod, container string, command ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	options := ExecOptions{Container: container, Command: command, Stdout: &stdout, Stderr: &stderr}
+	if err := ExecInPod(ctx, kubectl, namespace, pod, options); err != nil {
+		return stdout.String(), fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.String(), nil
+}
+
+type PortForwardFunc func(ctx context.Context, kubectl *Kubectl, namespace, pod string, localPort, remotePort int, ready chan struct{}, errOut io.Writer) error
+
+// PortForward - Forward a port on localhost to a port of a pod, until the
+// context is done, or the connection to the pod is lost.
+// The ready channel is closed once the local port is listening.
+// Errors handling individual connections are written to errOut, and don't
+// stop the forward.
+var PortForward PortForwardFunc = func(ctx context.Context, kubectl *Kubectl, namespace, pod string, localPort, remotePort int, ready chan struct{}, errOut io.Writer) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	if client.Config == nil {
+		return errors.New("port forwarding requires a client built from a kubeconfig")
+	}
+
+	restClient, err := rest.RESTClientFor(execRestConfig(client.Config))
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(client.Config)
+	if err != nil {
+		return err
+	}
+
+	req := restClient.Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(pod).
+		SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			close(stop)
+		case <-done:
+		}
+	}()
+
+	ports := []string{fmt.Sprintf("%d:%d", localPort, remotePort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"localhost"}, ports, stop, ready, io.Discard, errOut)
+	if err != nil {
+		return err
+	}
+
+	return forwarder.ForwardPorts()
+}
+
+// ServiceTargetPort - Get the port of a pod that a port of a service sends
+// traffic to.
+// Named target ports are looked up in the pod's containers.
+func ServiceTargetPort(kubectl *Kubectl, namespace, service string, port int, pod string) (int, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return 0, err
+	}
+
+	ctx := context.Background()
+	svc, err := client.Clientset.CoreV1().Services(namespace).Get(ctx, service, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	var target *intstr.IntOrString
+	for _, servicePort := range svc.Spec.Ports {
+		if int(servicePort.Port) == port {
+			target = &servicePort.TargetPort
+			break
+		}
+	}
+
+	if target == nil {
+		return 0, fmt.Errorf("service %s has no port %d", service, port)
+	}
+
+	if target.Type == intstr.Int {
+		if target.IntVal == 0 {
+			return port, nil
+		}
+		return int(target.IntVal), nil
+	}
+
+	p, err := client.Clientset.CoreV1().Pods(namespace).Get(ctx, pod, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, container := range p.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == target.StrVal {
+				return int(containerPort.ContainerPort), nil
+			}
+		}
+	}
+
+	return 0, fmt.Errorf("pod %s has no port named %s", pod, target.StrVal)
+}
+
+func execRestConfig(config *rest.Config) *rest.Config {
+	execConfig := rest.CopyConfig(config)
+	execConfig.APIPath = "/api"
+	execConfig.GroupVersion = &corev1.SchemeGroupVersion
+	execConfig.NegotiatedSerializer = scheme.Codecs.WithoutConversion()
+	return execConfig
+}
+
+// CreateServiceAccountToken - Request a short-lived token for a service
+// account, returning it along with when it expires.
+// A zero duration leaves the token's lifetime up to the cluster.
+func CreateServiceAccountToken(kubectl *Kubectl, namespace, serviceAccount string, duration time.Duration, audiences []string) (string, time.Time, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	request := &authenticationv1.TokenRequest{Spec: authenticationv1.TokenRequestSpec{Audiences: audiences}}
+	if duration != 0 {
+		seconds := int64(duration.Seconds())
+		request.Spec.ExpirationSeconds = &seconds
+	}
+
+	response, err := client.Clientset.CoreV1().ServiceAccounts(namespace).CreateToken(context.Background(), serviceAccount, request, metav1.CreateOptions{})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return response.Status.Token, response.Status.ExpirationTimestamp.Time, nil
+}
+
+// GetServiceAccountTokenSecret - Get the token held by a long-lived service
+// account token Secret of the service account.
+// Returns an empty token if there's no such Secret, or if the cluster hasn't
+// populated it yet.
+func GetServiceAccountTokenSecret(kubectl *Kubectl, namespace, serviceAccount string) (string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", err
+	}
+
+	selector := fields.OneTermEqualSelector("type", string(corev1.SecretTypeServiceAccountToken)).String()
+	secrets, err := client.Clientset.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return "", err
+	}
+
+	// Secrets are sorted so that the same one is picked each time, if a
+	//   service account happens to have several.
+	sort.Slice(secrets.Items, func(i, j int) bool {
+		return secrets.Items[i].Name < secrets.Items[j].Name
+	})
+
+	for _, secret := range secrets.Items {
+		if secret.Type != corev1.SecretTypeServiceAccountToken || secret.Annotations[corev1.ServiceAccountNameKey] != serviceAccount {
+			continue
+		}
+
+		if token := secret.Data[corev1.ServiceAccountTokenKey]; len(token) != 0 {
+			return string(token), nil
+		}
+	}
+
+	return "", nil
+}
+
+// CreateServiceAccountTokenSecret - Create a Secret that the cluster fills in
+// with a long-lived token for the service account, returning its name.
+func CreateServiceAccountTokenSecret(kubectl *Kubectl, namespace, serviceAccount string) (string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	if _, err := client.Clientset.CoreV1().ServiceAccounts(namespace).Get(ctx, serviceAccount, metav1.GetOptions{}); err != nil {
+		return "", err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        serviceAccount + "-token",
+			Namespace:   namespace,
+			Annotations: map[string]string{corev1.ServiceAccountNameKey: serviceAccount},
+		},
+		Type: corev1.SecretTypeServiceAccountToken,
+	}
+
+	if _, err := client.Clientset.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		return "", err
+	}
+
+	return secret.Name, nil
+}
+
+// CreateCertificateSigningRequest - Create a CertificateSigningRequest,
+// returning it as the cluster created it.
+func CreateCertificateSigningRequest(kubectl *Kubectl, csr *certificatesv1.CertificateSigningRequest) (*certificatesv1.CertificateSigningRequest, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Clientset.CertificatesV1().CertificateSigningRequests().Create(context.Background(), csr, metav1.CreateOptions{})
+}
+
+// ApproveCertificateSigningRequest - Approve a CertificateSigningRequest, so
+// that its signer issues its certificate.
+func ApproveCertificateSigningRequest(kubectl *Kubectl, name, reason, message string) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	csrs := client.Clientset.CertificatesV1().CertificateSigningRequests()
+	csr, err := csrs.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	csr.Status.Conditions = append(csr.Status.Conditions, certificatesv1.CertificateSigningRequestCondition{
+		Type:           certificatesv1.CertificateApproved,
+		Status:         corev1.ConditionTrue,
+		Reason:         reason,
+		Message:        message,
+		LastUpdateTime: metav1.Now(),
+	})
+
+	_, err = csrs.UpdateApproval(ctx, name, csr, metav1.UpdateOptions{})
+	return err
+}
+
+// GetIssuedCertificate - Get the PEM encoded certificate issued for a
+// CertificateSigningRequest.
+// Returns an empty certificate if it hasn't been issued yet, and an error if
+// it was denied, or couldn't be issued.
+func GetIssuedCertificate(kubectl *Kubectl, name string) ([]byte, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := client.Clientset.CertificatesV1().CertificateSigningRequests().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, condition := range csr.Status.Conditions {
+		if condition.Type == certificatesv1.CertificateDenied || condition.Type == certificatesv1.CertificateFailed {
+			return nil, fmt.Errorf("certificate signing request %s %s: %s", name, strings.ToLower(string(condition.Type)), condition.Message)
+		}
+	}
+
+	return csr.Status.Certificate, nil
+}
+
+// DeleteCertificateSigningRequests - Delete the CertificateSigningRequests
+// matching the label selector, returning their names.
+func DeleteCertificateSigningRequests(kubectl *Kubectl, selector string) ([]string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	csrs := client.Clientset.CertificatesV1().CertificateSigningRequests()
+	list, err := csrs.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, csr := range list.Items {
+		if err := csrs.Delete(ctx, csr.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return names, err
+		}
+		names = append(names, csr.Name)
+	}
+
+	return names, nil
+}
+
+// CreateOrReplaceClusterRoleBinding - Create a ClusterRoleBinding, replacing
+// the one with the same name, if there is one.
+// Bindings can't change the role they refer to, so existing bindings are
+// deleted, rather than updated.
+func CreateOrReplaceClusterRoleBinding(kubectl *Kubectl, binding *rbacv1.ClusterRoleBinding) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	bindings := client.Clientset.RbacV1().ClusterRoleBindings()
+	if err := bindings.Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = bindings.Create(ctx, binding, metav1.CreateOptions{})
+	return err
+}
+
+// CreateOrReplaceRoleBinding - Create a RoleBinding, replacing the one with
+// the same name in its namespace, if there is one.
+func CreateOrReplaceRoleBinding(kubectl *Kubectl, binding *rbacv1.RoleBinding) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	bindings := client.Clientset.RbacV1().RoleBindings(binding.Namespace)
+	if err := bindings.Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	_, err = bindings.Create(ctx, binding, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteRoleBindings - Delete the ClusterRoleBindings, and the RoleBindings
+// in any namespace, matching the label selector, returning them as
+// kind/name, or kind/namespace/name.
+func DeleteRoleBindings(kubectl *Kubectl, selector string) ([]string, error) {
+	client, err := kubectl.Client()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	rbac := client.Clientset.RbacV1()
+	deleted := []string{}
+
+	clusterRoleBindings, err := rbac.ClusterRoleBindings().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, binding := range clusterRoleBindings.Items {
+		if err := rbac.ClusterRoleBindings().Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, "ClusterRoleBinding/"+binding.Name)
+	}
+
+	roleBindings, err := rbac.RoleBindings(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return deleted, err
+	}
+
+	for _, binding := range roleBindings.Items {
+		if err := rbac.RoleBindings(binding.Namespace).Delete(ctx, binding.Name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return deleted, err
+		}
+		deleted = append(deleted, "RoleBinding/"+binding.Namespace+"/"+binding.Name)
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,480 @@
+package kubeutil
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func testNode(name, ip string) *corev1.Node {
+	return &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: corev1.NodeStatus{
+			Addresses: []corev1.NodeAddress{
+				{Type: corev1.NodeHostName, Address: name},
+				{Type: corev1.NodeInternalIP, Address: ip},
+			},
+			Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+				{Type: corev1.NodeMemoryPressure, Status: corev1.ConditionFalse},
+			},
+		},
+	}
+}
+
+func testJob(name string, conditions ...batchv1.JobConditionType) *batchv1.Job {
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"}}
+	for _, condition := range conditions {
+		job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: condition, Status: corev1.ConditionTrue})
+	}
+
+	return &job
+}
+
+func testKubectl(objects ...runtime.Object) *Kubectl {
+	return NewKubectlWithClient(&Client{Clientset: fake.NewSimpleClientset(objects...)})
+}
+
+func TestNodeNameFromHost(t *testing.T) {
+	kubectl := testKubectl(
+		testNode("node-10", "192.168.1.10"),
+		testNode("node-1", "192.168.1.1"),
+	)
+
+	name, err := NodeNameFromHost(kubectl, "192.168.1.1")
+	assert.Nil(t, err)
+	assert.Equal(t, "node-1", name)
+
+	name, err = NodeNameFromHost(kubectl, "node-10")
+	assert.Nil(t, err)
+	assert.Equal(t, "node-10", name)
+
+	_, err = NodeNameFromHost(kubectl, "192.168.1.")
+	assert.Equal(t, "host: 192.168.1. not found in this cluster", err.Error())
+
+	_, err = NodeNameFromHost(testKubectl(), "192.168.1.1")
+	assert.Equal(t, "no nodes found in this cluster", err.Error())
+}
+
+func TestNodeConditions(t *testing.T) {
+	kubectl := testKubectl(testNode("node-1", "192.168.1.1"))
+
+	conditions, err := NodeConditions(kubectl, "node-1")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"Ready": "True", "MemoryPressure": "False"}, conditions)
+
+	_, err = NodeConditions(kubectl, "node-2")
+	assert.Error(t, err)
+}
+
+func TestDecodeManifests(t *testing.T) {
+	manifests := `---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+---
+apiVersion: v1
+kind: List
+items:
+  - apiVersion: v1
+    kind: Service
+    metadata:
+      name: service
+      namespace: web
+  - apiVersion: apps/v1
+    kind: Deployment
+    metadata:
+      name: deployment
+---
+`
+
+	objects, err := DecodeManifests(manifests)
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.GetKind()+"/"+object.GetName())
+	}
+	assert.Equal(t, []string{"ConfigMap/config", "Service/service", "Deployment/deployment"}, names)
+}
+
+func TestServerSideApply(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, meta.RESTScopeRoot)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient.PrependReactor("patch", "*", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, nil
+	})
+
+	kubectl := NewKubectlWithClient(&Client{Dynamic: dynamicClient, Mapper: mapper})
+	manifests := `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: web
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`
+
+	err := ServerSideApply(kubectl, manifests, "hope")
+	assert.Nil(t, err)
+
+	actions := dynamicClient.Actions()
+	assert.Equal(t, 2, len(actions))
+
+	namespacePatch := actions[0].(k8stesting.PatchAction)
+	assert.Equal(t, "namespaces", namespacePatch.GetResource().Resource)
+	assert.Equal(t, "", namespacePatch.GetNamespace())
+	assert.Equal(t, "web", namespacePatch.GetName())
+	assert.Equal(t, types.ApplyPatchType, namespacePatch.GetPatchType())
+
+	configMapPatch := actions[1].(k8stesting.PatchAction)
+	assert.Equal(t, "configmaps", configMapPatch.GetResource().Resource)
+	assert.Equal(t, "default", configMapPatch.GetNamespace())
+	assert.Equal(t, "config", configMapPatch.GetName())
+	assert.JSONEq(t, `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"config","namespace":"default"}}`, string(configMapPatch.GetPatch()))
+
+	err = ServerSideApply(kubectl, "apiVersion: v1\nkind: Secret\nmetadata:\n  name: secret\n", "hope")
+	assert.ErrorContains(t, err, "failed to find resource for Secret secret")
+}
+
+func TestGetJobResult(t *testing.T) {
+	kubectl := testKubectl(
+		testJob("running"),
+		testJob("complete", batchv1.JobSuccessCriteriaMet, batchv1.JobComplete),
+		testJob("failed", batchv1.JobFailed),
+	)
+
+	var tests = []struct {
+		job    string
+		result JobResult
+	}{
+		{"running", JobResultRunning},
+		{"complete", JobResultComplete},
+		{"failed", JobResultFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.job, func(t *testing.T) {
+			result, err := GetJobResult(kubectl, "default", tt.job)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.result, result)
+		})
+	}
+}
+
+func TestWatchJob(t *testing.T) {
+	clientset := fake.NewSimpleClientset(testJob("job"))
+	kubectl := NewKubectlWithClient(&Client{Clientset: clientset})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	go func() {
+		// Give the watch a chance to start before finishing the job.
+		time.Sleep(time.Millisecond * 100)
+		clientset.BatchV1().Jobs("default").Update(context.Background(), testJob("job", batchv1.JobComplete), metav1.UpdateOptions{})
+	}()
+
+	result, err := WatchJob(ctx, kubectl, "default", "job")
+	assert.Nil(t, err)
+	assert.Equal(t, JobResultComplete, result)
+
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+
+	clientset = fake.NewSimpleClientset(testJob("job"))
+	result, err = WatchJob(ctx, NewKubectlWithClient(&Client{Clientset: clientset}), "default", "job")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, JobResultRunning, result)
+}
+
+func TestGetPodNames(t *testing.T) {
+	kubectl := testKubectl(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default", Labels: map[string]string{"job-name": "job"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "default", Labels: map[string]string{"job-name": "other"}}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "web", Labels: map[string]string{"job-name": "job"}}},
+	)
+
+	pods, err := GetPodNames(kubectl, "default", "job-name=job")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, pods)
+}
+
+func TestGetRunningPodNames(t *testing.T) {
+	now := metav1.Now()
+	pod := func(name string, phase corev1.PodPhase) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Status:     corev1.PodStatus{Phase: phase},
+		}
+	}
+
+	deleting := pod("d", corev1.PodRunning)
+	deleting.DeletionTimestamp = &now
+
+	kubectl := testKubectl(pod("c", corev1.PodRunning), pod("a", corev1.PodRunning), pod("b", corev1.PodPending), deleting)
+
+	pods, err := GetRunningPodNames(kubectl, "default", "app=web")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "c"}, pods)
+}
+
+func TestPodSelectorFor(t *testing.T) {
+	selector := &metav1.LabelSelector{
+		MatchLabels: map[string]string{"app": "web"},
+		MatchExpressions: []metav1.LabelSelectorRequirement{
+			{Key: "tier", Operator: metav1.LabelSelectorOpIn, Values: []string{"frontend"}},
+		},
+	}
+
+	kubectl := testKubectl(
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: appsv1.DeploymentSpec{Selector: selector}},
+		&appsv1.StatefulSet{ObjectMeta: metav1.ObjectMeta{Name: "db", Namespace: "default"}, Spec: appsv1.StatefulSetSpec{Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "db"}}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "web"}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "external", Namespace: "default"}},
+	)
+
+	var tests = []struct {
+		object   string
+		selector string
+		err      string
+	}{
+		{"deploy/web", "app=web,tier in (frontend)", ""},
+		{"Deployment/web", "app=web,tier in (frontend)", ""},
+		{"sts/db", "app=db", ""},
+		{"svc/web", "app=web", ""},
+		{"svc/external", "", "service external has no selector"},
+		{"deploy/missing", "", `deployments.apps "missing" not found`},
+		{"configmap/web", "", "can't find pods of kind configmap"},
+		{"web", "", "object web must be given as kind/name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.object, func(t *testing.T) {
+			selector, err := PodSelectorFor(kubectl, "default", tt.object)
+			if tt.err == "" {
+				assert.Nil(t, err)
+				assert.Equal(t, tt.selector, selector)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestGetPodEvents(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	kubectl := testKubectl(
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pod.2", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pod"},
+			Type:           "Warning",
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			LastTimestamp:  metav1.NewTime(now),
+		},
+		&corev1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: "pod.1", Namespace: "default"},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "pod"},
+			Type:           "Normal",
+			Reason:         "Scheduled",
+			Message:        "Successfully assigned default/pod to node-1",
+			LastTimestamp:  metav1.NewTime(now.Add(-time.Minute)),
+		},
+	)
+
+	events, err := GetPodEvents(kubectl, "default", "pod")
+	assert.Nil(t, err)
+	assert.Equal(t, []PodEvent{
+		{"Normal", "Scheduled", "Successfully assigned default/pod to node-1", now.Add(-time.Minute)},
+		{"Warning", "BackOff", "Back-off restarting failed container", now},
+	}, events)
+}
+
+func TestStreamPodLogs(t *testing.T) {
+	kubectl := testKubectl(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "pod", Namespace: "default"}})
+
+	var buf bytes.Buffer
+	err := StreamPodLogs(context.Background(), kubectl, "default", "pod", "", true, &buf)
+	assert.Nil(t, err)
+	assert.Equal(t, "fake logs", buf.String())
+}
+
+func TestExecInPodRequiresConfig(t *testing.T) {
+	kubectl := testKubectl()
+
+	err := ExecInPod(context.Background(), kubectl, "default", "pod", ExecOptions{Command: []string{"true"}})
+	assert.Equal(t, "pod exec requires a client built from a kubeconfig", err.Error())
+}
+
+func testAppliedConfigMap(name, namespace, manager string, operation metav1.ManagedFieldsOperationType) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{}
+	object.SetAPIVersion("v1")
+	object.SetKind("ConfigMap")
+	object.SetName(name)
+	object.SetNamespace(namespace)
+	object.SetLabels(map[string]string{"hope.resource": "config"})
+	object.SetManagedFields([]metav1.ManagedFieldsEntry{{Manager: manager, Operation: operation}})
+	return object
+}
+
+func TestListAppliedObjects(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clientset.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "delete", "patch"}},
+				{Name: "configmaps/status", Kind: "ConfigMap", Namespaced: true, Verbs: []string{"list", "delete"}},
+				{Name: "events", Kind: "Event", Namespaced: true, Verbs: []string{"list", "delete"}},
+				{Name: "bindings", Kind: "Binding", Namespaced: true, Verbs: []string{"create"}},
+			},
+		},
+	}
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{gvr: "ConfigMapList"},
+		testAppliedConfigMap("applied", "default", "hope", metav1.ManagedFieldsOperationApply),
+		testAppliedConfigMap("updated", "default", "hope", metav1.ManagedFieldsOperationUpdate),
+		testAppliedConfigMap("copied", "web", "kube-controller-manager", metav1.ManagedFieldsOperationUpdate),
+		testAppliedConfigMap("elsewhere", "web", "hope", metav1.ManagedFieldsOperationApply),
+	)
+
+	kubectl := NewKubectlWithClient(&Client{Clientset: clientset, Dynamic: dynamicClient})
+	objects, err := ListAppliedObjects(kubectl, "hope.resource=config", "hope")
+	assert.Nil(t, err)
+
+	names := []string{}
+	for _, object := range objects {
+		names = append(names, object.GetNamespace()+"/"+object.GetName())
+	}
+	assert.ElementsMatch(t, []string{"default/applied", "web/elsewhere"}, names)
+
+	listed := []string{}
+	for _, action := range dynamicClient.Actions() {
+		listed = append(listed, action.GetResource().Resource)
+	}
+	assert.Equal(t, []string{"configmaps"}, listed)
+}
+
+func TestGetObject(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		testAppliedConfigMap("config", "default", "hope", metav1.ManagedFieldsOperationApply),
+	)
+
+	kubectl := NewKubectlWithClient(&Client{Dynamic: dynamicClient, Mapper: mapper})
+
+	existing, err := GetObject(kubectl, testAppliedConfigMap("config", "", "hope", metav1.ManagedFieldsOperationApply))
+	assert.Nil(t, err)
+	assert.Equal(t, "config", existing.GetName())
+	assert.Equal(t, map[string]string{"hope.resource": "config"}, existing.GetLabels())
+
+	existing, err = GetObject(kubectl, testAppliedConfigMap("other", "default", "hope", metav1.ManagedFieldsOperationApply))
+	assert.Nil(t, err)
+	assert.Nil(t, existing)
+}
+
+func TestDeleteObject(t *testing.T) {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{})
+	mapper.Add(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, meta.RESTScopeNamespace)
+
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(
+		runtime.NewScheme(),
+		testAppliedConfigMap("config", "default", "hope", metav1.ManagedFieldsOperationApply),
+	)
+
+	kubectl := NewKubectlWithClient(&Client{Dynamic: dynamicClient, Mapper: mapper})
+	object := testAppliedConfigMap("config", "", "hope", metav1.ManagedFieldsOperationApply)
+
+	assert.Nil(t, DeleteObject(kubectl, object))
+	assert.Equal(t, "default", object.GetNamespace())
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	_, err := dynamicClient.Resource(gvr).Namespace("default").Get(context.Background(), "config", metav1.GetOptions{})
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Already deleted objects aren't an error.
+	assert.Nil(t, DeleteObject(kubectl, object))
+}
+
+func TestListJobs(t *testing.T) {
+	started := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	complete := testJob("complete", batchv1.JobComplete)
+	complete.Labels = map[string]string{"hope.job": "complete"}
+	complete.Status.StartTime = &metav1.Time{Time: started}
+	complete.Status.CompletionTime = &metav1.Time{Time: started.Add(time.Minute)}
+
+	failed := testJob("failed", batchv1.JobFailed)
+	failed.Namespace = "dev"
+	failed.Labels = map[string]string{"hope.job": "failed"}
+	failed.Status.StartTime = &metav1.Time{Time: started.Add(time.Hour)}
+	failed.Status.Conditions[0].LastTransitionTime = metav1.NewTime(started.Add(time.Hour * 2))
+
+	kubectl := testKubectl(complete, failed, testJob("unlabelled"))
+
+	jobs, err := ListJobs(kubectl, "", "hope.job")
+	assert.Nil(t, err)
+	assert.Equal(t, []JobSummary{
+		{"dev", "failed", failed.Labels, JobResultFailed, started.Add(time.Hour), started.Add(time.Hour * 2)},
+		{"default", "complete", complete.Labels, JobResultComplete, started, started.Add(time.Minute)},
+	}, jobs)
+
+	jobs, err = ListJobs(kubectl, "default", "hope.job")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(jobs))
+}
+
+func TestCreateAndDeleteJob(t *testing.T) {
+	kubectl := testKubectl()
+
+	job := testJob("job")
+	job.Namespace = ""
+
+	created, err := CreateJob(kubectl, job)
+	assert.Nil(t, err)
+	assert.Equal(t, "default", created.Namespace)
+	assert.Equal(t, "", job.Namespace)
+
+	_, err = CreateJob(kubectl, job)
+	assert.ErrorContains(t, err, "failed to create job job")
+
+	assert.Nil(t, DeleteJob(kubectl, "default", "job"))
+	_, err = GetJobResult(kubectl, "default", "job")
+	assert.True(t, apierrors.IsNotFound(err))
+
+	// Already deleted jobs aren't an error.
+	assert.Nil(t, DeleteJob(kubectl, "default", "job"))
+}
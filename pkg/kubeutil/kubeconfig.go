@@ -0,0 +1,259 @@
+package kubeutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"time"
+)
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// KubeconfigCluster - The details of a cluster needed to connect to it.
+type KubeconfigCluster struct {
+	Name                     string
+	Server                   string
+	CertificateAuthorityData []byte
+}
+
+// CurrentCluster - Get the cluster the current context of a kubeconfig file
+// points at.
+func CurrentCluster(kubeconfigPath string) (*KubeconfigCluster, error) {
+	config, err := clientcmd.LoadFromFile(kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, errors.New("kubeconfig has no current context")
+	}
+
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig has no cluster named %s", kubeContext.Cluster)
+	}
+
+	caData := cluster.CertificateAuthorityData
+	if len(caData) == 0 && cluster.CertificateAuthority != "" {
+		if caData, err = os.ReadFile(cluster.CertificateAuthority); err != nil {
+			return nil, err
+		}
+	}
+
+	return &KubeconfigCluster{Name: kubeContext.Cluster, Server: cluster.Server, CertificateAuthorityData: caData}, nil
+}
+
+// WithServerHost - Get a copy of the cluster whose server is at the given
+// host, keeping the server's scheme and port.
+// An empty host leaves the server as it is.
+func (cluster *KubeconfigCluster) WithServerHost(host string) (*KubeconfigCluster, error) {
+	server, err := serverWithHost(cluster.Server, host)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := *cluster
+	copied.Server = server
+	return &copied, nil
+}
+
+func serverWithHost(server, host string) (string, error) {
+	if host == "" {
+		return server, nil
+	}
+
+	serverUrl, err := url.Parse(server)
+	if err != nil {
+		return "", err
+	}
+
+	if port := serverUrl.Port(); port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	serverUrl.Host = host
+	return serverUrl.String(), nil
+}
+
+// StandaloneKubeconfig - Get a kubeconfig holding only the current context of
+// the given one, along with its cluster and user, all renamed to the given
+// name, and with the cluster's server at the given host.
+// An empty name keeps the existing names, and an empty host leaves the server
+// as it is.
+func StandaloneKubeconfig(config *clientcmdapi.Config, name, serverHost string) (*clientcmdapi.Config, error) {
+	kubeContext, ok := config.Contexts[config.CurrentContext]
+	if !ok {
+		return nil, errors.New("kubeconfig has no current context")
+	}
+
+	cluster, ok := config.Clusters[kubeContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig has no cluster named %s", kubeContext.Cluster)
+	}
+
+	authInfo, ok := config.AuthInfos[kubeContext.AuthInfo]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig has no user named %s", kubeContext.AuthInfo)
+	}
+
+	contextName, clusterName, userName := config.CurrentContext, kubeContext.Cluster, kubeContext.AuthInfo
+	if name != "" {
+		contextName, clusterName, userName = name, name, name
+	}
+
+	server, err := serverWithHost(cluster.Server, serverHost)
+	if err != nil {
+		return nil, err
+	}
+
+	standalone := clientcmdapi.NewConfig()
+	standalone.Clusters[clusterName] = cluster.DeepCopy()
+	standalone.Clusters[clusterName].Server = server
+	standalone.AuthInfos[userName] = authInfo.DeepCopy()
+	standalone.Contexts[contextName] = kubeContext.DeepCopy()
+	standalone.Contexts[contextName].Cluster = clusterName
+	standalone.Contexts[contextName].AuthInfo = userName
+	standalone.CurrentContext = contextName
+
+	return standalone, nil
+}
+
+// MergeKubeconfig - Merge a kubeconfig holding a single context into another.
+// Entries with the same names as the incoming ones are replaced, as are any
+// contexts of clusters with the same server, along with those clusters, and
+// any users only those contexts used.
+// The existing current context is kept, unless it was replaced.
+func MergeKubeconfig(existing, incoming *clientcmdapi.Config) (*clientcmdapi.Config, error) {
+	incomingContext, ok := incoming.Contexts[incoming.CurrentContext]
+	if !ok {
+		return nil, errors.New("kubeconfig has no current context")
+	}
+
+	incomingCluster, ok := incoming.Clusters[incomingContext.Cluster]
+	if !ok {
+		return nil, fmt.Errorf("kubeconfig has no cluster named %s", incomingContext.Cluster)
+	}
+
+	merged := existing.DeepCopy()
+
+	staleClusters := map[string]bool{incomingContext.Cluster: true}
+	for name, cluster := range merged.Clusters {
+		if cluster.Server == incomingCluster.Server {
+			staleClusters[name] = true
+		}
+	}
+
+	staleUsers := map[string]bool{incomingContext.AuthInfo: true}
+	for name, kubeContext := range merged.Contexts {
+		if name == incoming.CurrentContext || staleClusters[kubeContext.Cluster] {
+			staleUsers[kubeContext.AuthInfo] = true
+			delete(merged.Contexts, name)
+		}
+	}
+
+	for _, kubeContext := range merged.Contexts {
+		if kubeContext.AuthInfo != incomingContext.AuthInfo {
+			delete(staleUsers, kubeContext.AuthInfo)
+		}
+	}
+
+	for name := range staleClusters {
+		delete(merged.Clusters, name)
+	}
+
+	for name := range staleUsers {
+		delete(merged.AuthInfos, name)
+	}
+
+	for name, cluster := range incoming.Clusters {
+		merged.Clusters[name] = cluster.DeepCopy()
+	}
+
+	for name, authInfo := range incoming.AuthInfos {
+		merged.AuthInfos[name] = authInfo.DeepCopy()
+	}
+
+	for name, kubeContext := range incoming.Contexts {
+		merged.Contexts[name] = kubeContext.DeepCopy()
+	}
+
+	if _, ok := merged.Contexts[merged.CurrentContext]; !ok {
+		merged.CurrentContext = incoming.CurrentContext
+	}
+
+	return merged, nil
+}
+
+// NewKubeconfig - Build a kubeconfig whose only context connects to the
+// cluster as the given user, defaulting to the given namespace.
+// The context is named user@cluster, the way kubeadm names its admin
+// context.
+func NewKubeconfig(cluster *KubeconfigCluster, user string, authInfo *clientcmdapi.AuthInfo, namespace string) ([]byte, error) {
+	contextName := fmt.Sprintf("%s@%s", user, cluster.Name)
+
+	config := clientcmdapi.NewConfig()
+	config.Clusters[cluster.Name] = &clientcmdapi.Cluster{
+		Server:                   cluster.Server,
+		CertificateAuthorityData: cluster.CertificateAuthorityData,
+	}
+	config.AuthInfos[user] = authInfo
+	config.Contexts[contextName] = &clientcmdapi.Context{
+		Cluster:   cluster.Name,
+		AuthInfo:  user,
+		Namespace: namespace,
+	}
+	config.CurrentContext = contextName
+
+	return clientcmd.Write(*config)
+}
+
+// NewKubectlFromContext - Create a kubectl that uses a temporary kubeconfig
+// holding only the named context of the user's kubeconfig.
+func NewKubectlFromContext(contextName string) (*Kubectl, error) {
+	config, err := clientcmd.NewDefaultClientConfigLoadingRules().Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := config.Contexts[contextName]; !ok {
+		return nil, fmt.Errorf("kubeconfig has no context named %s", contextName)
+	}
+
+	config.CurrentContext = contextName
+	standalone, err := StandaloneKubeconfig(config, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := clientcmd.Write(*standalone)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewKubectlFromContents(contents)
+}
+
+type VerifyAccessFunc func(kubectl *Kubectl, timeout time.Duration) error
+
+// VerifyAccess - Check that the cluster accepts the kubectl's credentials,
+// by making a request that needs them.
+var VerifyAccess VerifyAccessFunc = func(kubectl *Kubectl, timeout time.Duration) error {
+	client, err := kubectl.Client()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_, err = client.Clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{Limit: 1})
+	return err
+}
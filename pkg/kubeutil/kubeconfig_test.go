@@ -0,0 +1,202 @@
+package kubeutil
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: kubernetes
+  cluster:
+    server: https://192.168.1.10:6443
+    certificate-authority-data: Y2E=
+contexts:
+- name: kubernetes-admin@kubernetes
+  context:
+    cluster: kubernetes
+    user: kubernetes-admin
+current-context: kubernetes-admin@kubernetes
+users:
+- name: kubernetes-admin
+  user:
+    token: admin
+`
+
+func TestCurrentCluster(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.Nil(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+
+	cluster, err := CurrentCluster(path)
+	assert.Nil(t, err)
+	assert.Equal(t, &KubeconfigCluster{Name: "kubernetes", Server: "https://192.168.1.10:6443", CertificateAuthorityData: []byte("ca")}, cluster)
+
+	assert.Nil(t, os.WriteFile(path, []byte("apiVersion: v1\nkind: Config\n"), 0600))
+	_, err = CurrentCluster(path)
+	assert.Equal(t, "kubeconfig has no current context", err.Error())
+}
+
+func TestNewKubeconfig(t *testing.T) {
+	cluster := &KubeconfigCluster{Name: "kubernetes", Server: "https://192.168.1.10:6443", CertificateAuthorityData: []byte("ca")}
+	kubeconfig, err := NewKubeconfig(cluster, "ci", &clientcmdapi.AuthInfo{Token: "token"}, "build")
+	assert.Nil(t, err)
+	assert.Equal(t, `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Y2E=
+    server: https://192.168.1.10:6443
+  name: kubernetes
+contexts:
+- context:
+    cluster: kubernetes
+    namespace: build
+    user: ci
+  name: ci@kubernetes
+current-context: ci@kubernetes
+kind: Config
+preferences: {}
+users:
+- name: ci
+  user:
+    token: token
+`, string(kubeconfig))
+}
+
+func TestWithServerHost(t *testing.T) {
+	cluster := &KubeconfigCluster{Name: "kubernetes", Server: "https://192.168.1.10:6443"}
+
+	var tests = []struct {
+		name   string
+		host   string
+		server string
+	}{
+		{"Keeps Port", "api.example.com", "https://api.example.com:6443"},
+		{"No Host", "", "https://192.168.1.10:6443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewritten, err := cluster.WithServerHost(tt.host)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.server, rewritten.Server)
+			assert.Equal(t, "https://192.168.1.10:6443", cluster.Server)
+		})
+	}
+
+	rewritten, err := (&KubeconfigCluster{Server: "https://192.168.1.10"}).WithServerHost("api.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://api.example.com", rewritten.Server)
+}
+
+func TestStandaloneKubeconfig(t *testing.T) {
+	config, err := clientcmd.Load([]byte(testKubeconfig))
+	assert.Nil(t, err)
+
+	config.Clusters["other"] = &clientcmdapi.Cluster{Server: "https://192.168.2.10:6443"}
+
+	standalone, err := StandaloneKubeconfig(config, "homelab", "api.example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, "homelab", standalone.CurrentContext)
+	assert.Equal(t, &clientcmdapi.Context{Cluster: "homelab", AuthInfo: "homelab", Extensions: map[string]runtime.Object{}}, standalone.Contexts["homelab"])
+	assert.Equal(t, []string{"homelab"}, mapKeys(standalone.Clusters))
+	assert.Equal(t, "https://api.example.com:6443", standalone.Clusters["homelab"].Server)
+	assert.Equal(t, []byte("ca"), standalone.Clusters["homelab"].CertificateAuthorityData)
+	assert.Equal(t, "admin", standalone.AuthInfos["homelab"].Token)
+
+	// The original isn't changed.
+	assert.Equal(t, "https://192.168.1.10:6443", config.Clusters["kubernetes"].Server)
+
+	standalone, err = StandaloneKubeconfig(config, "", "")
+	assert.Nil(t, err)
+	assert.Equal(t, "kubernetes-admin@kubernetes", standalone.CurrentContext)
+	assert.Equal(t, []string{"kubernetes"}, mapKeys(standalone.Clusters))
+	assert.Equal(t, "https://192.168.1.10:6443", standalone.Clusters["kubernetes"].Server)
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	existing := clientcmdapi.NewConfig()
+	existing.Clusters["kubernetes"] = &clientcmdapi.Cluster{Server: "https://api.example.com:6443"}
+	existing.Clusters["homelab"] = &clientcmdapi.Cluster{Server: "https://192.168.1.10:6443"}
+	existing.Clusters["work"] = &clientcmdapi.Cluster{Server: "https://work.example.com"}
+	existing.AuthInfos["kubernetes-admin"] = &clientcmdapi.AuthInfo{Token: "old"}
+	existing.AuthInfos["homelab"] = &clientcmdapi.AuthInfo{Token: "older"}
+	existing.AuthInfos["work"] = &clientcmdapi.AuthInfo{Token: "work"}
+	existing.Contexts["kubernetes-admin@kubernetes"] = &clientcmdapi.Context{Cluster: "kubernetes", AuthInfo: "kubernetes-admin"}
+	existing.Contexts["homelab"] = &clientcmdapi.Context{Cluster: "homelab", AuthInfo: "homelab"}
+	existing.Contexts["work"] = &clientcmdapi.Context{Cluster: "work", AuthInfo: "work"}
+	existing.CurrentContext = "work"
+
+	incoming := clientcmdapi.NewConfig()
+	incoming.Clusters["homelab"] = &clientcmdapi.Cluster{Server: "https://api.example.com:6443"}
+	incoming.AuthInfos["homelab"] = &clientcmdapi.AuthInfo{Token: "new"}
+	incoming.Contexts["homelab"] = &clientcmdapi.Context{Cluster: "homelab", AuthInfo: "homelab"}
+	incoming.CurrentContext = "homelab"
+
+	merged, err := MergeKubeconfig(existing, incoming)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"homelab", "work"}, mapKeys(merged.Clusters))
+	assert.Equal(t, []string{"homelab", "work"}, mapKeys(merged.AuthInfos))
+	assert.Equal(t, []string{"homelab", "work"}, mapKeys(merged.Contexts))
+	assert.Equal(t, "https://api.example.com:6443", merged.Clusters["homelab"].Server)
+	assert.Equal(t, "new", merged.AuthInfos["homelab"].Token)
+	assert.Equal(t, "work", merged.CurrentContext)
+
+	// The existing kubeconfig isn't changed.
+	assert.Equal(t, 3, len(existing.Contexts))
+
+	existing.CurrentContext = "kubernetes-admin@kubernetes"
+	merged, err = MergeKubeconfig(existing, incoming)
+	assert.Nil(t, err)
+	assert.Equal(t, "homelab", merged.CurrentContext)
+}
+
+func mapKeys[V any](m map[string]V) []string {
+	keys := []string{}
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestNewKubectlFromContext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	assert.Nil(t, os.WriteFile(path, []byte(testKubeconfig), 0600))
+	t.Setenv("KUBECONFIG", path)
+
+	kubectl, err := NewKubectlFromContext("kubernetes-admin@kubernetes")
+	assert.Nil(t, err)
+	defer kubectl.Destroy()
+
+	cluster, err := CurrentCluster(kubectl.KubeconfigPath)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://192.168.1.10:6443", cluster.Server)
+
+	_, err = NewKubectlFromContext("homelab")
+	assert.Equal(t, "kubeconfig has no context named homelab", err.Error())
+}
+
+func TestVerifyAccess(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	kubectl := NewKubectlWithClient(&Client{Clientset: clientset})
+	assert.Nil(t, VerifyAccess(kubectl, time.Second))
+
+	clientset.PrependReactor("list", "namespaces", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, apierrors.NewUnauthorized("Unauthorized")
+	})
+	assert.True(t, apierrors.IsUnauthorized(VerifyAccess(kubectl, time.Second)))
+}
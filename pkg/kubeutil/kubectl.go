@@ -9,37 +9,55 @@ import (
 )
 
 // Kubectl struct allows for execution of a kubectl command with a
-// non-environment set kubeconfig path.
+// non-environment set kubeconfig path, and provides a typed API client
+// built from that same kubeconfig.
 // TODO: Move more uses of kubeutil.ExecKubectl/GetKubectl/etc to use this
 // structure.
 type Kubectl struct {
 	KubeconfigPath string
+
+	client *Client
 }
 
 func NewKubectl(kubeconfigPath string) *Kubectl {
-	return &Kubectl{kubeconfigPath}
+	return &Kubectl{KubeconfigPath: kubeconfigPath}
+}
+
+// NewKubectlWithClient - Create a kubectl whose API calls go through the
+// given client instead of one built from a kubeconfig.
+func NewKubectlWithClient(client *Client) *Kubectl {
+	return &Kubectl{client: client}
 }
 
 func NewKubectlFromNode(host string) (*Kubectl, error) {
-	// Do not delete.
-	// Leave deletion up to destroying the kubectl instance.
-	tempFile, err := os.CreateTemp("", "")
+	// Because auth for the user in the config may not be for root, can't
+	//   reliably pull data via scp.
+	output, err := ssh.GetSSH(host, "sudo", "cat", "/etc/kubernetes/admin.conf")
 	if err != nil {
 		return nil, err
 	}
 
-	// Because auth for the user in the config may not be for root, can't
-	//   reliably pull data via scp.
-	output, err := ssh.GetSSH(host, "sudo", "cat", "/etc/kubernetes/admin.conf")
+	return NewKubectlFromContents([]byte(output))
+}
+
+// NewKubectlFromContents - Create a kubectl that uses a temporary copy of
+// the given kubeconfig.
+func NewKubectlFromContents(contents []byte) (*Kubectl, error) {
+	// Do not delete.
+	// Leave deletion up to destroying the kubectl instance.
+	tempFile, err := os.CreateTemp("", "")
 	if err != nil {
 		return nil, err
 	}
 
-	if _, err = tempFile.Write([]byte(output)); err != nil {
+	if _, err = tempFile.Write(contents); err != nil {
+		tempFile.Close()
+		os.Remove(tempFile.Name())
 		return nil, err
 	}
 
 	if err := tempFile.Close(); err != nil {
+		os.Remove(tempFile.Name())
 		return nil, err
 	}
 
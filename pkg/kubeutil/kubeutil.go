@@ -1,7 +1,6 @@
 package kubeutil
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -58,29 +57,6 @@ var GetInKubectl GetInKubectlFunc = func(kubectl *Kubectl, stdin string, args ..
 	return string(outputBytes), err
 }
 
-// Get the name by which the cluster recognizes a given host.
-func NodeNameFromHost(kubectl *Kubectl, host string) (string, error) {
-	nodesOutput, err := GetKubectl(kubectl, "get", "nodes", "-o", "custom-columns=NODE:metadata.name,IP:status.addresses[?(@.type=='InternalIP')].address")
-	if err != nil {
-		return "", errors.New(strings.Join([]string{nodesOutput, err.Error()}, " "))
-	}
-
-	outputRows := strings.Split(nodesOutput, "\n")
-	if len(outputRows) < 2 {
-		return "", errors.New("no nodes found in this cluster")
-	}
-
-	nodeRows := outputRows[1:]
-
-	for _, nodeRow := range nodeRows {
-		if strings.HasPrefix(nodeRow, host) || strings.HasSuffix(nodeRow, host) {
-			return strings.Split(nodeRow, " ")[0], nil
-		}
-	}
-
-	return "", fmt.Errorf("host: %s not found in this cluster", host)
-}
-
 func GetKubeConfigPath() (string, error) {
 	kubeconfigEnv := os.Getenv("KUBECONFIG")
 	if kubeconfigEnv != "" {
@@ -0,0 +1,286 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Descriptor - A reference to a manifest or blob, as it appears in another
+// manifest.
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// manifest - The parts of image manifests and indexes needed to find
+// everything they refer to.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []Descriptor `json:"manifests"`
+	Config    *Descriptor  `json:"config"`
+	Layers    []Descriptor `json:"layers"`
+}
+
+// Copy - Copy an image, including every platform of multi-platform images,
+// from one registry to another, without changing its digest.
+// Manifests and blobs the target already has aren't copied again, so
+// interrupted copies pick up where they left off.
+// Returns the digest of the copied image.
+func (c *Client) Copy(ctx context.Context, source, target string) (string, error) {
+	sourceRef, err := ParseReference(source)
+	if err != nil {
+		return "", err
+	}
+
+	targetRef, err := ParseReference(target)
+	if err != nil {
+		return "", err
+	}
+
+	contents, mediaType, digest, err := c.GetManifest(ctx, sourceRef)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.copyManifestContents(ctx, sourceRef, targetRef, contents, mediaType); err != nil {
+		return "", err
+	}
+
+	if err := c.PutManifest(ctx, targetRef, contents, mediaType); err != nil {
+		return "", err
+	}
+
+	return digest, nil
+}
+
+// GetManifest - Fetch the manifest an image reference points to, returning
+// it as-is, along with its media type and digest.
+func (c *Client) GetManifest(ctx context.Context, ref *Reference) ([]byte, string, string, error) {
+	response, err := c.Do(ctx, ref, "pull", http.MethodGet, fmt.Sprintf("manifests/%s", ref.Reference), http.Header{"Accept": ManifestMediaTypes}, nil)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, "", "", fmt.Errorf("%s not found", ref.String())
+	} else if response.StatusCode != http.StatusOK {
+		return nil, "", "", responseError(response)
+	}
+
+	contents, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	digest := fmt.Sprintf("sha256:%s", sha256Hex(contents))
+	if strings.HasPrefix(ref.Reference, "sha256:") && ref.Reference != digest {
+		return nil, "", "", fmt.Errorf("manifest of %s has digest %s", ref.String(), digest)
+	}
+
+	mediaType := response.Header.Get("Content-Type")
+	if mediaType == "" {
+		var parsed manifest
+		if err := json.Unmarshal(contents, &parsed); err != nil {
+			return nil, "", "", err
+		}
+		mediaType = parsed.MediaType
+	}
+
+	return contents, mediaType, digest, nil
+}
+
+// PutManifest - Upload a manifest to the reference's tag or digest.
+func (c *Client) PutManifest(ctx context.Context, ref *Reference, contents []byte, mediaType string) error {
+	body := func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(contents)), nil }
+	response, err := c.Do(ctx, ref, "pull,push", http.MethodPut, fmt.Sprintf("manifests/%s", ref.Reference), http.Header{"Content-Type": {mediaType}, "Content-Length": {fmt.Sprint(len(contents))}}, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated && response.StatusCode != http.StatusOK {
+		return responseError(response)
+	}
+
+	return nil
+}
+
+// copyManifestContents - Copy everything the manifest refers to, so that the
+// manifest itself can be uploaded.
+func (c *Client) copyManifestContents(ctx context.Context, sourceRef, targetRef *Reference, contents []byte, mediaType string) error {
+	var parsed manifest
+	if err := json.Unmarshal(contents, &parsed); err != nil {
+		return fmt.Errorf("failed to parse manifest of %s: %w", sourceRef.String(), err)
+	}
+
+	for _, child := range parsed.Manifests {
+		_, exists, err := c.ManifestDigest(ctx, targetRef.WithReference(child.Digest).String())
+		if err != nil {
+			return err
+		}
+
+		if exists {
+			continue
+		}
+
+		childSource := sourceRef.WithReference(child.Digest)
+		childContents, childMediaType, _, err := c.GetManifest(ctx, childSource)
+		if err != nil {
+			return err
+		}
+
+		if err := c.copyManifestContents(ctx, childSource, targetRef, childContents, childMediaType); err != nil {
+			return err
+		}
+
+		if err := c.PutManifest(ctx, targetRef.WithReference(child.Digest), childContents, childMediaType); err != nil {
+			return err
+		}
+	}
+
+	blobs := parsed.Layers
+	if parsed.Config != nil {
+		blobs = append([]Descriptor{*parsed.Config}, blobs...)
+	}
+
+	for _, blob := range blobs {
+		if err := c.copyBlob(ctx, sourceRef, targetRef, blob); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyBlob - Copy a single blob, unless the target already has it.
+// Blobs are downloaded to a temporary file and verified before they're
+// uploaded, so uploads can be retried, and corrupt downloads never make it
+// to the target.
+func (c *Client) copyBlob(ctx context.Context, sourceRef, targetRef *Reference, blob Descriptor) error {
+	exists, err := c.BlobExists(ctx, targetRef, blob.Digest)
+	if err != nil || exists {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp("", "hope-blob")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+	defer tempFile.Close()
+
+	if err := c.downloadBlob(ctx, sourceRef, blob.Digest, tempFile); err != nil {
+		return err
+	}
+
+	return c.uploadBlob(ctx, targetRef, blob.Digest, tempFile.Name())
+}
+
+// BlobExists - Whether the reference's repository has the blob.
+func (c *Client) BlobExists(ctx context.Context, ref *Reference, digest string) (bool, error) {
+	response, err := c.Do(ctx, ref, "pull,push", http.MethodHead, fmt.Sprintf("blobs/%s", digest), http.Header{}, nil)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	default:
+		return false, responseError(response)
+	}
+}
+
+func (c *Client) downloadBlob(ctx context.Context, ref *Reference, digest string, w io.Writer) error {
+	response, err := c.Do(ctx, ref, "pull", http.MethodGet, fmt.Sprintf("blobs/%s", digest), http.Header{}, nil)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return responseError(response)
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(w, hash), response.Body); err != nil {
+		return fmt.Errorf("failed to download %s from %s: %w", digest, ref.Registry, err)
+	}
+
+	if actual := fmt.Sprintf("sha256:%s", hex.EncodeToString(hash.Sum(nil))); actual != digest {
+		return fmt.Errorf("blob %s from %s has digest %s", digest, ref.Registry, actual)
+	}
+
+	return nil
+}
+
+func (c *Client) uploadBlob(ctx context.Context, ref *Reference, digest, path string) error {
+	response, err := c.Do(ctx, ref, "pull,push", http.MethodPost, "blobs/uploads/", http.Header{}, nil)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusAccepted {
+		return responseError(response)
+	}
+
+	location, err := url.Parse(fmt.Sprintf("%s/v2/%s/blobs/uploads/", baseUrl(ref.Registry), ref.Repository))
+	if err != nil {
+		return err
+	}
+
+	location, err = location.Parse(response.Header.Get("Location"))
+	if err != nil {
+		return fmt.Errorf("registry returned an invalid upload location: %w", err)
+	}
+
+	query := location.Query()
+	query.Set("digest", digest)
+	location.RawQuery = query.Encode()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	body := func() (io.ReadCloser, error) {
+		return os.Open(path)
+	}
+
+	headers := http.Header{
+		"Content-Type":   {"application/octet-stream"},
+		"Content-Length": {fmt.Sprint(info.Size())},
+	}
+
+	response, err = c.Do(ctx, ref, "pull,push", http.MethodPut, location.String(), headers, body)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		return responseError(response)
+	}
+
+	return nil
+}
+
+func sha256Hex(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,161 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryRegistry - Just enough of a registry to copy images in and out of.
+type memoryRegistry struct {
+	lock      sync.Mutex
+	manifests map[string][]byte
+	types     map[string]string
+	blobs     map[string][]byte
+	uploads   int
+}
+
+func newMemoryRegistry(t *testing.T) (*memoryRegistry, string) {
+	r := &memoryRegistry{
+		manifests: map[string][]byte{},
+		types:     map[string]string{},
+		blobs:     map[string][]byte{},
+	}
+
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	InsecureRegistries = append(InsecureRegistries, host)
+	t.Cleanup(func() { InsecureRegistries = []string{} })
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	return r, host
+}
+
+func digestOf(contents []byte) string {
+	sum := sha256.Sum256(contents)
+	return fmt.Sprintf("sha256:%s", hex.EncodeToString(sum[:]))
+}
+
+func (r *memoryRegistry) putManifest(repository, reference, mediaType string, contents []byte) string {
+	digest := digestOf(contents)
+	for _, key := range []string{reference, digest} {
+		r.manifests[repository+":"+key] = contents
+		r.types[repository+":"+key] = mediaType
+	}
+	return digest
+}
+
+func (r *memoryRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+	switch {
+	case strings.Contains(path, "/manifests/"):
+		repository, reference, _ := strings.Cut(path, "/manifests/")
+		key := repository + ":" + reference
+		switch req.Method {
+		case http.MethodPut:
+			contents, _ := io.ReadAll(req.Body)
+			r.putManifest(repository, reference, req.Header.Get("Content-Type"), contents)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			contents, ok := r.manifests[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", r.types[key])
+			w.Header().Set("Docker-Content-Digest", digestOf(contents))
+			w.Write(contents)
+		}
+	case strings.HasSuffix(path, "/blobs/uploads/") && req.Method == http.MethodPost:
+		w.Header().Set("Location", "/upload/some-session?state=abc")
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(path, "/blobs/"):
+		_, digest, _ := strings.Cut(path, "/blobs/")
+		contents, ok := r.blobs[digest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(contents)
+	case req.URL.Path == "/upload/some-session" && req.Method == http.MethodPut:
+		contents, _ := io.ReadAll(req.Body)
+		if req.URL.Query().Get("state") != "abc" || req.ContentLength != int64(len(contents)) || digestOf(contents) != req.URL.Query().Get("digest") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		r.blobs[digestOf(contents)] = contents
+		r.uploads++
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (r *memoryRegistry) addImage(repository, tag string) string {
+	config := []byte(`{"architecture": "arm64"}`)
+	layer := []byte("some layer contents for " + tag)
+	r.blobs[digestOf(config)] = config
+	r.blobs[digestOf(layer)] = layer
+
+	platformManifest := fmt.Sprintf(`{"mediaType": "application/vnd.oci.image.manifest.v1+json", "config": {"digest": "%s", "size": %d}, "layers": [{"digest": "%s", "size": %d}]}`, digestOf(config), len(config), digestOf(layer), len(layer))
+	platformDigest := r.putManifest(repository, digestOf([]byte(platformManifest)), "application/vnd.oci.image.manifest.v1+json", []byte(platformManifest))
+
+	index := fmt.Sprintf(`{"mediaType": "application/vnd.oci.image.index.v1+json", "manifests": [{"digest": "%s", "size": %d}]}`, platformDigest, len(platformManifest))
+	return r.putManifest(repository, tag, "application/vnd.oci.image.index.v1+json", []byte(index))
+}
+
+func TestCopy(t *testing.T) {
+	source, sourceHost := newMemoryRegistry(t)
+	target, targetHost := newMemoryRegistry(t)
+
+	digest := source.addImage("library/python", "3.7")
+
+	client := NewClient()
+	copied, err := client.Copy(context.Background(), sourceHost+"/library/python:3.7", targetHost+"/python:3.7")
+	assert.Nil(t, err)
+	assert.Equal(t, digest, copied)
+
+	assert.Equal(t, source.manifests["library/python:3.7"], target.manifests["python:3.7"])
+	assert.Equal(t, 3, len(target.manifests))
+	assert.Equal(t, source.blobs, target.blobs)
+	assert.Equal(t, 2, target.uploads)
+
+	targetDigest, exists, err := client.ManifestDigest(context.Background(), targetHost+"/python:3.7")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, digest, targetDigest)
+
+	// Copying again only uploads what's missing.
+	_, err = client.Copy(context.Background(), sourceHost+"/library/python@"+digest, targetHost+"/python:3.7-again")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, target.uploads)
+}
+
+func TestCopyCorruptBlob(t *testing.T) {
+	source, sourceHost := newMemoryRegistry(t)
+	_, targetHost := newMemoryRegistry(t)
+
+	source.addImage("app", "1.0")
+	for digest := range source.blobs {
+		source.blobs[digest] = []byte("corrupted")
+	}
+
+	_, err := NewClient().Copy(context.Background(), sourceHost+"/app:1.0", targetHost+"/app:1.0")
+	assert.Contains(t, err.Error(), "has digest "+digestOf([]byte("corrupted")))
+}
@@ -0,0 +1,359 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+import (
+	"github.com/Eagerod/hope/pkg/docker"
+)
+
+// Media types accepted when fetching manifests, so that registries return
+// multi-platform indexes as they are, rather than a single platform's
+// manifest.
+var ManifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// InsecureRegistries - Registry hosts that are spoken to over plain http.
+var InsecureRegistries []string = []string{}
+
+// Reference - A parsed image reference.
+// Reference holds either the tag, or the digest of the image.
+type Reference struct {
+	Registry   string
+	Repository string
+	Reference  string
+}
+
+// Client - A client for the registry HTTP API, authenticating the same way
+// the docker CLI would.
+type Client struct {
+	httpClient *http.Client
+
+	lock   sync.Mutex
+	tokens map[string]string
+}
+
+// NewClient - Create a registry client.
+func NewClient() *Client {
+	return &Client{httpClient: &http.Client{}, tokens: map[string]string{}}
+}
+
+// ParseReference - Split an image reference into its registry, repository,
+// and tag or digest.
+// Images without a registry come from Docker Hub, and images without a tag
+// are the latest tag.
+func ParseReference(image string) (*Reference, error) {
+	if image == "" {
+		return nil, fmt.Errorf("empty image reference")
+	}
+
+	registry := docker.ImageRegistry(image)
+	remainder := image
+	if first, rest, found := strings.Cut(image, "/"); found && first == registry {
+		remainder = rest
+	}
+
+	var repository, reference string
+	if name, digest, found := strings.Cut(remainder, "@"); found {
+		// The digest identifies the image on its own; any tag alongside it
+		//   is only informational.
+		repository, _ = docker.SplitImageTag(name)
+		reference = digest
+	} else {
+		repository, reference = docker.SplitImageTag(remainder)
+	}
+
+	if registry == "docker.io" && !strings.Contains(repository, "/") {
+		repository = fmt.Sprintf("library/%s", repository)
+	}
+
+	if repository == "" || reference == "" {
+		return nil, fmt.Errorf("invalid image reference %s", image)
+	}
+
+	return &Reference{registry, repository, reference}, nil
+}
+
+// String - The reference in its fully qualified form.
+func (r *Reference) String() string {
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Reference)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Reference)
+}
+
+// WithReference - The same repository, at another tag or digest.
+func (r *Reference) WithReference(reference string) *Reference {
+	return &Reference{r.Registry, r.Repository, reference}
+}
+
+// ManifestDigest - The digest of the manifest the image reference points
+// to, and whether the registry has it at all.
+func (c *Client) ManifestDigest(ctx context.Context, image string) (string, bool, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return "", false, err
+	}
+
+	response, err := c.Do(ctx, ref, "pull", http.MethodHead, fmt.Sprintf("manifests/%s", ref.Reference), http.Header{"Accept": ManifestMediaTypes}, nil)
+	if err != nil {
+		return "", false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		digest := response.Header.Get("Docker-Content-Digest")
+		if digest == "" {
+			return "", false, fmt.Errorf("registry didn't report a digest for %s", image)
+		}
+		return digest, true, nil
+	case http.StatusNotFound:
+		return "", false, nil
+	default:
+		return "", false, responseError(response)
+	}
+}
+
+// Do - Send a request for a path inside the reference's repository,
+// authenticating with the scope's actions if the registry asks for it.
+// Bodies are opened for each attempt, since a request may have to be sent
+// again after authenticating.
+func (c *Client) Do(ctx context.Context, ref *Reference, actions, method, path string, headers http.Header, body func() (io.ReadCloser, error)) (*http.Response, error) {
+	requestUrl := path
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		requestUrl = fmt.Sprintf("%s/v2/%s/%s", baseUrl(ref.Registry), ref.Repository, path)
+	}
+
+	scope := fmt.Sprintf("repository:%s:%s", ref.Repository, actions)
+	send := func() (*http.Response, error) {
+		var requestBody io.ReadCloser
+		if body != nil {
+			var err error
+			if requestBody, err = body(); err != nil {
+				return nil, err
+			}
+		}
+
+		request, err := http.NewRequestWithContext(ctx, method, requestUrl, requestBody)
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range headers {
+			request.Header[key] = values
+		}
+
+		// Go only sends the length of bodies it can measure itself.
+		if length := headers.Get("Content-Length"); length != "" {
+			if request.ContentLength, err = strconv.ParseInt(length, 10, 64); err != nil {
+				return nil, err
+			}
+		}
+
+		c.lock.Lock()
+		authorization, ok := c.tokens[c.tokenKey(ref.Registry, scope)]
+		c.lock.Unlock()
+		if ok {
+			request.Header.Set("Authorization", authorization)
+		}
+
+		return c.httpClient.Do(request)
+	}
+
+	response, err := send()
+	if err != nil || response.StatusCode != http.StatusUnauthorized {
+		return response, err
+	}
+
+	challenge := response.Header.Get("WWW-Authenticate")
+	response.Body.Close()
+
+	if err := c.authenticate(ctx, ref.Registry, scope, challenge); err != nil {
+		return nil, err
+	}
+
+	return send()
+}
+
+func (c *Client) tokenKey(registry, scope string) string {
+	return fmt.Sprintf("%s %s", registry, scope)
+}
+
+// authenticate - Answer the registry's challenge, keeping the resulting
+// authorization header for later requests in the same scope.
+func (c *Client) authenticate(ctx context.Context, registry, scope, challenge string) error {
+	credentials, err := docker.RegistryCredentials(registry)
+	if err != nil {
+		return err
+	}
+
+	scheme, params := parseChallenge(challenge)
+
+	var authorization string
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if credentials.Username == "" {
+			return fmt.Errorf("registry %s requires credentials; run docker login %s", registry, registry)
+		}
+
+		request, _ := http.NewRequest(http.MethodGet, "/", nil)
+		request.SetBasicAuth(credentials.Username, credentials.Password)
+		authorization = request.Header.Get("Authorization")
+	case "bearer":
+		tokenScope := scope
+		if challengeScope, ok := params["scope"]; ok {
+			tokenScope = challengeScope
+		}
+
+		token, err := c.fetchToken(ctx, params["realm"], params["service"], tokenScope, credentials)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate with %s: %w", registry, err)
+		}
+
+		authorization = fmt.Sprintf("Bearer %s", token)
+	default:
+		return fmt.Errorf("registry %s asked for unsupported authentication: %s", registry, challenge)
+	}
+
+	c.lock.Lock()
+	c.tokens[c.tokenKey(registry, scope)] = authorization
+	c.lock.Unlock()
+
+	return nil
+}
+
+func (c *Client) fetchToken(ctx context.Context, realm, service, scope string, credentials *docker.Credentials) (string, error) {
+	if realm == "" {
+		return "", fmt.Errorf("bearer challenge has no realm")
+	}
+
+	query := url.Values{}
+	if service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", scope)
+
+	var request *http.Request
+	var err error
+	if credentials.IdentityToken != "" {
+		query.Set("grant_type", "refresh_token")
+		query.Set("refresh_token", credentials.IdentityToken)
+		query.Set("client_id", "hope")
+		request, err = http.NewRequestWithContext(ctx, http.MethodPost, realm, strings.NewReader(query.Encode()))
+		if err != nil {
+			return "", err
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		request, err = http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s?%s", realm, query.Encode()), nil)
+		if err != nil {
+			return "", err
+		}
+
+		if credentials.Username != "" {
+			request.SetBasicAuth(credentials.Username, credentials.Password)
+		}
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", responseError(response)
+	}
+
+	var tokenResponse struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&tokenResponse); err != nil {
+		return "", err
+	}
+
+	if tokenResponse.Token != "" {
+		return tokenResponse.Token, nil
+	}
+
+	if tokenResponse.AccessToken != "" {
+		return tokenResponse.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("token server didn't return a token")
+}
+
+// parseChallenge - Split a WWW-Authenticate header into its scheme and
+// parameters, e.g. Bearer realm="https://auth.docker.io/token",service="x"
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, found := strings.Cut(rest, "=")
+		if !found {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		if strings.HasPrefix(value, "\"") {
+			end := strings.Index(value[1:], "\"")
+			if end == -1 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = strings.TrimPrefix(value[end+2:], ",")
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = value
+		}
+	}
+
+	return scheme, params
+}
+
+func baseUrl(registry string) string {
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+
+	if slices.Contains(InsecureRegistries, registry) {
+		return fmt.Sprintf("http://%s", registry)
+	}
+
+	return fmt.Sprintf("https://%s", registry)
+}
+
+func responseError(response *http.Response) error {
+	var registryErrors struct {
+		Errors []struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	contents, _ := io.ReadAll(response.Body)
+	if err := json.Unmarshal(contents, &registryErrors); err != nil || len(registryErrors.Errors) == 0 {
+		return fmt.Errorf("registry returned %s", response.Status)
+	}
+
+	return fmt.Errorf("registry returned %s: %s", response.Status, registryErrors.Errors[0].Message)
+}
@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"github.com/stretchr/testify/assert"
+)
+
+// testRegistry - Serve a registry that requires a bearer token for every
+// request, handing tokens out to a single user.
+func testRegistry(t *testing.T, manifests map[string]string) string {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			username, password, ok := r.BasicAuth()
+			if !ok || username != "user" || password != "pass" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			assert.Equal(t, "repository:team/app:pull", r.URL.Query().Get("scope"))
+			w.Write([]byte(`{"token": "secret-token"}`))
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		reference := strings.TrimPrefix(r.URL.Path, "/v2/team/app/manifests/")
+		digest, ok := manifests[reference]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	t.Cleanup(server.Close)
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	InsecureRegistries = []string{host}
+	t.Cleanup(func() { InsecureRegistries = []string{} })
+
+	configDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", configDir)
+	auth := base64.StdEncoding.EncodeToString([]byte("user:pass"))
+	config := `{"auths": {"` + host + `": {"auth": "` + auth + `"}}}`
+	assert.Nil(t, os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0600))
+
+	return host
+}
+
+func TestManifestDigest(t *testing.T) {
+	host := testRegistry(t, map[string]string{"1.0": "sha256:abc"})
+	client := NewClient()
+
+	digest, exists, err := client.ManifestDigest(context.Background(), host+"/team/app:1.0")
+	assert.Nil(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, "sha256:abc", digest)
+
+	digest, exists, err = client.ManifestDigest(context.Background(), host+"/team/app:2.0")
+	assert.Nil(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, "", digest)
+
+	assert.Equal(t, 1, len(client.tokens))
+}
+
+func TestManifestDigestBadCredentials(t *testing.T) {
+	host := testRegistry(t, map[string]string{})
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+
+	_, _, err := NewClient().ManifestDigest(context.Background(), host+"/team/app:1.0")
+	assert.Equal(t, "failed to authenticate with "+host+": registry returned 401 Unauthorized", err.Error())
+}
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		image    string
+		expected Reference
+	}{
+		{"python", Reference{"docker.io", "library/python", "latest"}},
+		{"python:3.7", Reference{"docker.io", "library/python", "3.7"}},
+		{"bitnami/redis:7", Reference{"docker.io", "bitnami/redis", "7"}},
+		{"registry.example.com:5000/team/app:1.0", Reference{"registry.example.com:5000", "team/app", "1.0"}},
+		{"registry.example.com/app@sha256:abc", Reference{"registry.example.com", "app", "sha256:abc"}},
+		{"python:3.7@sha256:abc", Reference{"docker.io", "library/python", "sha256:abc"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			ref, err := ParseReference(tt.image)
+			assert.Nil(t, err)
+			assert.Equal(t, &tt.expected, ref)
+		})
+	}
+
+	ref, _ := ParseReference("registry.example.com/app@sha256:abc")
+	assert.Equal(t, "registry.example.com/app@sha256:abc", ref.String())
+	assert.Equal(t, "registry.example.com/app:1.0", ref.WithReference("1.0").String())
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/python:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/python:pull",
+	}, params)
+
+	scheme, params = parseChallenge(`Basic realm=Registry`)
+	assert.Equal(t, "Basic", scheme)
+	assert.Equal(t, map[string]string{"realm": "Registry"}, params)
+}